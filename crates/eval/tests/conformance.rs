@@ -410,6 +410,11 @@ fn numeric_int_promotion() {
     run_eval_fixture(&numeric_dir(), "int_promotion");
 }
 
+#[test]
+fn numeric_int_large() {
+    run_eval_fixture(&numeric_dir(), "int_large");
+}
+
 #[test]
 fn numeric_decimal_rounding() {
     run_eval_fixture(&numeric_dir(), "decimal_rounding");
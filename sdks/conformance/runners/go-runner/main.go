@@ -4,7 +4,9 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"sort"
@@ -13,9 +15,13 @@ import (
 )
 
 func main() {
+	golden := flag.String("golden", "", "also compare Go SDK output with this golden dump (see tenor.DumpConformance)")
+	update := flag.Bool("update", false, "rewrite the -golden dump instead of comparing with it")
+	flag.Parse()
+
 	fixturesDir := "fixtures"
-	if len(os.Args) > 1 {
-		fixturesDir = os.Args[1]
+	if flag.NArg() > 0 {
+		fixturesDir = flag.Arg(0)
 	}
 
 	bundle := mustRead(fixturesDir + "/escrow-bundle.json")
@@ -128,6 +134,54 @@ func main() {
 		}
 	}
 
+	// Test 6: ListFlows
+	flows, err := eval.ListFlows()
+	if err != nil {
+		fmt.Printf("FAIL: listFlows — error: %v\n", err)
+		failed++
+	} else if len(flows) == 1 && flows[0].ID == "approval_flow" {
+		fmt.Println("PASS: listFlows")
+		passed++
+	} else {
+		fmt.Println("FAIL: listFlows")
+		fmt.Printf("  expected: [approval_flow]\n")
+		fmt.Printf("  actual:   %s\n", mustMarshal(flows))
+		failed++
+	}
+
+	// Test 7: Go-vs-Go golden dump, guarding against regressions between SDK
+	// versions.
+	if *golden != "" {
+		cases := []tenor.ConformanceCase{
+			{Name: "active", Facts: toFactSet(facts), States: entityStateFlat, Persona: "admin", FlowID: "approval_flow"},
+			{Name: "inactive", Facts: toFactSet(factsInactive), States: entityStateFlat, Persona: "admin", FlowID: "approval_flow"},
+		}
+		if *update {
+			var buf bytes.Buffer
+			if err := tenor.DumpConformance(eval, cases, &buf); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to dump conformance: %v\n", err)
+				os.Exit(1)
+			}
+			if err := os.WriteFile(*golden, buf.Bytes(), 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", *golden, err)
+				os.Exit(1)
+			}
+			fmt.Printf("UPDATED: %s\n", *golden)
+		} else if mismatches, err := tenor.CompareConformance(mustOpen(*golden), eval, cases); err != nil {
+			fmt.Printf("FAIL: golden dump — error: %v\n", err)
+			failed++
+		} else if len(mismatches) > 0 {
+			fmt.Println("FAIL: golden dump")
+			for _, m := range mismatches {
+				fmt.Printf("  %s\n    golden: %s\n    actual: %s\n", m.Name, m.Golden, m.Got)
+			}
+			failed++
+		} else {
+			fmt.Println("PASS: golden dump")
+			passed++
+		}
+	}
+
 	fmt.Printf("\nGo SDK: %d passed, %d failed\n", passed, failed)
 	if failed > 0 {
 		os.Exit(1)
@@ -145,6 +199,15 @@ func mustRead(path string) string {
 	return string(data)
 }
 
+func mustOpen(path string) *os.File {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	return f
+}
+
 func mustReadObj(path string) map[string]interface{} {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -0,0 +1,221 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ComputeActionSpaceBatch computes the action space for persona under each of
+// states, sharing the same facts. It is equivalent to calling
+// ComputeActionSpaceNested in a loop, but marshals and validates facts once
+// and holds the evaluator's lock for the whole batch, which matters when the
+// batch runs to thousands of workflow instances.
+//
+// Results are aligned with states. Errors that concern the whole batch, such
+// as invalid facts, are returned with nil results. If only some items fail,
+// their results are nil and the error is a *BatchError whose Errors are
+// aligned with states; the other results are still returned.
+func (e *Evaluator) ComputeActionSpaceBatch(
+	facts FactSet,
+	states []EntityStateMapNested,
+	persona string,
+) ([]*ActionSpace, error) {
+	if err := e.requireOperations(); err != nil {
+		return nil, err
+	}
+	if err := e.checkFacts(facts); err != nil {
+		return nil, err
+	}
+	factsJSON, err := e.marshalFacts(facts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal facts: %w", err)
+	}
+
+	errs := make([]error, len(states))
+	argSets := make([][]string, len(states))
+	inferred := make([]EntityStateMapNested, len(states))
+	for i, s := range states {
+		inferred[i] = e.inferStatesNested(s)
+		statesJSON, err := json.Marshal(inferred[i])
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to marshal entity states: %w", err)
+			continue
+		}
+		argSets[i] = []string{string(factsJSON), string(statesJSON), persona}
+	}
+
+	results, callErrs := e.runtime.CallHandleBatch("compute_action_space", e.handle, argSets)
+	spaces := make([]*ActionSpace, len(states))
+	failed := false
+	for i := range states {
+		switch {
+		case errs[i] != nil:
+		case callErrs[i] != nil:
+			errs[i] = fmt.Errorf("compute_action_space WASM call failed: %w", callErrs[i])
+		default:
+			spaces[i], errs[i] = e.decodeActionSpace(results[i], inferred[i])
+		}
+		failed = failed || errs[i] != nil
+	}
+	if failed {
+		return spaces, &BatchError{Errors: errs}
+	}
+	return spaces, nil
+}
+
+// ComputeActionSpaceMulti computes the combined action space for a caller
+// acting under several personas at once.
+//
+// An action is available if it is available under any of the personas;
+// duplicates (same flow and instance bindings) are kept once, attributed to
+// the first persona that made them available. An action is blocked only if it
+// is blocked under every persona, and the reason reported is the one given
+// for the first persona. CurrentVerdicts depend only on facts and are taken
+// from the first persona's result. PersonaID is the personas joined by ",".
+func (e *Evaluator) ComputeActionSpaceMulti(
+	facts FactSet,
+	entityStates EntityStateMap,
+	personas []string,
+) (*ActionSpace, error) {
+	if len(personas) == 0 {
+		return nil, fmt.Errorf("at least one persona is required")
+	}
+
+	spaces := make([]*ActionSpace, len(personas))
+	for i, persona := range personas {
+		space, err := e.ComputeActionSpace(facts, entityStates, persona)
+		if err != nil {
+			return nil, fmt.Errorf("persona %q: %w", persona, err)
+		}
+		spaces[i] = space
+	}
+
+	combined := &ActionSpace{
+		PersonaID:       strings.Join(personas, ","),
+		Actions:         []Action{},
+		CurrentVerdicts: spaces[0].CurrentVerdicts,
+		BlockedActions:  []BlockedAction{},
+	}
+
+	available := make(map[string]bool)
+	for _, space := range spaces {
+		for _, a := range space.Actions {
+			key := actionKey(a.FlowID, a.InstanceBindings)
+			if available[key] {
+				continue
+			}
+			available[key] = true
+			combined.Actions = append(combined.Actions, a)
+		}
+	}
+
+	// Count how many personas block each action; only those blocked for all
+	// personas (and available for none) survive.
+	blockedCount := make(map[string]int)
+	for _, space := range spaces {
+		seen := make(map[string]bool)
+		for _, b := range space.BlockedActions {
+			key := actionKey(b.FlowID, b.InstanceBindings)
+			if !seen[key] {
+				seen[key] = true
+				blockedCount[key]++
+			}
+		}
+	}
+	emitted := make(map[string]bool)
+	for _, b := range spaces[0].BlockedActions {
+		key := actionKey(b.FlowID, b.InstanceBindings)
+		if available[key] || emitted[key] || blockedCount[key] != len(spaces) {
+			continue
+		}
+		emitted[key] = true
+		combined.BlockedActions = append(combined.BlockedActions, b)
+	}
+
+	return combined, nil
+}
+
+// ComputeActionSpaceAfter answers "what could persona do once flowID has run?"
+// without committing anything: it simulates flowID, applies the flow's
+// WouldTransition to entityStates, and computes the action space in the
+// resulting state. It returns the simulated flow result and the projected
+// action space.
+//
+// Facts are reused unchanged for the projection; only entity state moves.
+// entityStates itself is not modified.
+func (e *Evaluator) ComputeActionSpaceAfter(
+	flowID string,
+	facts FactSet,
+	entityStates EntityStateMap,
+	persona string,
+) (*FlowResult, *ActionSpace, error) {
+	flowResult, err := e.ExecuteFlow(flowID, facts, entityStates, persona)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	projected := entityStates.nested()
+	for _, c := range flowResult.WouldTransition {
+		projected.set(c.EntityID, c.InstanceID, c.ToState)
+	}
+
+	space, err := e.ComputeActionSpaceNested(facts, projected, persona)
+	if err != nil {
+		return nil, nil, err
+	}
+	return flowResult, space, nil
+}
+
+// InitialActionSpace computes the action space for persona in a brand-new
+// workflow: every declared entity at its initial state under the default
+// instance, and no facts supplied, so that every fact takes its declared
+// default. It answers "what can a fresh Order do?" at creation time.
+//
+// It fails with a *MissingFactDefaultsError if any declared fact has no
+// default, since such facts can only come from the caller; use
+// ComputeActionSpaceNested with Bundle().InitialStates() and the facts
+// instead.
+func (e *Evaluator) InitialActionSpace(persona string) (*ActionSpace, error) {
+	var missing []string
+	for i := range e.bundle.Facts {
+		if _, ok := e.bundle.Facts[i].DefaultValue(); !ok {
+			missing = append(missing, e.bundle.Facts[i].ID)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, &MissingFactDefaultsError{FactIDs: missing}
+	}
+	return e.ComputeActionSpaceNested(FactSet{}, e.bundle.InitialStates(), persona)
+}
+
+// filterAllowedFlows drops actions for flows outside WithFlowAllowList.
+func (e *Evaluator) filterAllowedFlows(space *ActionSpace) {
+	if e.opts.flowAllowList == nil {
+		return
+	}
+	actions := space.Actions[:0]
+	for _, a := range space.Actions {
+		if e.opts.flowAllowed(a.FlowID) {
+			actions = append(actions, a)
+		}
+	}
+	space.Actions = actions
+
+	blocked := space.BlockedActions[:0]
+	for _, b := range space.BlockedActions {
+		if e.opts.flowAllowed(b.FlowID) {
+			blocked = append(blocked, b)
+		}
+	}
+	space.BlockedActions = blocked
+}
+
+// actionKey identifies an action by flow and candidate instance bindings.
+func actionKey(flowID string, bindings map[string][]string) string {
+	// encoding/json sorts map keys, so equal bindings encode identically.
+	data, _ := json.Marshal(bindings)
+	return flowID + "|" + string(data)
+}
@@ -0,0 +1,155 @@
+// Package audit produces signed, tamper-evident records of flow simulations.
+//
+// A record holds the inputs of a simulation and the evaluator's result. It is
+// signed over its canonical JSON (see tenor.CanonicalJSON), so a verifier in
+// any language can check it by re-encoding the record the same way:
+//
+//	signed, err := audit.ExecuteFlowSigned(eval, "approval_flow", facts, states, "admin", key)
+//	// store signed; later:
+//	err = audit.VerifySignedFlowResult(key.Public(), signed)
+//
+// The package is separate from tenor so that callers who do not sign records
+// do not depend on it.
+package audit
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+// Signature algorithms, recorded in SignedFlowResult.Algorithm.
+const (
+	// AlgEd25519 signs the canonical bytes directly.
+	AlgEd25519 = "Ed25519"
+	// AlgECDSASHA256 signs the SHA-256 digest of the canonical bytes; the
+	// signature is ASN.1 DER encoded.
+	AlgECDSASHA256 = "ECDSA-SHA256"
+	// AlgRSASHA256 signs the SHA-256 digest of the canonical bytes with
+	// RSASSA-PKCS1-v1_5.
+	AlgRSASHA256 = "RSA-PKCS1v15-SHA256"
+)
+
+// ErrInvalidSignature is returned by VerifySignedFlowResult when the
+// signature does not match the record.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// FlowRecord is the signed content: what was simulated and what the
+// evaluator decided.
+type FlowRecord struct {
+	ContractID   string               `json:"contract_id"`
+	FlowID       string               `json:"flow_id"`
+	Persona      string               `json:"persona"`
+	Facts        tenor.FactSet        `json:"facts"`
+	EntityStates tenor.EntityStateMap `json:"entity_states"`
+	Result       *tenor.FlowResult    `json:"result"`
+}
+
+// UnmarshalJSON decodes r with numbers kept as json.Number rather than
+// float64, so a stored record re-encodes to the canonical bytes it was signed
+// over even when a fact or payload holds an integer beyond 2^53.
+func (r *FlowRecord) UnmarshalJSON(data []byte) error {
+	type plain FlowRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode((*plain)(r))
+}
+
+// SignedFlowResult is a FlowRecord with a signature over its canonical JSON.
+type SignedFlowResult struct {
+	Record    FlowRecord `json:"record"`
+	Algorithm string     `json:"algorithm"`
+	Signature []byte     `json:"signature"`
+}
+
+// ExecuteFlowSigned simulates flowID with e.ExecuteFlow and signs a record of
+// the inputs and result with signer, which must hold an Ed25519, ECDSA or RSA
+// key.
+func ExecuteFlowSigned(
+	e *tenor.Evaluator,
+	flowID string,
+	facts tenor.FactSet,
+	entityStates tenor.EntityStateMap,
+	persona string,
+	signer crypto.Signer,
+) (*SignedFlowResult, error) {
+	result, err := e.ExecuteFlow(flowID, facts, entityStates, persona)
+	if err != nil {
+		return nil, err
+	}
+	return SignFlowRecord(FlowRecord{
+		ContractID:   e.ContractID(),
+		FlowID:       flowID,
+		Persona:      persona,
+		Facts:        facts,
+		EntityStates: entityStates,
+		Result:       result,
+	}, signer)
+}
+
+// SignFlowRecord signs an existing record, e.g. one built from a result
+// obtained elsewhere.
+func SignFlowRecord(record FlowRecord, signer crypto.Signer) (*SignedFlowResult, error) {
+	msg, err := tenor.CanonicalJSON(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize flow record: %w", err)
+	}
+
+	var alg string
+	var sig []byte
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		alg = AlgEd25519
+		sig, err = signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		alg = AlgECDSASHA256
+		digest := sha256.Sum256(msg)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case *rsa.PublicKey:
+		alg = AlgRSASHA256
+		digest := sha256.Sum256(msg)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported signer key type %T", signer.Public())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign flow record: %w", err)
+	}
+	return &SignedFlowResult{Record: record, Algorithm: alg, Signature: sig}, nil
+}
+
+// VerifySignedFlowResult checks that r was signed by the private key of pub.
+// It returns an error matching ErrInvalidSignature if the record or signature
+// was altered, or if r.Algorithm does not match the key type.
+func VerifySignedFlowResult(pub crypto.PublicKey, r *SignedFlowResult) error {
+	msg, err := tenor.CanonicalJSON(r.Record)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize flow record: %w", err)
+	}
+	digest := sha256.Sum256(msg)
+
+	var ok bool
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		ok = r.Algorithm == AlgEd25519 && ed25519.Verify(key, msg, r.Signature)
+	case *ecdsa.PublicKey:
+		ok = r.Algorithm == AlgECDSASHA256 && ecdsa.VerifyASN1(key, digest[:], r.Signature)
+	case *rsa.PublicKey:
+		ok = r.Algorithm == AlgRSASHA256 && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], r.Signature) == nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", pub)
+	}
+	if !ok {
+		return fmt.Errorf("flow record for %q: %w", r.Record.FlowID, ErrInvalidSignature)
+	}
+	return nil
+}
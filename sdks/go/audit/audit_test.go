@@ -0,0 +1,107 @@
+package audit_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	tenor "github.com/riverline-labs/tenor-go"
+	"github.com/riverline-labs/tenor-go/audit"
+)
+
+func record() audit.FlowRecord {
+	return audit.FlowRecord{
+		ContractID:   "entity_operation_basic",
+		FlowID:       "approval_flow",
+		Persona:      "admin",
+		Facts:        tenor.FactSet{"is_active": true},
+		EntityStates: tenor.EntityStateMap{"Order": "pending"},
+		Result: &tenor.FlowResult{
+			FlowID:  "approval_flow",
+			Outcome: "order_approved",
+			WouldTransition: []tenor.EntityStateChange{
+				{EntityID: "Order", InstanceID: "_default", FromState: "pending", ToState: "approved"},
+			},
+		},
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := audit.SignFlowRecord(record(), edKey)
+	if err != nil {
+		t.Fatalf("SignFlowRecord failed: %v", err)
+	}
+	if err := audit.VerifySignedFlowResult(edKey.Public(), signed); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	// The record survives a JSON round trip, as when it is stored and reloaded.
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloaded audit.SignedFlowResult
+	if err := json.Unmarshal(data, &reloaded); err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.VerifySignedFlowResult(edKey.Public(), &reloaded); err != nil {
+		t.Errorf("expected reloaded record to verify, got %v", err)
+	}
+
+	reloaded.Record.Result.Outcome = "approval_failed"
+	if err := audit.VerifySignedFlowResult(edKey.Public(), &reloaded); !errors.Is(err, audit.ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for a tampered record, got %v", err)
+	}
+
+	ecSigned, err := audit.SignFlowRecord(record(), ecKey)
+	if err != nil {
+		t.Fatalf("SignFlowRecord failed: %v", err)
+	}
+	if err := audit.VerifySignedFlowResult(&ecKey.PublicKey, ecSigned); err != nil {
+		t.Errorf("expected valid ECDSA signature, got %v", err)
+	}
+	if err := audit.VerifySignedFlowResult(edKey.Public(), ecSigned); !errors.Is(err, audit.ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for the wrong key, got %v", err)
+	}
+}
+
+func TestReloadedRecordWithLargeIntVerifies(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := record()
+	r.Facts["amount"] = int64(9007199254740993) // 2^53 + 1: not a float64
+	signed, err := audit.SignFlowRecord(r, key)
+	if err != nil {
+		t.Fatalf("SignFlowRecord failed: %v", err)
+	}
+
+	data, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloaded audit.SignedFlowResult
+	if err := json.Unmarshal(data, &reloaded); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := reloaded.Record.Facts["amount"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Fatalf("expected the exact json.Number 9007199254740993, got %#v", reloaded.Record.Facts["amount"])
+	}
+	if err := audit.VerifySignedFlowResult(key.Public(), &reloaded); err != nil {
+		t.Errorf("expected reloaded record to verify, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package tenor
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BindingPolicy picks one instance for an entity from the candidate instances
+// an action space reports for it. Candidates are never empty.
+type BindingPolicy func(entityID string, candidates []string) (string, error)
+
+// FirstInstance picks the first candidate in the order the action space
+// reports them.
+var FirstInstance BindingPolicy = func(entityID string, candidates []string) (string, error) {
+	return candidates[0], nil
+}
+
+// LowestID picks the lexicographically lowest candidate instance ID.
+var LowestID BindingPolicy = func(entityID string, candidates []string) (string, error) {
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+	return sorted[0], nil
+}
+
+// Custom adapts a selection function to a BindingPolicy. fn may return an
+// error to refuse a choice, e.g. when several candidates are equally valid.
+func Custom(fn func(entityID string, candidates []string) (string, error)) BindingPolicy {
+	return BindingPolicy(fn)
+}
+
+// ExecuteFlowAuto simulates flowID with instance bindings resolved from the
+// action space: it computes the action space for persona, takes the candidate
+// instances of the flow's available action, and lets policy choose one per
+// entity before calling ExecuteFlowWithBindings.
+//
+// It returns an error if the flow is not available to persona, or if policy
+// fails or returns an instance that is not among the candidates.
+func (e *Evaluator) ExecuteFlowAuto(
+	flowID string,
+	facts FactSet,
+	entityStates EntityStateMapNested,
+	persona string,
+	policy BindingPolicy,
+) (*FlowResult, error) {
+	if policy == nil {
+		return nil, fmt.Errorf("binding policy is required")
+	}
+	if err := e.checkFlowAllowed(flowID); err != nil {
+		return nil, err
+	}
+
+	space, err := e.ComputeActionSpaceNested(facts, entityStates, persona)
+	if err != nil {
+		return nil, err
+	}
+
+	var action *Action
+	for i := range space.Actions {
+		if space.Actions[i].FlowID == flowID {
+			action = &space.Actions[i]
+			break
+		}
+	}
+	if action == nil {
+		for _, b := range space.BlockedActions {
+			if b.FlowID == flowID {
+				return nil, fmt.Errorf("flow %q is blocked for persona %q: %s", flowID, persona, b.Reason.Type)
+			}
+		}
+		return nil, fmt.Errorf("flow %q is not in the action space for persona %q", flowID, persona)
+	}
+
+	bindings, err := resolveBindings(action.InstanceBindings, policy)
+	if err != nil {
+		return nil, fmt.Errorf("flow %q: %w", flowID, err)
+	}
+	return e.ExecuteFlowWithBindings(flowID, facts, entityStates, persona, bindings)
+}
+
+// DefaultMaxBindingCombinations is the cap BindingCombinations applies when
+// called with a limit of zero or less.
+const DefaultMaxBindingCombinations = 1024
+
+// BindingCombinations enumerates every concrete InstanceBindings the action
+// can be executed with: the Cartesian product of its candidate instances per
+// entity. Combinations are ordered by entity ID, then by the order the action
+// space reports each entity's candidates, so the result is stable across
+// calls.
+//
+// limit caps the number of combinations; a limit of zero or less uses
+// DefaultMaxBindingCombinations. If the product exceeds the cap no
+// combinations are built and an error matching ErrTooManyCombinations is
+// returned. An action without candidate bindings yields a single empty
+// combination; an entity with no candidates yields none.
+func (a Action) BindingCombinations(limit int) ([]InstanceBindings, error) {
+	if limit <= 0 {
+		limit = DefaultMaxBindingCombinations
+	}
+
+	entityIDs := make([]string, 0, len(a.InstanceBindings))
+	total := 1
+	for entityID, instanceIDs := range a.InstanceBindings {
+		if len(instanceIDs) == 0 {
+			return nil, nil
+		}
+		if total > limit/len(instanceIDs) {
+			return nil, fmt.Errorf("flow %q: more than %d binding combinations: %w", a.FlowID, limit, ErrTooManyCombinations)
+		}
+		total *= len(instanceIDs)
+		entityIDs = append(entityIDs, entityID)
+	}
+	if total > limit {
+		return nil, fmt.Errorf("flow %q: more than %d binding combinations: %w", a.FlowID, limit, ErrTooManyCombinations)
+	}
+	sort.Strings(entityIDs)
+
+	combos := make([]InstanceBindings, 0, total)
+	current := make(InstanceBindings, len(entityIDs))
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(entityIDs) {
+			combo := make(InstanceBindings, len(current))
+			for k, v := range current {
+				combo[k] = v
+			}
+			combos = append(combos, combo)
+			return
+		}
+		for _, instanceID := range a.InstanceBindings[entityIDs[i]] {
+			current[entityIDs[i]] = instanceID
+			walk(i + 1)
+		}
+	}
+	walk(0)
+	return combos, nil
+}
+
+// resolveBindings applies policy to each entity's candidates.
+func resolveBindings(candidates map[string][]string, policy BindingPolicy) (InstanceBindings, error) {
+	bindings := make(InstanceBindings, len(candidates))
+	for entityID, instanceIDs := range candidates {
+		if len(instanceIDs) == 0 {
+			return nil, fmt.Errorf("no candidate instances for entity %q", entityID)
+		}
+		chosen, err := policy(entityID, instanceIDs)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve binding for entity %q: %w", entityID, err)
+		}
+		if !contains(instanceIDs, chosen) {
+			return nil, fmt.Errorf("binding policy chose %q for entity %q, which is not among the candidates %v",
+				chosen, entityID, instanceIDs)
+		}
+		bindings[entityID] = chosen
+	}
+	return bindings, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1026 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultInstanceID is the instance ID the evaluator uses for entities
+// addressed through the single-instance (flat) state format.
+const DefaultInstanceID = "_default"
+
+// Bundle is a Go-side view of a Tenor interchange bundle.
+//
+// The WASM evaluator owns evaluation semantics; Bundle exists so that static
+// questions about a contract (which facts it declares, how a flow is wired,
+// what an operation's effects are) can be answered without a WASM round trip.
+// System constructs, which the SDK does not model, are skipped.
+type Bundle struct {
+	ID           string
+	TenorVersion string
+	Facts        []FactDef
+	Entities     []EntityDef
+	Rules        []RuleDef
+	Operations   []OperationDef
+	Flows        []FlowDef
+	Sources      []SourceDef
+	Personas     []PersonaDef
+
+	// header and constructs hold the bundle JSON as parsed, for MarshalJSON:
+	// the top-level fields other than constructs, and every construct.
+	header     map[string]json.RawMessage
+	constructs []rawConstruct
+}
+
+// rawConstruct is one construct of a parsed bundle, kept verbatim.
+type rawConstruct struct {
+	Kind string
+	ID   string
+	JSON json.RawMessage
+}
+
+// Provenance records where a construct was declared in the .tenor source.
+type Provenance struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// FactDef is a declared Fact construct.
+type FactDef struct {
+	ID         string          `json:"id"`
+	Type       json.RawMessage `json:"type"`
+	Source     json.RawMessage `json:"source,omitempty"`
+	Default    json.RawMessage `json:"default,omitempty"`
+	Provenance Provenance      `json:"provenance"`
+}
+
+// BaseType returns the fact's declared base type, such as "Bool", "Text" or
+// "DateTime", or "" if the type cannot be read.
+func (f *FactDef) BaseType() string {
+	var t struct {
+		Base string `json:"base"`
+	}
+	_ = json.Unmarshal(f.Type, &t)
+	return t.Base
+}
+
+// DefaultValue returns the fact's declared default in a form the evaluator
+// accepts as fact input. Structured Bool and Int literals are unwrapped to
+// their plain values; Decimal and Money defaults keep their structured form.
+func (f *FactDef) DefaultValue() (interface{}, bool) {
+	if len(f.Default) == 0 {
+		return nil, false
+	}
+	var v interface{}
+	if err := json.Unmarshal(f.Default, &v); err != nil {
+		return nil, false
+	}
+	if obj, ok := v.(map[string]interface{}); ok {
+		switch obj["kind"] {
+		case "bool_literal", "int_literal":
+			return obj["value"], true
+		}
+	}
+	return v, true
+}
+
+// SourceID returns the ID of the Source construct the fact is bound to, or ""
+// if the fact uses a legacy freetext "system.field" binding.
+func (f *FactDef) SourceID() string {
+	var src struct {
+		SourceID string `json:"source_id"`
+	}
+	_ = json.Unmarshal(f.Source, &src)
+	return src.SourceID
+}
+
+// PersonaDef is a declared Persona: an identity token for a class of actor.
+type PersonaDef struct {
+	ID         string     `json:"id"`
+	Provenance Provenance `json:"provenance"`
+}
+
+// SourceDef is a declared external data Source. Protocol is one of http,
+// database, graphql, grpc, static, manual, or an x_ extension tag.
+type SourceDef struct {
+	ID          string            `json:"id"`
+	Protocol    string            `json:"protocol"`
+	Fields      map[string]string `json:"fields"`
+	Description string            `json:"description,omitempty"`
+	Provenance  Provenance        `json:"provenance"`
+}
+
+// EntityDef is a declared Entity state machine.
+type EntityDef struct {
+	ID          string       `json:"id"`
+	Initial     string       `json:"initial"`
+	States      []string     `json:"states"`
+	Transitions []Transition `json:"transitions"`
+	Provenance  Provenance   `json:"provenance"`
+}
+
+// Transition is a permitted from -> to edge in an entity state machine.
+type Transition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RuleDef is a declared verdict-producing Rule.
+//
+// Description and Tags are optional human-readable metadata. Bundles that do
+// not carry them (including every bundle produced before they existed) leave
+// both empty.
+type RuleDef struct {
+	ID          string     `json:"id"`
+	Stratum     int        `json:"stratum"`
+	Body        RuleBody   `json:"body"`
+	Description string     `json:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Provenance  Provenance `json:"provenance"`
+}
+
+// RuleBody is the when/produce pair of a rule.
+type RuleBody struct {
+	When    json.RawMessage `json:"when"`
+	Produce ProduceClause   `json:"produce"`
+}
+
+// ProduceClause names the verdict a rule produces and its payload.
+type ProduceClause struct {
+	VerdictType string          `json:"verdict_type"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// OperationDef is a declared Operation.
+type OperationDef struct {
+	ID              string          `json:"id"`
+	AllowedPersonas []string        `json:"allowed_personas"`
+	Precondition    json.RawMessage `json:"precondition"`
+	Effects         []Effect        `json:"effects"`
+	ErrorContract   []string        `json:"error_contract"`
+	Outcomes        []string        `json:"outcomes,omitempty"`
+	Provenance      Provenance      `json:"provenance"`
+}
+
+// Effect is an entity state transition produced by an operation.
+type Effect struct {
+	EntityID string `json:"entity_id"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Outcome  string `json:"outcome,omitempty"`
+}
+
+// FlowDef is a declared Flow.
+type FlowDef struct {
+	ID         string     `json:"id"`
+	Entry      string     `json:"entry"`
+	Snapshot   string     `json:"snapshot"`
+	Steps      []FlowStep `json:"steps"`
+	Provenance Provenance `json:"provenance"`
+}
+
+// FlowStep is a single step of a flow. Kind is one of OperationStep,
+// BranchStep, HandoffStep, SubFlowStep or ParallelStep; only the fields
+// relevant to that kind are populated.
+type FlowStep struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+
+	// OperationStep
+	Op        string                `json:"op,omitempty"`
+	Persona   string                `json:"persona,omitempty"`
+	Outcomes  map[string]StepTarget `json:"outcomes,omitempty"`
+	OnFailure *FailureHandler       `json:"on_failure,omitempty"`
+
+	// BranchStep
+	Condition json.RawMessage `json:"condition,omitempty"`
+	IfTrue    *StepTarget     `json:"if_true,omitempty"`
+	IfFalse   *StepTarget     `json:"if_false,omitempty"`
+
+	// SubFlowStep
+	Flow      string      `json:"flow,omitempty"`
+	OnSuccess *StepTarget `json:"on_success,omitempty"`
+
+	// HandoffStep
+	FromPersona string `json:"from_persona,omitempty"`
+	ToPersona   string `json:"to_persona,omitempty"`
+	Next        string `json:"next,omitempty"`
+
+	// ParallelStep
+	Branches []ParallelBranch `json:"branches,omitempty"`
+	Join     *JoinPolicy      `json:"join,omitempty"`
+}
+
+// StepTarget is where a flow continues: either another step (StepID) or a
+// terminal outcome (Outcome). Exactly one of the two is set.
+type StepTarget struct {
+	StepID  string
+	Outcome string
+}
+
+// IsTerminal reports whether the target ends the flow.
+func (t StepTarget) IsTerminal() bool {
+	return t.StepID == ""
+}
+
+// UnmarshalJSON accepts both interchange encodings of a step target: a bare
+// step ID string, or a {"kind": "Terminal", "outcome": ...} object.
+func (t *StepTarget) UnmarshalJSON(data []byte) error {
+	var stepID string
+	if err := json.Unmarshal(data, &stepID); err == nil {
+		*t = StepTarget{StepID: stepID}
+		return nil
+	}
+	var terminal struct {
+		Kind    string `json:"kind"`
+		Outcome string `json:"outcome"`
+	}
+	if err := json.Unmarshal(data, &terminal); err != nil {
+		return fmt.Errorf("step target must be a step ID or a Terminal object: %w", err)
+	}
+	*t = StepTarget{Outcome: terminal.Outcome}
+	return nil
+}
+
+// MarshalJSON writes the target back in its interchange encoding.
+func (t StepTarget) MarshalJSON() ([]byte, error) {
+	if !t.IsTerminal() {
+		return json.Marshal(t.StepID)
+	}
+	return json.Marshal(map[string]string{"kind": "Terminal", "outcome": t.Outcome})
+}
+
+// FailureHandler describes what a flow does when a step fails. Kind is one of
+// Terminate, Compensate or Escalate.
+type FailureHandler struct {
+	Kind string `json:"kind"`
+
+	// Terminate
+	Outcome string `json:"outcome,omitempty"`
+
+	// Compensate
+	Steps []CompensationStep `json:"steps,omitempty"`
+	Then  *StepTarget        `json:"then,omitempty"`
+
+	// Escalate
+	ToPersona string `json:"to_persona,omitempty"`
+	Next      string `json:"next,omitempty"`
+}
+
+// CompensationStep is an operation run while compensating a failed step.
+type CompensationStep struct {
+	Op        string     `json:"op"`
+	Persona   string     `json:"persona"`
+	OnFailure StepTarget `json:"on_failure"`
+}
+
+// ParallelBranch is one branch of a ParallelStep.
+type ParallelBranch struct {
+	ID    string     `json:"id"`
+	Entry string     `json:"entry"`
+	Steps []FlowStep `json:"steps"`
+}
+
+// JoinPolicy describes how a ParallelStep continues once its branches finish.
+type JoinPolicy struct {
+	OnAllSuccess  *StepTarget     `json:"on_all_success,omitempty"`
+	OnAnyFailure  *FailureHandler `json:"on_any_failure,omitempty"`
+	OnAllComplete *StepTarget     `json:"on_all_complete,omitempty"`
+}
+
+// ParseBundle parses interchange bundle JSON into a Bundle.
+//
+// ParseBundle checks structure only; it does not validate the contract the
+// way the evaluator does when loading it.
+func ParseBundle(bundleJSON []byte) (*Bundle, error) {
+	var raw struct {
+		ID           string            `json:"id"`
+		Kind         string            `json:"kind"`
+		TenorVersion string            `json:"tenor_version"`
+		Constructs   []json.RawMessage `json:"constructs"`
+	}
+	if err := json.Unmarshal(bundleJSON, &raw); err != nil {
+		return nil, fmt.Errorf("invalid bundle JSON: %w", err)
+	}
+	if raw.Kind != "Bundle" {
+		return nil, fmt.Errorf("expected kind \"Bundle\", got %q", raw.Kind)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bundleJSON, &fields); err != nil {
+		return nil, fmt.Errorf("invalid bundle JSON: %w", err)
+	}
+	delete(fields, "constructs")
+
+	b := &Bundle{ID: raw.ID, TenorVersion: raw.TenorVersion, header: fields}
+	for i, c := range raw.Constructs {
+		var header struct {
+			ID   string `json:"id"`
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(c, &header); err != nil {
+			return nil, fmt.Errorf("constructs[%d]: %w", i, err)
+		}
+		b.constructs = append(b.constructs, rawConstruct{Kind: header.Kind, ID: header.ID, JSON: c})
+
+		var err error
+		switch header.Kind {
+		case "Fact":
+			var f FactDef
+			err = json.Unmarshal(c, &f)
+			b.Facts = append(b.Facts, f)
+		case "Entity":
+			var e EntityDef
+			err = json.Unmarshal(c, &e)
+			b.Entities = append(b.Entities, e)
+		case "Rule":
+			var r RuleDef
+			if err = json.Unmarshal(c, &r); err == nil {
+				_, err = r.Body.condition()
+			}
+			b.Rules = append(b.Rules, r)
+		case "Operation":
+			var o OperationDef
+			if err = json.Unmarshal(c, &o); err == nil {
+				_, err = o.PreconditionExpr()
+			}
+			b.Operations = append(b.Operations, o)
+		case "Flow":
+			var f FlowDef
+			err = json.Unmarshal(c, &f)
+			b.Flows = append(b.Flows, f)
+		case "Persona":
+			var p PersonaDef
+			err = json.Unmarshal(c, &p)
+			b.Personas = append(b.Personas, p)
+		case "Source":
+			var src SourceDef
+			err = json.Unmarshal(c, &src)
+			b.Sources = append(b.Sources, src)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("constructs[%d] (%s %q): %w", i, header.Kind, header.ID, err)
+		}
+	}
+
+	return b, nil
+}
+
+// Capabilities reports which kinds of constructs a contract declares.
+//
+// A pure rule contract (facts and rules only) supports Evaluate but has no
+// action space and no flows to execute.
+type Capabilities struct {
+	HasFacts      bool
+	HasRules      bool
+	HasEntities   bool
+	HasOperations bool
+	HasFlows      bool
+}
+
+// Capabilities reports which kinds of constructs the bundle declares, so that
+// callers can choose which Evaluator methods apply.
+func (b *Bundle) Capabilities() Capabilities {
+	return Capabilities{
+		HasFacts:      len(b.Facts) > 0,
+		HasRules:      len(b.Rules) > 0,
+		HasEntities:   len(b.Entities) > 0,
+		HasOperations: len(b.Operations) > 0,
+		HasFlows:      len(b.Flows) > 0,
+	}
+}
+
+// Fact returns the fact with the given ID.
+func (b *Bundle) Fact(id string) (*FactDef, bool) {
+	for i := range b.Facts {
+		if b.Facts[i].ID == id {
+			return &b.Facts[i], true
+		}
+	}
+	return nil, false
+}
+
+// Entity returns the entity with the given ID.
+func (b *Bundle) Entity(id string) (*EntityDef, bool) {
+	for i := range b.Entities {
+		if b.Entities[i].ID == id {
+			return &b.Entities[i], true
+		}
+	}
+	return nil, false
+}
+
+// Rule returns the rule with the given ID.
+func (b *Bundle) Rule(id string) (*RuleDef, bool) {
+	for i := range b.Rules {
+		if b.Rules[i].ID == id {
+			return &b.Rules[i], true
+		}
+	}
+	return nil, false
+}
+
+// Operation returns the operation with the given ID.
+func (b *Bundle) Operation(id string) (*OperationDef, bool) {
+	for i := range b.Operations {
+		if b.Operations[i].ID == id {
+			return &b.Operations[i], true
+		}
+	}
+	return nil, false
+}
+
+// Flow returns the flow with the given ID.
+func (b *Bundle) Flow(id string) (*FlowDef, bool) {
+	for i := range b.Flows {
+		if b.Flows[i].ID == id {
+			return &b.Flows[i], true
+		}
+	}
+	return nil, false
+}
+
+// OperationsForPersona returns the sorted IDs of every operation whose
+// allowed_personas include persona. It is a static view of what a persona
+// could ever perform, independent of facts and entity states.
+//
+// An operation with no allowed personas admits nobody, as in the evaluator;
+// the elaborator rejects such operations, so valid bundles never contain one.
+func (b *Bundle) OperationsForPersona(persona string) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, op := range b.Operations {
+		if seen[op.ID] {
+			continue
+		}
+		for _, p := range op.AllowedPersonas {
+			if p == persona {
+				seen[op.ID] = true
+				ids = append(ids, op.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// TransitionsForPersona returns the distinct entity state transitions
+// persona can cause through the effects of the operations it is allowed to
+// perform, independent of flows, facts and entity states. InstanceID is left
+// empty, as operations do not name instances. The result is sorted by entity,
+// then from and to state.
+func (b *Bundle) TransitionsForPersona(persona string) []EntityStateChange {
+	seen := make(map[EntityStateChange]bool)
+	var changes []EntityStateChange
+	for _, id := range b.OperationsForPersona(persona) {
+		op, _ := b.Operation(id)
+		for _, effect := range op.Effects {
+			c := EntityStateChange{EntityID: effect.EntityID, FromState: effect.From, ToState: effect.To}
+			if !seen[c] {
+				seen[c] = true
+				changes = append(changes, c)
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.EntityID != b.EntityID {
+			return a.EntityID < b.EntityID
+		}
+		if a.FromState != b.FromState {
+			return a.FromState < b.FromState
+		}
+		return a.ToState < b.ToState
+	})
+	return changes
+}
+
+// AllOutcomes returns every terminal outcome a flow of the contract can end
+// with, sorted and de-duplicated: the terminal targets of operation step
+// outcomes, branches, sub-flow steps and parallel joins, and of failure
+// handlers, compensation steps included. Outcomes internal to parallel
+// branches only appear in the parallel step's record, never as a flow's
+// outcome, and are not listed. Use it to build a complete outcome
+// dictionary, for example for localization.
+func (b *Bundle) AllOutcomes() []string {
+	seen := make(map[string]bool)
+	add := func(t *StepTarget) {
+		if t != nil && t.IsTerminal() && t.Outcome != "" {
+			seen[t.Outcome] = true
+		}
+	}
+	addHandler := func(h *FailureHandler) {
+		if h == nil {
+			return
+		}
+		if h.Kind == "Terminate" && h.Outcome != "" {
+			seen[h.Outcome] = true
+		}
+		add(h.Then)
+		for i := range h.Steps {
+			add(&h.Steps[i].OnFailure)
+		}
+	}
+	for _, flow := range b.Flows {
+		for i := range flow.Steps {
+			step := &flow.Steps[i]
+			for _, t := range step.Outcomes {
+				add(&t)
+			}
+			add(step.IfTrue)
+			add(step.IfFalse)
+			add(step.OnSuccess)
+			addHandler(step.OnFailure)
+			if step.Join != nil {
+				add(step.Join.OnAllSuccess)
+				add(step.Join.OnAllComplete)
+				addHandler(step.Join.OnAnyFailure)
+			}
+		}
+	}
+
+	outcomes := make([]string, 0, len(seen))
+	for o := range seen {
+		outcomes = append(outcomes, o)
+	}
+	sort.Strings(outcomes)
+	return outcomes
+}
+
+// TerminalState marks an entity state as final by design, for DeadEndStates.
+type TerminalState struct {
+	EntityID string
+	State    string
+}
+
+// DeadEndStates returns, per entity ID, the declared states with no outgoing
+// transition, in declaration order, omitting those listed in terminal. Tenor
+// has no terminal-state declaration, so every final state shows up until it
+// is listed: review the result, pass the states that are final by design
+// (such as an approved order), and whatever remains is a likely authoring
+// mistake. It returns nil when no dead ends remain.
+func (b *Bundle) DeadEndStates(terminal ...TerminalState) map[string][]string {
+	final := make(map[TerminalState]bool, len(terminal))
+	for _, t := range terminal {
+		final[t] = true
+	}
+	var deadEnds map[string][]string
+	for _, ent := range b.Entities {
+		exits := make(map[string]bool, len(ent.Transitions))
+		for _, t := range ent.Transitions {
+			exits[t.From] = true
+		}
+		for _, state := range ent.States {
+			if exits[state] || final[TerminalState{EntityID: ent.ID, State: state}] {
+				continue
+			}
+			if deadEnds == nil {
+				deadEnds = make(map[string][]string)
+			}
+			deadEnds[ent.ID] = append(deadEnds[ent.ID], state)
+		}
+	}
+	return deadEnds
+}
+
+// InitiableFlows returns the sorted IDs of every flow persona can start: the
+// flow's entry step is an OperationStep run as persona, and its operation's
+// allowed_personas include persona. An entry SubFlowStep defers to the
+// sub-flow's entry. Like OperationsForPersona it is static, ignoring facts and
+// entity states; ComputeActionSpace gives the runtime view. Flows entered by
+// any other step kind have no single initiating persona and are never listed.
+func (b *Bundle) InitiableFlows(persona string) []string {
+	allowed := make(map[string]bool)
+	for _, id := range b.OperationsForPersona(persona) {
+		allowed[id] = true
+	}
+	var initiable func(flowID string, visiting map[string]bool) bool
+	initiable = func(flowID string, visiting map[string]bool) bool {
+		flow, ok := b.Flow(flowID)
+		if !ok || visiting[flowID] {
+			return false
+		}
+		visiting[flowID] = true
+		entry, ok := flow.Step(flow.Entry)
+		if !ok {
+			return false
+		}
+		switch entry.Kind {
+		case "OperationStep":
+			return entry.Persona == persona && allowed[entry.Op]
+		case "SubFlowStep":
+			return initiable(entry.Flow, visiting)
+		}
+		return false
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, flow := range b.Flows {
+		if seen[flow.ID] {
+			continue
+		}
+		seen[flow.ID] = true
+		if initiable(flow.ID, make(map[string]bool)) {
+			ids = append(ids, flow.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Source returns the source with the given ID.
+func (b *Bundle) Source(id string) (*SourceDef, bool) {
+	for i := range b.Sources {
+		if b.Sources[i].ID == id {
+			return &b.Sources[i], true
+		}
+	}
+	return nil, false
+}
+
+// Step returns the step with the given ID, searching parallel branches too.
+func (f *FlowDef) Step(id string) (*FlowStep, bool) {
+	return findStep(f.Steps, id)
+}
+
+func findStep(steps []FlowStep, id string) (*FlowStep, bool) {
+	for i := range steps {
+		if steps[i].ID == id {
+			return &steps[i], true
+		}
+		for j := range steps[i].Branches {
+			if s, ok := findStep(steps[i].Branches[j].Steps, id); ok {
+				return s, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ValidateFailurePaths lints the failure handling of flowID. It reports, as
+// *FailurePathError values, every OperationStep or SubFlowStep without an
+// on_failure handler, every handler whose Terminate or Terminal target names
+// no outcome, and every handler or compensation that continues to a step or
+// operation the contract does not declare. Steps in parallel branches and
+// join policies are checked too. It returns nil when every failure path is
+// handled.
+//
+// A step has a single on_failure handler for all of its operation's
+// error_contract codes, so an operation that declares codes is handled
+// exactly when its step has a valid handler; the codes are included in the
+// report to show what would go unhandled.
+func (b *Bundle) ValidateFailurePaths(flowID string) []error {
+	flow, ok := b.Flow(flowID)
+	if !ok {
+		return []error{fmt.Errorf("flow %q not found", flowID)}
+	}
+	v := failurePathValidator{bundle: b, flow: flow}
+	v.steps(flow.Steps)
+	return v.errs
+}
+
+type failurePathValidator struct {
+	bundle *Bundle
+	flow   *FlowDef
+	errs   []error
+}
+
+func (v *failurePathValidator) report(stepID, format string, args ...interface{}) {
+	v.errs = append(v.errs, &FailurePathError{
+		FlowID:  v.flow.ID,
+		StepID:  stepID,
+		Problem: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *failurePathValidator) steps(steps []FlowStep) {
+	for i := range steps {
+		step := &steps[i]
+		switch step.Kind {
+		case "OperationStep":
+			op, ok := v.bundle.Operation(step.Op)
+			if !ok {
+				v.report(step.ID, "operation %q is not declared", step.Op)
+			}
+			if step.OnFailure == nil {
+				if ok && len(op.ErrorContract) > 0 {
+					v.report(step.ID, "no on_failure handler for error codes %v of operation %q", op.ErrorContract, step.Op)
+				} else {
+					v.report(step.ID, "no on_failure handler")
+				}
+				continue
+			}
+			v.handler(step.ID, step.OnFailure)
+		case "SubFlowStep":
+			if step.OnFailure == nil {
+				v.report(step.ID, "no on_failure handler for sub-flow %q", step.Flow)
+				continue
+			}
+			v.handler(step.ID, step.OnFailure)
+		case "ParallelStep":
+			for j := range step.Branches {
+				v.steps(step.Branches[j].Steps)
+			}
+			if step.Join != nil && step.Join.OnAnyFailure != nil {
+				v.handler(step.ID, step.Join.OnAnyFailure)
+			}
+		}
+	}
+}
+
+func (v *failurePathValidator) handler(stepID string, h *FailureHandler) {
+	switch h.Kind {
+	case "Terminate":
+		if h.Outcome == "" {
+			v.report(stepID, "Terminate handler has no outcome")
+		}
+	case "Compensate":
+		for _, comp := range h.Steps {
+			if _, ok := v.bundle.Operation(comp.Op); !ok {
+				v.report(stepID, "compensation operation %q is not declared", comp.Op)
+			}
+			v.target(stepID, "compensation "+comp.Op+" on_failure", &comp.OnFailure)
+		}
+		if h.Then == nil {
+			v.report(stepID, "Compensate handler has no then target")
+		} else {
+			v.target(stepID, "Compensate then", h.Then)
+		}
+	case "Escalate":
+		if _, ok := v.flow.Step(h.Next); !ok {
+			v.report(stepID, "Escalate handler continues to undeclared step %q", h.Next)
+		}
+	default:
+		v.report(stepID, "unknown on_failure kind %q", h.Kind)
+	}
+}
+
+func (v *failurePathValidator) target(stepID, what string, t *StepTarget) {
+	if t.IsTerminal() {
+		if t.Outcome == "" {
+			v.report(stepID, "%s is a Terminal with no outcome", what)
+		}
+		return
+	}
+	if _, ok := v.flow.Step(t.StepID); !ok {
+		v.report(stepID, "%s continues to undeclared step %q", what, t.StepID)
+	}
+}
+
+// ValidateFlowPersonas checks that every OperationStep of flowID, including
+// those in parallel branches, and every compensation step runs as a persona
+// its operation allows. A step whose persona is not in the operation's
+// allowed_personas can never succeed, leaving the flow dead for that
+// persona. Each mismatch is reported as a *FlowPersonaError. Steps naming an
+// undeclared operation are left to ValidateFailurePaths. It returns nil when
+// every step is consistent.
+func (b *Bundle) ValidateFlowPersonas(flowID string) []error {
+	flow, ok := b.Flow(flowID)
+	if !ok {
+		return []error{fmt.Errorf("flow %q not found", flowID)}
+	}
+
+	var errs []error
+	check := func(stepID, opID, persona string) {
+		op, ok := b.Operation(opID)
+		if !ok {
+			return
+		}
+		for _, p := range op.AllowedPersonas {
+			if p == persona {
+				return
+			}
+		}
+		errs = append(errs, &FlowPersonaError{
+			FlowID:          flow.ID,
+			StepID:          stepID,
+			OperationID:     opID,
+			Persona:         persona,
+			AllowedPersonas: op.AllowedPersonas,
+		})
+	}
+	var walk func(steps []FlowStep)
+	walk = func(steps []FlowStep) {
+		for i := range steps {
+			step := &steps[i]
+			switch step.Kind {
+			case "OperationStep":
+				check(step.ID, step.Op, step.Persona)
+			case "ParallelStep":
+				for j := range step.Branches {
+					walk(step.Branches[j].Steps)
+				}
+			}
+			handlers := []*FailureHandler{step.OnFailure}
+			if step.Join != nil {
+				handlers = append(handlers, step.Join.OnAnyFailure)
+			}
+			for _, h := range handlers {
+				if h == nil {
+					continue
+				}
+				for _, comp := range h.Steps {
+					check(step.ID, comp.Op, comp.Persona)
+				}
+			}
+		}
+	}
+	walk(flow.Steps)
+	return errs
+}
+
+// ValidateFlowTransitions walks every path through flowID from its entry and
+// reports, as a *FlowTransitionError, each operation whose effect expects an
+// entity in a state other than the one an earlier step on the same path left
+// it in, such as approving an order twice. Paths are followed through each
+// branch side, failure handler (compensation effects included), handoff and
+// parallel join, and through the one outcome the evaluator gives each
+// operation, with all of its effects applied; a conflict on one path says
+// nothing about another. Sub-flows and the steps inside parallel branches are opaque,
+// and each entity is taken to have a single instance. Each conflict is
+// reported once, however many paths reach it, and each step is walked once
+// per distinct set of entity positions, so paths that branch and rejoin do
+// not multiply the work.
+func (b *Bundle) ValidateFlowTransitions(flowID string) []error {
+	flow, ok := b.Flow(flowID)
+	if !ok {
+		return []error{fmt.Errorf("flow %q not found", flowID)}
+	}
+	v := &transitionValidator{
+		bundle:   b,
+		flow:     flow,
+		reported: make(map[FlowTransitionError]bool),
+		visited:  make(map[string]bool),
+	}
+	v.walk(flow.Entry, nil)
+	return v.errs
+}
+
+// transitionValidator holds the state of one ValidateFlowTransitions walk.
+type transitionValidator struct {
+	bundle   *Bundle
+	flow     *FlowDef
+	reported map[FlowTransitionError]bool
+	errs     []error
+	// visited holds the (step, entity positions) pairs already walked; what
+	// follows a step depends on nothing else.
+	visited map[string]bool
+}
+
+// entityPosition is the state a path has left an entity in, and the step
+// that moved it there.
+type entityPosition struct {
+	state  string
+	stepID string
+}
+
+// walk follows the paths from stepID, unless it was already walked with the
+// same entity positions, which also stops loops. states holds the entity
+// positions along the current path and is not modified.
+func (v *transitionValidator) walk(stepID string, states map[string]entityPosition) {
+	key := visitKey(stepID, states)
+	if v.visited[key] {
+		return
+	}
+	v.visited[key] = true
+	step, ok := v.flow.Step(stepID)
+	if !ok {
+		return
+	}
+
+	switch step.Kind {
+	case "OperationStep":
+		if op, ok := v.bundle.Operation(step.Op); ok {
+			if outcome, ok := op.outcome(); ok {
+				if target, ok := step.Outcomes[outcome]; ok {
+					v.follow(&target, v.apply(step.ID, step.Op, states))
+				}
+			}
+		}
+		v.handler(step.ID, step.OnFailure, states)
+	case "BranchStep":
+		v.follow(step.IfTrue, states)
+		v.follow(step.IfFalse, states)
+	case "SubFlowStep":
+		v.follow(step.OnSuccess, states)
+		v.handler(step.ID, step.OnFailure, states)
+	case "HandoffStep":
+		v.walk(step.Next, states)
+	case "ParallelStep":
+		if step.Join != nil {
+			v.follow(step.Join.OnAllSuccess, states)
+			v.follow(step.Join.OnAllComplete, states)
+			v.handler(step.ID, step.Join.OnAnyFailure, states)
+		}
+	}
+}
+
+func (v *transitionValidator) follow(t *StepTarget, states map[string]entityPosition) {
+	if t != nil && !t.IsTerminal() {
+		v.walk(t.StepID, states)
+	}
+}
+
+// visitKey identifies stepID reached with the entity positions in states.
+func visitKey(stepID string, states map[string]entityPosition) string {
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var b strings.Builder
+	b.WriteString(stepID)
+	for _, id := range ids {
+		pos := states[id]
+		b.WriteString("\x00" + id + "\x00" + pos.state + "\x00" + pos.stepID)
+	}
+	return b.String()
+}
+
+// handler follows the failure handler of stepID, applying compensation
+// effects.
+func (v *transitionValidator) handler(stepID string, h *FailureHandler, states map[string]entityPosition) {
+	if h == nil {
+		return
+	}
+	switch h.Kind {
+	case "Compensate":
+		for _, comp := range h.Steps {
+			states = v.apply(stepID, comp.Op, states)
+		}
+		v.follow(h.Then, states)
+	case "Escalate":
+		v.walk(h.Next, states)
+	}
+}
+
+// apply returns states advanced by every effect of opID, reporting each effect
+// whose source state conflicts with states. Like the evaluator, it applies an
+// operation's effects whatever their outcome, checking each source state
+// before applying any.
+func (v *transitionValidator) apply(stepID, opID string, states map[string]entityPosition) map[string]entityPosition {
+	op, ok := v.bundle.Operation(opID)
+	if !ok || len(op.Effects) == 0 {
+		return states
+	}
+	next := make(map[string]entityPosition, len(states)+len(op.Effects))
+	for id, pos := range states {
+		next[id] = pos
+	}
+	for _, effect := range op.Effects {
+		if prior, ok := states[effect.EntityID]; ok && prior.state != effect.From {
+			err := FlowTransitionError{
+				FlowID:      v.flow.ID,
+				StepID:      stepID,
+				OperationID: opID,
+				EntityID:    effect.EntityID,
+				From:        effect.From,
+				To:          effect.To,
+				PriorStepID: prior.stepID,
+				PriorState:  prior.state,
+			}
+			if !v.reported[err] {
+				v.reported[err] = true
+				v.errs = append(v.errs, &err)
+			}
+		}
+		next[effect.EntityID] = entityPosition{state: effect.To, stepID: stepID}
+	}
+	return next
+}
+
+// outcome returns the outcome the evaluator gives op when it succeeds: that
+// of its first effect naming one, else its only declared outcome, else
+// "success". It reports false for an operation declaring several outcomes
+// without an effect naming one, which the evaluator fails.
+func (op *OperationDef) outcome() (string, bool) {
+	for _, effect := range op.Effects {
+		if effect.Outcome != "" {
+			return effect.Outcome, true
+		}
+	}
+	switch len(op.Outcomes) {
+	case 0:
+		return "success", true
+	case 1:
+		return op.Outcomes[0], true
+	}
+	return "", false
+}
+
+// Validate runs the bundle's static checks, ValidateFailurePaths,
+// ValidateFlowPersonas and ValidateFlowTransitions, on every flow and returns
+// everything they report, or nil if nothing is wrong.
+func (b *Bundle) Validate() []error {
+	var errs []error
+	for _, f := range b.Flows {
+		errs = append(errs, b.ValidateFailurePaths(f.ID)...)
+		errs = append(errs, b.ValidateFlowPersonas(f.ID)...)
+		errs = append(errs, b.ValidateFlowTransitions(f.ID)...)
+	}
+	return errs
+}
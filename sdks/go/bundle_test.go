@@ -0,0 +1,873 @@
+package tenor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+func TestParseBundle(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	if b.ID != "entity_operation_basic" {
+		t.Errorf("expected id 'entity_operation_basic', got %q", b.ID)
+	}
+	if len(b.Facts) != 1 || b.Facts[0].ID != "is_active" {
+		t.Errorf("expected fact is_active, got %+v", b.Facts)
+	}
+	if len(b.Rules) != 1 || b.Rules[0].Body.Produce.VerdictType != "account_active" {
+		t.Errorf("expected rule producing account_active, got %+v", b.Rules)
+	}
+
+	order, ok := b.Entity("Order")
+	if !ok {
+		t.Fatal("expected entity Order")
+	}
+	if order.Initial != "pending" {
+		t.Errorf("expected initial 'pending', got %q", order.Initial)
+	}
+
+	flow, ok := b.Flow("approval_flow")
+	if !ok {
+		t.Fatal("expected flow approval_flow")
+	}
+	step, ok := flow.Step("step_approve")
+	if !ok {
+		t.Fatal("expected step step_approve")
+	}
+	if step.Op != "approve_order" {
+		t.Errorf("expected op 'approve_order', got %q", step.Op)
+	}
+	success := step.Outcomes["success"]
+	if !success.IsTerminal() || success.Outcome != "order_approved" {
+		t.Errorf("expected terminal order_approved, got %+v", success)
+	}
+	if step.OnFailure == nil || step.OnFailure.Kind != "Terminate" || step.OnFailure.Outcome != "approval_failed" {
+		t.Errorf("expected Terminate approval_failed, got %+v", step.OnFailure)
+	}
+}
+
+func TestParseBundleRejectsNonBundle(t *testing.T) {
+	if _, err := tenor.ParseBundle([]byte(`{"kind": "Fact"}`)); err == nil {
+		t.Fatal("expected error for non-bundle JSON, got nil")
+	}
+	if _, err := tenor.ParseBundle([]byte("not json")); err == nil {
+		t.Fatal("expected error for invalid JSON, got nil")
+	}
+}
+
+func TestInitialStates(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	states := b.InitialStates()
+	if got := states["Order"][tenor.DefaultInstanceID]; got != "pending" {
+		t.Errorf("expected Order initial 'pending', got %q", got)
+	}
+}
+
+func TestParseBundleRuleMetadata(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	rule, ok := b.Rule("check_active")
+	if !ok {
+		t.Fatal("expected rule check_active")
+	}
+	if rule.Description != "" || len(rule.Tags) != 0 {
+		t.Errorf("expected empty metadata for legacy bundle, got %q %v", rule.Description, rule.Tags)
+	}
+
+	annotated := strings.Replace(basicBundle,
+		`"id": "check_active",`,
+		`"id": "check_active", "description": "Account is active", "tags": ["account", "status"],`, 1)
+	b, err = tenor.ParseBundle([]byte(annotated))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	rule, _ = b.Rule("check_active")
+	if rule.Description != "Account is active" {
+		t.Errorf("expected description 'Account is active', got %q", rule.Description)
+	}
+	if len(rule.Tags) != 2 || rule.Tags[0] != "account" || rule.Tags[1] != "status" {
+		t.Errorf("expected tags [account status], got %v", rule.Tags)
+	}
+}
+
+func TestFactDefBaseTypeAndDefault(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(`{
+  "id": "defaults",
+  "kind": "Bundle",
+  "tenor": "1.0",
+  "tenor_version": "1.1.0",
+  "constructs": [
+    {"id": "flag", "kind": "Fact", "type": {"base": "Bool"},
+     "default": {"kind": "bool_literal", "value": true},
+     "provenance": {"file": "t.tenor", "line": 1}},
+    {"id": "as_of", "kind": "Fact", "type": {"base": "Date"},
+     "provenance": {"file": "t.tenor", "line": 2}}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	flag, _ := b.Fact("flag")
+	if got := flag.BaseType(); got != "Bool" {
+		t.Errorf("expected base type Bool, got %q", got)
+	}
+	if v, ok := flag.DefaultValue(); !ok || v != true {
+		t.Errorf("expected unwrapped default true, got %v (%v)", v, ok)
+	}
+
+	asOf, _ := b.Fact("as_of")
+	if got := asOf.BaseType(); got != "Date" {
+		t.Errorf("expected base type Date, got %q", got)
+	}
+	if _, ok := asOf.DefaultValue(); ok {
+		t.Error("expected no default for as_of")
+	}
+}
+
+func TestBundleAnalysisCreatesNoRuntime(t *testing.T) {
+	count, restore := tenor.CountRuntimeCreations()
+	defer restore()
+
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	b.Validate()
+	b.ValidateReferences()
+	b.UnusedFacts()
+	b.InitiableFlows("admin")
+	if _, err := tenor.MergeBundles([]byte(basicBundle), []byte(overlayBundle)); err != nil {
+		t.Fatalf("MergeBundles failed: %v", err)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("expected no runtime before the first evaluator, got %d", n)
+	}
+
+	// Creation is attempted on first use, whether or not the binary loads.
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err == nil {
+		eval.Close()
+	}
+	if n := count(); n != 1 {
+		t.Errorf("expected one runtime creation, got %d", n)
+	}
+}
+
+func TestOperationsForPersona(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	ops := b.OperationsForPersona("admin")
+	if len(ops) != 1 || ops[0] != "approve_order" {
+		t.Errorf("expected [approve_order] for admin, got %v", ops)
+	}
+	if ops := b.OperationsForPersona("guest"); len(ops) != 0 {
+		t.Errorf("expected no operations for guest, got %v", ops)
+	}
+}
+
+func TestTransitionsForPersona(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	want := []tenor.EntityStateChange{{EntityID: "Order", FromState: "pending", ToState: "approved"}}
+	if got := b.TransitionsForPersona("admin"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v for admin, got %+v", want, got)
+	}
+	if got := b.TransitionsForPersona("guest"); len(got) != 0 {
+		t.Errorf("expected no transitions for guest, got %+v", got)
+	}
+}
+
+func TestAllOutcomes(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	want := []string{"approval_failed", "order_approved"}
+	if got := b.AllOutcomes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	b = &tenor.Bundle{Flows: []tenor.FlowDef{{ID: "f", Steps: []tenor.FlowStep{
+		{ID: "route", Kind: "BranchStep", IfTrue: &tenor.StepTarget{StepID: "sub"}, IfFalse: &tenor.StepTarget{Outcome: "rejected"}},
+		{ID: "sub", Kind: "SubFlowStep", OnSuccess: &tenor.StepTarget{Outcome: "done"},
+			OnFailure: &tenor.FailureHandler{Kind: "Compensate", Then: &tenor.StepTarget{Outcome: "rolled_back"},
+				Steps: []tenor.CompensationStep{{Op: "undo", OnFailure: tenor.StepTarget{Outcome: "stuck"}}}}},
+		{ID: "fan", Kind: "ParallelStep", Join: &tenor.JoinPolicy{OnAllSuccess: &tenor.StepTarget{Outcome: "done"}},
+			Branches: []tenor.ParallelBranch{{ID: "x", Steps: []tenor.FlowStep{{ID: "x1", Kind: "BranchStep", IfTrue: &tenor.StepTarget{Outcome: "branch_only"}}}}}},
+	}}}}
+	want = []string{"done", "rejected", "rolled_back", "stuck"}
+	if got := b.AllOutcomes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDeadEndStates(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	want := map[string][]string{"Order": {"approved"}}
+	if got := b.DeadEndStates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := b.DeadEndStates(tenor.TerminalState{EntityID: "Order", State: "approved"}); got != nil {
+		t.Errorf("expected approved to be suppressed, got %v", got)
+	}
+}
+
+func TestInitiableFlows(t *testing.T) {
+	// Add a flow entered through approval_flow as a sub-flow, and one whose
+	// entry step runs approve_order as a persona the operation does not allow.
+	extra := `,
+    {
+      "entry": "step_sub", "id": "wrapper_flow", "kind": "Flow",
+      "provenance": { "file": "test.tenor", "line": 40 }, "snapshot": "at_initiation",
+      "steps": [{
+        "id": "step_sub", "kind": "SubFlowStep", "flow": "approval_flow",
+        "on_success": { "kind": "Terminal", "outcome": "done" },
+        "on_failure": { "kind": "Terminate", "outcome": "failed" }
+      }],
+      "tenor": "1.0"
+    },
+    {
+      "entry": "step_approve", "id": "clerk_flow", "kind": "Flow",
+      "provenance": { "file": "test.tenor", "line": 50 }, "snapshot": "at_initiation",
+      "steps": [{
+        "id": "step_approve", "kind": "OperationStep", "op": "approve_order", "persona": "clerk",
+        "outcomes": { "success": { "kind": "Terminal", "outcome": "done" } },
+        "on_failure": { "kind": "Terminate", "outcome": "failed" }
+      }],
+      "tenor": "1.0"
+    }
+  ],
+  "id": "entity_operation_basic"`
+	bundle := strings.Replace(basicBundle, "\n  ],\n  \"id\": \"entity_operation_basic\"", extra, 1)
+	b, err := tenor.ParseBundle([]byte(bundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	if len(b.Flows) != 3 {
+		t.Fatalf("expected 3 flows, got %d", len(b.Flows))
+	}
+
+	if got := b.InitiableFlows("admin"); !reflect.DeepEqual(got, []string{"approval_flow", "wrapper_flow"}) {
+		t.Errorf("expected [approval_flow wrapper_flow] for admin, got %v", got)
+	}
+	for _, persona := range []string{"clerk", "guest"} {
+		if got := b.InitiableFlows(persona); len(got) != 0 {
+			t.Errorf("expected no flows for %s, got %v", persona, got)
+		}
+	}
+}
+
+func TestOperationDependencies(t *testing.T) {
+	b := &tenor.Bundle{
+		Entities: []tenor.EntityDef{{ID: "Order", Initial: "draft", States: []string{"draft", "submitted", "approved", "shipped"}}},
+		Operations: []tenor.OperationDef{
+			{ID: "submit", Effects: []tenor.Effect{{EntityID: "Order", From: "draft", To: "submitted"}}},
+			{ID: "approve", Effects: []tenor.Effect{{EntityID: "Order", From: "submitted", To: "approved"}}},
+			{ID: "ship", Effects: []tenor.Effect{{EntityID: "Order", From: "approved", To: "shipped"}}},
+			{ID: "reopen", Effects: []tenor.Effect{{EntityID: "Order", From: "approved", To: "draft"}}},
+		},
+	}
+	if got, want := b.OperationDependencies("ship"), []string{"approve", "submit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	for _, id := range []string{"submit", "missing"} {
+		if got := b.OperationDependencies(id); got == nil || len(got) != 0 {
+			t.Errorf("expected an empty slice for %s, got %#v", id, got)
+		}
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	entity, err := b.MermaidEntity("Order")
+	if err != nil {
+		t.Fatalf("MermaidEntity failed: %v", err)
+	}
+	for _, want := range []string{"stateDiagram-v2\n", "[*] --> pending\n", "pending --> approved: approve_order\n", "approved --> [*]\n"} {
+		if !strings.Contains(entity, want) {
+			t.Errorf("expected %q in:\n%s", want, entity)
+		}
+	}
+
+	flow, err := b.MermaidFlow("approval_flow")
+	if err != nil {
+		t.Fatalf("MermaidFlow failed: %v", err)
+	}
+	for _, want := range []string{
+		"flowchart TD\n",
+		`step0["step_approve: approve_order as admin"]`,
+		`outcome0(["order_approved"])`,
+		`step0 -->|"success"| outcome0`,
+		`step0 -.->|"failure"| outcome1`,
+	} {
+		if !strings.Contains(flow, want) {
+			t.Errorf("expected %q in:\n%s", want, flow)
+		}
+	}
+
+	if _, err := b.MermaidEntity("Invoice"); err == nil {
+		t.Error("expected an error for an undeclared entity")
+	}
+	if _, err := b.MermaidFlow("missing"); err == nil {
+		t.Error("expected an error for an undeclared flow")
+	}
+
+	quoted := &tenor.Bundle{Entities: []tenor.EntityDef{{ID: "Doc", Initial: "in review", States: []string{"in review", `"done"`},
+		Transitions: []tenor.Transition{{From: "in review", To: `"done"`}}}}}
+	entity, err = quoted.MermaidEntity("Doc")
+	if err != nil {
+		t.Fatalf("MermaidEntity failed: %v", err)
+	}
+	for _, want := range []string{`state "in review" as state0`, `state "#quot;done#quot;" as state1`, "state0 --> state1\n"} {
+		if !strings.Contains(entity, want) {
+			t.Errorf("expected %q in:\n%s", want, entity)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	want := tenor.Capabilities{HasFacts: true, HasRules: true, HasEntities: true, HasOperations: true, HasFlows: true}
+	if got := b.Capabilities(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	rules, err := tenor.ParseBundle([]byte(ruleOnlyBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	want = tenor.Capabilities{HasFacts: true, HasRules: true}
+	if got := rules.Capabilities(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestParseBundleSources(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(staticSourceBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	src, ok := b.Source("config")
+	if !ok || src.Protocol != "static" {
+		t.Fatalf("expected static source config, got %+v", src)
+	}
+	fact, _ := b.Fact("is_active")
+	if got := fact.SourceID(); got != "config" {
+		t.Errorf("expected source id config, got %q", got)
+	}
+
+	legacy, _ := tenor.ParseBundle([]byte(basicBundle))
+	fact, _ = legacy.Fact("is_active")
+	if got := fact.SourceID(); got != "" {
+		t.Errorf("expected no source id for a freetext binding, got %q", got)
+	}
+}
+
+func TestParseBundlePersonas(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(`{
+  "id": "personas",
+  "kind": "Bundle",
+  "tenor": "1.0",
+  "tenor_version": "1.1.0",
+  "constructs": [
+    {"id": "buyer", "kind": "Persona", "provenance": {"file": "p.tenor", "line": 1}, "tenor": "1.0"},
+    {"id": "seller", "kind": "Persona", "provenance": {"file": "p.tenor", "line": 2}, "tenor": "1.0"}
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	if len(b.Personas) != 2 || b.Personas[0].ID != "buyer" || b.Personas[1].Provenance.Line != 2 {
+		t.Errorf("unexpected personas: %+v", b.Personas)
+	}
+}
+
+func TestValidateFailurePaths(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	if errs := b.ValidateFailurePaths("approval_flow"); errs != nil {
+		t.Errorf("expected no failure path gaps, got %v", errs)
+	}
+	if errs := b.ValidateFailurePaths("missing_flow"); len(errs) != 1 {
+		t.Errorf("expected 1 error for an unknown flow, got %v", errs)
+	}
+
+	flow, _ := b.Flow("approval_flow")
+	step, _ := flow.Step("step_approve")
+
+	step.OnFailure = &tenor.FailureHandler{Kind: "Escalate", ToPersona: "admin", Next: "step_review"}
+	errs := b.ValidateFailurePaths("approval_flow")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `"step_review"`) {
+		t.Errorf("expected an undeclared step error, got %v", errs)
+	}
+
+	step.OnFailure = nil
+	errs = b.ValidateFailurePaths("approval_flow")
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error for a missing handler, got %v", errs)
+	}
+	var fpe *tenor.FailurePathError
+	if !errors.As(errs[0], &fpe) || fpe.StepID != "step_approve" {
+		t.Errorf("expected a FailurePathError for step_approve, got %v", errs[0])
+	}
+	if !strings.Contains(fpe.Problem, "precondition_failed") {
+		t.Errorf("expected the unhandled error codes in the report, got %q", fpe.Problem)
+	}
+}
+
+func TestVerdictFactDependencies(t *testing.T) {
+	rule := func(id, when, verdict string) tenor.RuleDef {
+		return tenor.RuleDef{ID: id, Body: tenor.RuleBody{
+			When:    json.RawMessage(when),
+			Produce: tenor.ProduceClause{VerdictType: verdict, Payload: json.RawMessage(`{"type":{"base":"Bool"},"value":true}`)},
+		}}
+	}
+	b := &tenor.Bundle{Rules: []tenor.RuleDef{
+		rule("active", `{"left":{"fact_ref":"is_active"},"op":"=","right":{"literal":true,"type":{"base":"Bool"}}}`, "account_active"),
+		rule("limit", `{"left":{"fact_ref":"balance"},"op":"<","right":{"fact_ref":"limit"}}`, "within_limit"),
+		rule("approve", `{"left":{"verdict_present":"account_active"},"op":"and","right":{"verdict_present":"within_limit"}}`, "approvable"),
+		rule("approve_vip", `{"left":{"fact_ref":"is_vip"},"op":"=","right":{"literal":true,"type":{"base":"Bool"}}}`, "approvable"),
+	}}
+
+	got := b.VerdictFactDependencies("approvable")
+	want := []string{"balance", "is_active", "is_vip", "limit"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := b.VerdictFactDependencies("account_active"); !reflect.DeepEqual(got, []string{"is_active"}) {
+		t.Errorf("expected [is_active], got %v", got)
+	}
+	if got := b.VerdictFactDependencies("unknown"); got != nil {
+		t.Errorf("expected nil for an unproduced verdict, got %v", got)
+	}
+}
+
+func TestVerdictSourceLocation(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	v := tenor.Verdict{Type: "account_active", Provenance: tenor.VerdictProvenance{Rule: "check_active"}}
+	file, line, ok := v.SourceLocation(b)
+	if !ok || file != "test.tenor" || line != 16 {
+		t.Errorf("expected test.tenor:16, got %s:%d (ok=%v)", file, line, ok)
+	}
+
+	v.Provenance.Rule = "no_such_rule"
+	if _, _, ok := v.SourceLocation(b); ok {
+		t.Error("expected no location for an undeclared rule")
+	}
+}
+
+func TestRuleCondition(t *testing.T) {
+	rule := func(id, when string) tenor.RuleDef {
+		return tenor.RuleDef{ID: id, Body: tenor.RuleBody{When: json.RawMessage(when)}}
+	}
+	b := &tenor.Bundle{Rules: []tenor.RuleDef{
+		rule("active", `{"left":{"fact_ref":"is_active"},"op":"=","right":{"literal":true,"type":{"base":"Bool"}}}`),
+		rule("nested", `{"left":{"left":{"fact_ref":"a"},"op":"or","right":{"op":"not","operand":{"verdict_present":"flagged"}}},`+
+			`"op":"and","right":{"left":{"fact_ref":"amount"},"op":">=","right":{"literal":{"amount":{"kind":"decimal_value","precision":10,"scale":2,"value":"0.00"},"currency":"USD"},"type":{"base":"Money"}}}}`),
+		rule("all_present", `{"quantifier":"forall","variable":"doc","variable_type":{"base":"Record"},"domain":{"fact_ref":"docs"},`+
+			`"body":{"left":{"field_ref":{"var":"doc","field":"status"}},"op":"=","right":{"literal":"valid","type":{"base":"Text"}}}}`),
+	}}
+
+	cond, err := b.RuleCondition("active")
+	if err != nil {
+		t.Fatalf("RuleCondition failed: %v", err)
+	}
+	if cond.Op != "=" || cond.Left.FactRef != "is_active" || string(cond.Right.Literal) != "true" {
+		t.Errorf("unexpected condition %+v", cond)
+	}
+
+	for id, want := range map[string]string{
+		"active":      "is_active = true",
+		"nested":      "(a ∨ ¬verdict_present(flagged)) ∧ amount >= 0.00 USD",
+		"all_present": `∀ doc ∈ docs . doc.status = "valid"`,
+	} {
+		cond, err := b.RuleCondition(id)
+		if err != nil {
+			t.Fatalf("RuleCondition(%q) failed: %v", id, err)
+		}
+		if got := cond.String(); got != want {
+			t.Errorf("RuleCondition(%q): expected %q, got %q", id, want, got)
+		}
+	}
+
+	prov := tenor.VerdictProvenance{Rule: "active"}
+	if cond, err := prov.Condition(b); err != nil || cond.String() != "is_active = true" {
+		t.Errorf("expected provenance to link to the rule condition, got %v, %v", cond, err)
+	}
+	if _, err := b.RuleCondition("missing"); err == nil {
+		t.Error("expected an error for an unknown rule")
+	}
+}
+
+func TestUnusedFacts(t *testing.T) {
+	b := &tenor.Bundle{
+		Facts: []tenor.FactDef{{ID: "legacy_score"}, {ID: "is_active"}, {ID: "balance"}, {ID: "approved_by"}, {ID: "region"}},
+		Rules: []tenor.RuleDef{{ID: "active", Body: tenor.RuleBody{
+			When:    json.RawMessage(`{"left":{"fact_ref":"is_active"},"op":"=","right":{"literal":true,"type":{"base":"Bool"}}}`),
+			Produce: tenor.ProduceClause{VerdictType: "account_active", Payload: json.RawMessage(`{"type":{"base":"Bool"},"value":true}`)},
+		}}},
+		Operations: []tenor.OperationDef{{ID: "withdraw", Precondition: json.RawMessage(`{"left":{"fact_ref":"balance"},"op":">","right":{"literal":0,"type":{"base":"Int"}}}`)}},
+		Flows: []tenor.FlowDef{{ID: "review", Steps: []tenor.FlowStep{{ID: "route", Kind: "BranchStep",
+			Condition: json.RawMessage(`{"left":{"fact_ref":"region"},"op":"=","right":{"literal":"EU","type":{"base":"Text"}}}`)}}}},
+	}
+
+	got := b.UnusedFacts()
+	want := []string{"approved_by", "legacy_score"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestShadowedVerdicts(t *testing.T) {
+	rule := func(id string, stratum int, verdict string) tenor.RuleDef {
+		return tenor.RuleDef{ID: id, Stratum: stratum, Body: tenor.RuleBody{Produce: tenor.ProduceClause{VerdictType: verdict}}}
+	}
+	b := &tenor.Bundle{Rules: []tenor.RuleDef{
+		rule("risk_late", 2, "high_risk"),
+		rule("active", 0, "account_active"),
+		rule("risk_early", 0, "high_risk"),
+		rule("vip_b", 1, "vip"),
+		rule("vip_a", 1, "vip"),
+	}}
+
+	want := []tenor.VerdictShadow{
+		{VerdictType: "high_risk", Rules: []string{"risk_early", "risk_late"}, Strata: []int{0, 2}, CrossStratum: true},
+		{VerdictType: "vip", Rules: []string{"vip_a", "vip_b"}, Strata: []int{1, 1}},
+	}
+	if got := b.ShadowedVerdicts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	basic, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	if got := basic.ShadowedVerdicts(); got != nil {
+		t.Errorf("expected no shadowed verdicts, got %+v", got)
+	}
+}
+
+func TestOperationPrecondition(t *testing.T) {
+	op := func(precondition string) tenor.OperationDef {
+		return tenor.OperationDef{
+			ID:           "approve_order",
+			Precondition: json.RawMessage(precondition),
+			Effects:      []tenor.Effect{{EntityID: "Order", From: "pending", To: "approved"}},
+		}
+	}
+	active := tenor.VerdictSummary{VerdictType: "account_active"}
+	flagged := tenor.VerdictSummary{VerdictType: "account_flagged"}
+	pending := tenor.EntityStateMap{"Order": "pending"}
+
+	simple := op(`{"verdict_present":"account_active"}`)
+	if got := simple.RequiredVerdicts(); !reflect.DeepEqual(got, []string{"account_active"}) {
+		t.Errorf("expected [account_active], got %v", got)
+	}
+	if ok, reason := simple.PreconditionSatisfied([]tenor.VerdictSummary{active}, pending); !ok {
+		t.Errorf("expected satisfied, got %+v", reason)
+	}
+	ok, reason := simple.PreconditionSatisfied(nil, pending)
+	if ok || reason.Type != tenor.ReasonPreconditionNotMet || !reflect.DeepEqual(reason.MissingVerdicts, []string{"account_active"}) {
+		t.Errorf("expected PreconditionNotMet on account_active, got %v %+v", ok, reason)
+	}
+	ok, reason = simple.PreconditionSatisfied([]tenor.VerdictSummary{active}, tenor.EntityStateMap{"Order": "approved"})
+	if ok || reason.Type != tenor.ReasonEntityNotInSourceState || reason.CurrentState != "approved" || reason.RequiredState != "pending" {
+		t.Errorf("expected EntityNotInSourceState, got %v %+v", ok, reason)
+	}
+
+	// account_active ∧ ¬account_flagged
+	compound := op(`{"left":{"verdict_present":"account_active"},"op":"and",` +
+		`"right":{"op":"not","operand":{"verdict_present":"account_flagged"}}}`)
+	if got := compound.RequiredVerdicts(); !reflect.DeepEqual(got, []string{"account_active", "account_flagged"}) {
+		t.Errorf("expected [account_active account_flagged], got %v", got)
+	}
+	if ok, reason := compound.PreconditionSatisfied([]tenor.VerdictSummary{active}, pending); !ok {
+		t.Errorf("expected satisfied without account_flagged, got %+v", reason)
+	}
+	if ok, _ := compound.PreconditionSatisfied([]tenor.VerdictSummary{active, flagged}, pending); ok {
+		t.Error("expected account_flagged to block")
+	}
+
+	// A fact comparison cannot be decided without facts, unless the other
+	// operand settles the result.
+	withFact := op(`{"left":{"verdict_present":"account_active"},"op":"and",` +
+		`"right":{"left":{"fact_ref":"amount"},"op":">","right":{"literal":0,"type":{"base":"Int"}}}}`)
+	ok, reason = withFact.PreconditionSatisfied([]tenor.VerdictSummary{active}, pending)
+	if ok || reason.Type != tenor.ReasonMissingFacts || !reflect.DeepEqual(reason.FactIDs, []string{"amount"}) {
+		t.Errorf("expected MissingFacts on amount, got %v %+v", ok, reason)
+	}
+	if ok, reason := withFact.PreconditionSatisfied(nil, pending); ok || reason.Type != tenor.ReasonPreconditionNotMet {
+		t.Errorf("expected PreconditionNotMet, got %v %+v", ok, reason)
+	}
+}
+
+func TestBundleSlice(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+
+	slice, err := b.Slice("account_active")
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	if len(slice.Facts) != 1 || slice.Facts[0].ID != "is_active" {
+		t.Errorf("expected facts [is_active], got %+v", slice.Facts)
+	}
+	if len(slice.Rules) != 1 || slice.Rules[0].ID != "check_active" {
+		t.Errorf("expected rules [check_active], got %+v", slice.Rules)
+	}
+	if len(slice.Entities)+len(slice.Operations)+len(slice.Flows)+len(slice.Personas) != 0 {
+		t.Errorf("expected only facts and rules, got %+v", slice)
+	}
+
+	data, err := json.Marshal(slice)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	reparsed, err := tenor.ParseBundle(data)
+	if err != nil {
+		t.Fatalf("sliced bundle does not parse: %v", err)
+	}
+	if reparsed.ID != b.ID || len(reparsed.Facts) != 1 || len(reparsed.Rules) != 1 || reparsed.Capabilities().HasEntities {
+		t.Errorf("sliced bundle did not round-trip: %s", data)
+	}
+
+	if _, err := b.Slice("no_such_verdict"); err == nil {
+		t.Error("expected an error slicing an unproduced verdict")
+	}
+}
+
+func TestValidateFlowPersonas(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	if errs := b.ValidateFlowPersonas("approval_flow"); errs != nil {
+		t.Errorf("expected consistent personas, got %v", errs)
+	}
+	if errs := b.Validate(); errs != nil {
+		t.Errorf("expected a valid bundle, got %v", errs)
+	}
+
+	flow, _ := b.Flow("approval_flow")
+	step, _ := flow.Step("step_approve")
+	step.Persona = "guest"
+
+	errs := b.ValidateFlowPersonas("approval_flow")
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	var fpe *tenor.FlowPersonaError
+	if !errors.As(errs[0], &fpe) || fpe.StepID != "step_approve" || fpe.OperationID != "approve_order" || fpe.Persona != "guest" {
+		t.Errorf("expected a FlowPersonaError for step_approve, got %v", errs[0])
+	}
+	if errs := b.Validate(); len(errs) != 1 {
+		t.Errorf("expected Validate to report the persona error, got %v", errs)
+	}
+}
+
+func TestValidateFlowTransitions(t *testing.T) {
+	opStep := func(id, op string, next tenor.StepTarget) tenor.FlowStep {
+		return tenor.FlowStep{ID: id, Kind: "OperationStep", Op: op, Persona: "clerk",
+			Outcomes:  map[string]tenor.StepTarget{"success": next},
+			OnFailure: &tenor.FailureHandler{Kind: "Terminate", Outcome: "failed"}}
+	}
+	b := &tenor.Bundle{
+		Operations: []tenor.OperationDef{
+			{ID: "approve", AllowedPersonas: []string{"clerk"}, Effects: []tenor.Effect{{EntityID: "Order", From: "pending", To: "approved"}}},
+			{ID: "ship", AllowedPersonas: []string{"clerk"}, Effects: []tenor.Effect{{EntityID: "Order", From: "approved", To: "shipped"}}},
+		},
+		Flows: []tenor.FlowDef{{ID: "fulfil", Entry: "approve_1", Steps: []tenor.FlowStep{
+			opStep("approve_1", "approve", tenor.StepTarget{StepID: "route"}),
+			{ID: "route", Kind: "BranchStep", IfTrue: &tenor.StepTarget{StepID: "approve_2"}, IfFalse: &tenor.StepTarget{StepID: "ship"}},
+			opStep("approve_2", "approve", tenor.StepTarget{StepID: "ship"}),
+			opStep("ship", "ship", tenor.StepTarget{Outcome: "done"}),
+		}}},
+	}
+
+	// Only the true side approves twice; ship follows an approval on both.
+	errs := b.ValidateFlowTransitions("fulfil")
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	var fte *tenor.FlowTransitionError
+	if !errors.As(errs[0], &fte) || fte.StepID != "approve_2" || fte.PriorStepID != "approve_1" || fte.From != "pending" || fte.PriorState != "approved" {
+		t.Errorf("expected approve_2 to conflict with approve_1, got %v", errs[0])
+	}
+	if errs := b.Validate(); len(errs) != 1 {
+		t.Errorf("expected Validate to report the conflict, got %v", errs)
+	}
+
+	basic, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	if errs := basic.ValidateFlowTransitions("approval_flow"); errs != nil {
+		t.Errorf("expected no conflicts, got %v", errs)
+	}
+}
+
+func TestValidateFlowTransitionsOutcomeKeyedEffects(t *testing.T) {
+	// Like the evaluator, the validator applies both of review's effects,
+	// leaving Order rejected, and routes on the first effect's outcome, so
+	// only ship is reached, and from the wrong state.
+	b := &tenor.Bundle{
+		Operations: []tenor.OperationDef{
+			{ID: "review", AllowedPersonas: []string{"clerk"}, Outcomes: []string{"approved", "rejected"}, Effects: []tenor.Effect{
+				{EntityID: "Order", From: "pending", To: "approved", Outcome: "approved"},
+				{EntityID: "Order", From: "pending", To: "rejected", Outcome: "rejected"},
+			}},
+			{ID: "ship", AllowedPersonas: []string{"clerk"}, Effects: []tenor.Effect{{EntityID: "Order", From: "approved", To: "shipped"}}},
+			{ID: "archive", AllowedPersonas: []string{"clerk"}, Effects: []tenor.Effect{{EntityID: "Order", From: "approved", To: "archived"}}},
+		},
+		Flows: []tenor.FlowDef{{ID: "review_flow", Entry: "review", Steps: []tenor.FlowStep{
+			{ID: "review", Kind: "OperationStep", Op: "review", Persona: "clerk", Outcomes: map[string]tenor.StepTarget{
+				"approved": {StepID: "ship"},
+				"rejected": {StepID: "archive"},
+			}},
+			{ID: "ship", Kind: "OperationStep", Op: "ship", Persona: "clerk", Outcomes: map[string]tenor.StepTarget{"success": {Outcome: "shipped"}}},
+			{ID: "archive", Kind: "OperationStep", Op: "archive", Persona: "clerk", Outcomes: map[string]tenor.StepTarget{"success": {Outcome: "archived"}}},
+		}}},
+	}
+
+	errs := b.ValidateFlowTransitions("review_flow")
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	var fte *tenor.FlowTransitionError
+	if !errors.As(errs[0], &fte) || fte.StepID != "ship" || fte.PriorStepID != "review" || fte.PriorState != "rejected" {
+		t.Errorf("expected ship to find Order rejected by review, got %v", errs[0])
+	}
+}
+
+func TestValidateFlowTransitionsRejoiningBranches(t *testing.T) {
+	// 60 branch steps whose sides rejoin: 2^60 paths, but one set of entity
+	// positions per step.
+	const n = 60
+	steps := []tenor.FlowStep{{ID: "approve", Kind: "OperationStep", Op: "approve", Persona: "clerk",
+		Outcomes: map[string]tenor.StepTarget{"success": {StepID: "b0"}}}}
+	for i := 0; i < n; i++ {
+		next := tenor.StepTarget{StepID: fmt.Sprintf("b%d", i+1)}
+		if i == n-1 {
+			next = tenor.StepTarget{StepID: "approve_again"}
+		}
+		steps = append(steps, tenor.FlowStep{ID: fmt.Sprintf("b%d", i), Kind: "BranchStep", IfTrue: &next, IfFalse: &next})
+	}
+	steps = append(steps, tenor.FlowStep{ID: "approve_again", Kind: "OperationStep", Op: "approve", Persona: "clerk",
+		Outcomes: map[string]tenor.StepTarget{"success": {Outcome: "done"}}})
+	b := &tenor.Bundle{
+		Operations: []tenor.OperationDef{{ID: "approve", AllowedPersonas: []string{"clerk"}, Effects: []tenor.Effect{{EntityID: "Order", From: "pending", To: "approved"}}}},
+		Flows:      []tenor.FlowDef{{ID: "chain", Entry: "approve", Steps: steps}},
+	}
+
+	done := make(chan []error, 1)
+	go func() { done <- b.ValidateFlowTransitions("chain") }()
+	select {
+	case errs := <-done:
+		if len(errs) != 1 {
+			t.Errorf("expected the second approval to be reported once, got %v", errs)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("ValidateFlowTransitions did not finish on rejoining branches")
+	}
+}
+
+// overlayBundle is a tenant overlay for basicBundle: a rule producing a new
+// verdict from the base contract's fact and verdict.
+const overlayBundle = `{
+  "constructs": [
+    {
+      "body": {
+        "produce": {
+          "payload": { "type": { "base": "Bool" }, "value": true },
+          "verdict_type": "tenant_eligible"
+        },
+        "when": {
+          "left": { "left": { "fact_ref": "is_active" }, "op": "=", "right": { "literal": true, "type": { "base": "Bool" } } },
+          "op": "and",
+          "right": { "verdict_present": "account_active" }
+        }
+      },
+      "id": "check_tenant",
+      "kind": "Rule",
+      "provenance": { "file": "tenant.tenor", "line": 1 },
+      "stratum": 1,
+      "tenor": "1.0"
+    }
+  ],
+  "id": "tenant_overlay",
+  "kind": "Bundle",
+  "tenor": "1.0",
+  "tenor_version": "1.0.0"
+}`
+
+func TestMergeBundles(t *testing.T) {
+	merged, err := tenor.MergeBundles([]byte(basicBundle), []byte(overlayBundle))
+	if err != nil {
+		t.Fatalf("MergeBundles failed: %v", err)
+	}
+	b, err := tenor.ParseBundle(merged)
+	if err != nil {
+		t.Fatalf("merged bundle does not parse: %v", err)
+	}
+	if b.ID != "entity_operation_basic" || len(b.Rules) != 2 || len(b.Flows) != 1 {
+		t.Errorf("unexpected merged bundle: id %q, %d rules, %d flows", b.ID, len(b.Rules), len(b.Flows))
+	}
+	if _, ok := b.Rule("check_tenant"); !ok {
+		t.Error("expected the overlay rule in the merged bundle")
+	}
+
+	_, err = tenor.MergeBundles([]byte(basicBundle), []byte(overlayBundle), []byte(overlayBundle))
+	var dup *tenor.DuplicateConstructError
+	if !errors.Is(err, tenor.ErrDuplicateConstruct) || !errors.As(err, &dup) || dup.ID != "check_tenant" || dup.First != 1 || dup.Second != 2 {
+		t.Errorf("expected a duplicate check_tenant between bundles 1 and 2, got %v", err)
+	}
+
+	// Without the base, the overlay's references dangle.
+	_, err = tenor.MergeBundles([]byte(ruleOnlyBundle), []byte(strings.Replace(overlayBundle, "account_active", "account_closed", 1)))
+	var ref *tenor.ReferenceError
+	if !errors.As(err, &ref) || ref.ID != "check_tenant" || !strings.Contains(ref.Ref, "account_closed") {
+		t.Errorf("expected a dangling verdict reference, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package tenor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// CanonicalVerdictSetJSON serializes vs in the canonical form of the Rust
+// evaluator, so that a verdict set hashed or signed in Go can be verified
+// against the same bytes produced in Rust.
+//
+// The canonical form is the Rust evaluator's verdict set JSON with:
+//
+//   - object keys sorted lexicographically at every level;
+//   - no insignificant whitespace;
+//   - integers written in plain decimal notation (Decimal and Money amounts
+//     are strings in the payload, so no fractional numbers occur);
+//   - strings escaped as serde_json escapes them: only '"', '\\' and control
+//     characters, with no HTML escaping and non-ASCII text written as UTF-8;
+//   - only the fields the Rust evaluator emits. SDK-side additions such as
+//     VerdictProvenance.RuleDescription, FactSources, File and Line are
+//     omitted, and nil FactsUsed or VerdictsUsed are written as [].
+//
+// Verdicts keep the order they have in vs, which for a set returned by
+// Evaluate is the evaluator's order.
+func CanonicalVerdictSetJSON(vs *VerdictSet) ([]byte, error) {
+	verdicts := make([]interface{}, 0, len(vs.Verdicts))
+	for i, v := range vs.Verdicts {
+		payload, err := canonicalValue(v.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("verdicts[%d].payload: %w", i, err)
+		}
+		verdicts = append(verdicts, map[string]interface{}{
+			"type":    v.Type,
+			"payload": payload,
+			"provenance": map[string]interface{}{
+				"rule":          v.Provenance.Rule,
+				"stratum":       json.Number(strconv.Itoa(v.Provenance.Stratum)),
+				"facts_used":    stringList(v.Provenance.FactsUsed),
+				"verdicts_used": stringList(v.Provenance.VerdictsUsed),
+			},
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, map[string]interface{}{"verdicts": verdicts}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CanonicalJSON serializes v in the same canonical form as
+// CanonicalVerdictSetJSON: v's JSON encoding with object keys sorted at every
+// level, no insignificant whitespace, and serde_json string escaping. Numbers
+// must be integral; pass Decimal values as strings. Other languages reproduce
+// the form by sorting keys and encoding compactly without ASCII or HTML
+// escaping (in Python, json.dumps(v, sort_keys=True, separators=(",", ":"),
+// ensure_ascii=False)).
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	value, err := canonicalValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalValue round-trips v through JSON so that any Go value reduces to
+// maps, slices, strings, bools, nil and json.Number.
+func canonicalValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func stringList(s []string) []interface{} {
+	out := make([]interface{}, len(s))
+	for i, v := range s {
+		out[i] = v
+	}
+	return out
+}
+
+// writeCanonical writes v, which must hold only values produced by
+// canonicalValue, with sorted keys and no whitespace.
+func writeCanonical(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		n, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(n)
+	case string:
+		writeCanonicalString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, k)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported canonical value of type %T", v)
+	}
+	return nil
+}
+
+// canonicalNumber renders a number as serde_json renders an i64. Values
+// decoded as float64 elsewhere in the SDK (e.g. 1e+06) are accepted as long
+// as they are integral.
+func canonicalNumber(n json.Number) (string, error) {
+	if i, err := n.Int64(); err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+	f, err := n.Float64()
+	if err != nil || f != float64(int64(f)) {
+		return "", fmt.Errorf("number %s has no canonical integer form", n)
+	}
+	return strconv.FormatInt(int64(f), 10), nil
+}
+
+// writeCanonicalString escapes s as serde_json does.
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,157 @@
+package tenor_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+// TestCanonicalVerdictSetJSONMatchesFixtures checks byte equality against the
+// Rust-generated conformance fixtures. The fixtures are pretty-printed with
+// sorted keys, so compacting them yields the Rust canonical form.
+func TestCanonicalVerdictSetJSONMatchesFixtures(t *testing.T) {
+	for _, name := range []string{"expected-verdicts.json", "expected-verdicts-inactive.json"} {
+		t.Run(name, func(t *testing.T) {
+			fixture, err := os.ReadFile("../conformance/fixtures/" + name)
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+
+			var vs tenor.VerdictSet
+			if err := json.Unmarshal(fixture, &vs); err != nil {
+				t.Fatalf("failed to parse fixture: %v", err)
+			}
+			// SDK-side metadata must not leak into canonical output.
+			for i := range vs.Verdicts {
+				vs.Verdicts[i].Provenance.RuleDescription = "described"
+			}
+
+			got, err := tenor.CanonicalVerdictSetJSON(&vs)
+			if err != nil {
+				t.Fatalf("CanonicalVerdictSetJSON failed: %v", err)
+			}
+
+			var want bytes.Buffer
+			if err := json.Compact(&want, fixture); err != nil {
+				t.Fatalf("failed to compact fixture: %v", err)
+			}
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("canonical output differs from Rust fixture\n got: %s\nwant: %s", got, want.Bytes())
+			}
+		})
+	}
+}
+
+func TestCanonicalVerdictSetJSONEscaping(t *testing.T) {
+	vs := &tenor.VerdictSet{Verdicts: []tenor.Verdict{{
+		Type:    "note",
+		Payload: map[string]interface{}{"kind": "text_value", "value": "a<b & \"c\"\né"},
+		Provenance: tenor.VerdictProvenance{
+			Rule:    "r",
+			Stratum: 2,
+		},
+	}}}
+
+	got, err := tenor.CanonicalVerdictSetJSON(vs)
+	if err != nil {
+		t.Fatalf("CanonicalVerdictSetJSON failed: %v", err)
+	}
+	want := `{"verdicts":[{"payload":{"kind":"text_value","value":"a<b & \"c\"\n` + "é" +
+		`"},"provenance":{"facts_used":[],"rule":"r","stratum":2,"verdicts_used":[]},"type":"note"}]}`
+	if string(got) != want {
+		t.Errorf("unexpected canonical output\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	got, err := tenor.CanonicalJSON(map[string]interface{}{
+		"b": []int{2, 1},
+		"a": map[string]interface{}{"y": "<&>", "x": 1e6},
+	})
+	if err != nil {
+		t.Fatalf("CanonicalJSON failed: %v", err)
+	}
+	if want := `{"a":{"x":1000000,"y":"<&>"},"b":[2,1]}`; string(got) != want {
+		t.Errorf("unexpected canonical output\n got: %s\nwant: %s", got, want)
+	}
+
+	if _, err := tenor.CanonicalJSON(map[string]float64{"x": 1.5}); err == nil {
+		t.Error("expected an error for a non-integral number")
+	}
+}
+
+// TestFactSetPlainNumbers checks that FactSet writes numbers the way the Rust
+// evaluator reads them, using the large-Int evaluator conformance fixture.
+func TestFactSetPlainNumbers(t *testing.T) {
+	fixture, err := os.ReadFile("../../conformance/eval/numeric/int_large.facts.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	var want bytes.Buffer
+	if err := json.Compact(&want, fixture); err != nil {
+		t.Fatalf("failed to compact fixture: %v", err)
+	}
+	got, err := json.Marshal(tenor.FactSet{"ledger_balance": json.Number("9.007199254740993e15")})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("expected %s, got %s", want.Bytes(), got)
+	}
+
+	for _, tc := range []struct {
+		value interface{}
+		want  string
+	}{
+		{1e21, "1000000000000000000000"},
+		{-2.5e22, "-25000000000000000000000"},
+		{1e-7, "0.0000001"},
+		{float32(1e21), "1000000000000000000000"},
+		{json.Number("5e3"), "5000"},
+		{json.Number("1.50E+2"), "150"},
+		{json.Number("-12.5e-3"), "-0.0125"},
+		{json.Number("0e10"), "0"},
+		{json.Number("42"), "42"},
+		{[]interface{}{1e21, map[string]interface{}{"n": json.Number("2e2")}}, `[1000000000000000000000,{"n":200}]`},
+		{"1e21", `"1e21"`},
+	} {
+		got, err := json.Marshal(tenor.FactSet{"x": tc.value})
+		if err != nil {
+			t.Errorf("%v: Marshal failed: %v", tc.value, err)
+			continue
+		}
+		if want := `{"x":` + tc.want + `}`; string(got) != want {
+			t.Errorf("%v: expected %s, got %s", tc.value, want, got)
+		}
+	}
+}
+
+func TestVerdictSetWriteCSV(t *testing.T) {
+	vs := &tenor.VerdictSet{Verdicts: []tenor.Verdict{
+		{
+			Type:       "account_active",
+			Payload:    map[string]interface{}{"type": map[string]interface{}{"base": "Bool"}, "value": true},
+			Provenance: tenor.VerdictProvenance{Rule: "check_active", Stratum: 0, FactsUsed: []string{"is_active"}},
+		},
+		{
+			Type:       "needs_review",
+			Payload:    "flagged, pending \"manual\" check & more",
+			Provenance: tenor.VerdictProvenance{Rule: "review", Stratum: 1, FactsUsed: []string{"balance", "region"}},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := vs.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	want := `verdict_type,stratum,rule,facts_used,payload
+account_active,0,check_active,is_active,"{""type"":{""base"":""Bool""},""value"":true}"
+needs_review,1,review,balance;region,"""flagged, pending \""manual\"" check & more"""
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected CSV:\n%s\nwant:\n%s", got, want)
+	}
+}
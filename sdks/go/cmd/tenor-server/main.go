@@ -0,0 +1,86 @@
+// Command tenor-server hosts Tenor contract evaluators for callers in other
+// languages. It speaks JSON-RPC 2.0 over stdio, so a Python or Node process
+// can embed Tenor by running it as a subprocess.
+//
+// # Framing
+//
+// Each request is one JSON object on a single line of stdin, terminated by
+// "\n". Each response is one JSON object on a single line of stdout. Nothing
+// else is written to stdout; diagnostics go to stderr. Blank lines are
+// ignored.
+//
+// Requests are handled concurrently, so responses may be written in a
+// different order than the requests arrived; match them by id. Calls for the
+// same contract are serialised by its evaluator, while calls for different
+// contracts run in parallel. At most 64 requests are handled at once; while
+// that many are in flight the server stops reading stdin, so a client that
+// pipelines faster than requests complete is held back. A request without
+// an "id" member is a notification: it is executed but no response is
+// written.
+//
+// Numbers in params are decoded exactly, so Int facts beyond 2^53 reach the
+// evaluator unrounded.
+//
+// When stdin is closed the server waits for in-flight requests to be
+// answered, closes every loaded contract, and exits with status 0.
+//
+// # Methods
+//
+// Every method takes named params (a JSON object). Results use the JSON
+// encoding of the Go SDK types named below, whose field names match the
+// Rust evaluator's output.
+//
+//	load
+//	  params: {"bundle": <interchange bundle object>}
+//	  result: {"contract_id": string}
+//	  Loads the bundle and registers it under its top-level "id". Loading an
+//	  id that is already registered fails with -32002; unload it first.
+//
+//	unload
+//	  params: {"contract_id": string}
+//	  result: {"contract_id": string}
+//
+//	evaluate
+//	  params: {"contract_id": string, "facts": {fact_id: value}}
+//	  result: tenor.VerdictSet, {"verdicts": [...]}
+//
+//	actionSpace
+//	  params: {"contract_id": string, "facts": {...},
+//	           "entity_states": <states>, "persona": string}
+//	  result: tenor.ActionSpace
+//
+//	simulateFlow
+//	  params: {"contract_id": string, "flow_id": string, "facts": {...},
+//	           "entity_states": <states>, "persona": string,
+//	           "instance_bindings": {entity_id: instance_id}}
+//	  result: tenor.FlowResult
+//
+// <states> is either flat, {entity_id: state}, or nested,
+// {entity_id: {instance_id: state}}, and may be omitted. "instance_bindings"
+// is optional and requires nested states.
+//
+// # Errors
+//
+// Failures are reported as JSON-RPC error objects with these codes:
+//
+//	-32700  parse error: the line is not valid JSON
+//	-32600  invalid request: not a JSON-RPC 2.0 request object
+//	-32601  method not found
+//	-32602  invalid params
+//	-32000  contract error: loading or evaluation failed; see message
+//	-32001  unknown contract_id
+//	-32002  contract already loaded
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	s := newServer()
+	if err := s.serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "tenor-server: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+// JSON-RPC error codes; see the package documentation.
+const (
+	codeParseError      = -32700
+	codeInvalidRequest  = -32600
+	codeMethodNotFound  = -32601
+	codeInvalidParams   = -32602
+	codeContractError   = -32000
+	codeUnknownContract = -32001
+	codeAlreadyLoaded   = -32002
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func errorf(code int, format string, args ...interface{}) *rpcError {
+	return &rpcError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// maxInFlight bounds the requests handled at once. Reading stops while that
+// many are in flight, so a client pipelining requests faster than they
+// complete is slowed down instead of growing the server without bound.
+const maxInFlight = 64
+
+// server is the evaluator registry behind the stdio protocol.
+type server struct {
+	mu        sync.RWMutex
+	contracts map[string]*tenor.Evaluator
+
+	outMu sync.Mutex
+	// inFlight holds a token per request being handled.
+	inFlight chan struct{}
+}
+
+func newServer() *server {
+	return &server{
+		contracts: make(map[string]*tenor.Evaluator),
+		inFlight:  make(chan struct{}, maxInFlight),
+	}
+}
+
+// serve reads requests from r until EOF, answering each on w from its own
+// goroutine, at most maxInFlight at a time, then closes every loaded
+// contract.
+func (s *server) serve(r io.Reader, w io.Writer) error {
+	var wg sync.WaitGroup
+	defer s.closeAll()
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			s.inFlight <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-s.inFlight }()
+				if resp := s.handle(line); resp != nil {
+					s.write(w, resp)
+				}
+			}()
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			wg.Wait()
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *server) write(w io.Writer, resp *response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(&response{
+			JSONRPC: "2.0",
+			ID:      resp.ID,
+			Error:   errorf(codeContractError, "failed to encode result: %v", err),
+		})
+	}
+
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		fmt.Fprintf(os.Stderr, "tenor-server: failed to write response: %v\n", err)
+	}
+}
+
+// handle answers one request line. It returns nil for notifications.
+func (s *server) handle(line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: errorf(codeParseError, "parse error: %v", err)}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		id := req.ID
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		return &response{JSONRPC: "2.0", ID: id, Error: errorf(codeInvalidRequest, "invalid request: expected a JSON-RPC 2.0 request with a method")}
+	}
+
+	result, err := s.dispatch(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rerr *rpcError
+		if !errors.As(err, &rerr) {
+			rerr = errorf(codeContractError, "%v", err)
+		}
+		resp.Result = nil
+		resp.Error = rerr
+	}
+	return resp
+}
+
+func (s *server) dispatch(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "load":
+		return s.load(params)
+	case "unload":
+		return s.unload(params)
+	case "evaluate":
+		return s.evaluate(params)
+	case "actionSpace":
+		return s.actionSpace(params)
+	case "simulateFlow":
+		return s.simulateFlow(params)
+	default:
+		return nil, errorf(codeMethodNotFound, "method %q not found", method)
+	}
+}
+
+type contractResult struct {
+	ContractID string `json:"contract_id"`
+}
+
+func (s *server) load(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Bundle json.RawMessage `json:"bundle"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Bundle) == 0 {
+		return nil, errorf(codeInvalidParams, "invalid params: bundle is required")
+	}
+
+	eval, err := tenor.NewEvaluatorFromBundle(p.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	id := eval.ContractID()
+	if id == "" {
+		eval.Close()
+		return nil, errorf(codeInvalidParams, "invalid params: bundle has no id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.contracts[id]; ok {
+		eval.Close()
+		return nil, errorf(codeAlreadyLoaded, "contract %q is already loaded", id)
+	}
+	s.contracts[id] = eval
+	return contractResult{ContractID: id}, nil
+}
+
+func (s *server) unload(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ContractID string `json:"contract_id"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	eval, ok := s.contracts[p.ContractID]
+	delete(s.contracts, p.ContractID)
+	s.mu.Unlock()
+	if !ok {
+		return nil, errorf(codeUnknownContract, "contract %q is not loaded", p.ContractID)
+	}
+	// Close waits for any call already running on this evaluator.
+	if err := eval.Close(); err != nil {
+		return nil, err
+	}
+	return contractResult{ContractID: p.ContractID}, nil
+}
+
+func (s *server) evaluate(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ContractID string        `json:"contract_id"`
+		Facts      tenor.FactSet `json:"facts"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	eval, err := s.contract(p.ContractID)
+	if err != nil {
+		return nil, err
+	}
+	return eval.Evaluate(p.Facts)
+}
+
+func (s *server) actionSpace(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ContractID   string          `json:"contract_id"`
+		Facts        tenor.FactSet   `json:"facts"`
+		EntityStates json.RawMessage `json:"entity_states"`
+		Persona      string          `json:"persona"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	flat, nested, err := decodeStates(p.EntityStates)
+	if err != nil {
+		return nil, err
+	}
+	eval, err := s.contract(p.ContractID)
+	if err != nil {
+		return nil, err
+	}
+	if nested != nil {
+		return eval.ComputeActionSpaceNested(p.Facts, nested, p.Persona)
+	}
+	return eval.ComputeActionSpace(p.Facts, flat, p.Persona)
+}
+
+func (s *server) simulateFlow(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ContractID       string                 `json:"contract_id"`
+		FlowID           string                 `json:"flow_id"`
+		Facts            tenor.FactSet          `json:"facts"`
+		EntityStates     json.RawMessage        `json:"entity_states"`
+		Persona          string                 `json:"persona"`
+		InstanceBindings tenor.InstanceBindings `json:"instance_bindings"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	flat, nested, err := decodeStates(p.EntityStates)
+	if err != nil {
+		return nil, err
+	}
+	if p.InstanceBindings != nil && nested == nil && len(flat) > 0 {
+		return nil, errorf(codeInvalidParams, "invalid params: instance_bindings requires nested entity_states")
+	}
+	eval, err := s.contract(p.ContractID)
+	if err != nil {
+		return nil, err
+	}
+	if p.InstanceBindings == nil {
+		if nested != nil {
+			return eval.ExecuteFlowWithBindings(p.FlowID, p.Facts, nested, p.Persona, tenor.InstanceBindings{})
+		}
+		return eval.ExecuteFlow(p.FlowID, p.Facts, flat, p.Persona)
+	}
+	if nested == nil {
+		nested = tenor.EntityStateMapNested{}
+	}
+	return eval.ExecuteFlowWithBindings(p.FlowID, p.Facts, nested, p.Persona, p.InstanceBindings)
+}
+
+func (s *server) contract(id string) (*tenor.Evaluator, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	eval, ok := s.contracts[id]
+	if !ok {
+		return nil, errorf(codeUnknownContract, "contract %q is not loaded", id)
+	}
+	return eval, nil
+}
+
+func (s *server) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, eval := range s.contracts {
+		eval.Close()
+		delete(s.contracts, id)
+	}
+}
+
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return errorf(codeInvalidParams, "invalid params: params object is required")
+	}
+	// Numbers stay json.Number, as in EvaluateJSON: float64 would silently
+	// round Int facts beyond 2^53.
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return errorf(codeInvalidParams, "invalid params: %v", err)
+	}
+	return nil
+}
+
+// decodeStates accepts flat or nested entity states; exactly one of the
+// returned maps is non-nil unless raw is empty or null.
+func decodeStates(raw json.RawMessage) (tenor.EntityStateMap, tenor.EntityStateMapNested, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil, nil
+	}
+	var flat tenor.EntityStateMap
+	if err := json.Unmarshal(raw, &flat); err == nil {
+		return flat, nil, nil
+	}
+	var nested tenor.EntityStateMapNested
+	if err := json.Unmarshal(raw, &nested); err != nil {
+		return nil, nil, errorf(codeInvalidParams,
+			"invalid params: entity_states must be {entity_id: state} or {entity_id: {instance_id: state}}")
+	}
+	return nil, nested, nil
+}
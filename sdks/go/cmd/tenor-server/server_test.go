@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+// run feeds input to a fresh server and returns its responses keyed by id.
+func run(t *testing.T, input string) map[string]response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := newServer().serve(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	responses := make(map[string]response)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp struct {
+			response
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("response is not JSON: %q", line)
+		}
+		resp.response.Result = resp.Result
+		responses[string(resp.ID)] = resp.response
+	}
+	return responses
+}
+
+func TestServerProtocolErrors(t *testing.T) {
+	responses := run(t, strings.Join([]string{
+		`not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"nope","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"evaluate","params":{"contract_id":"missing","facts":{}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"evaluate"}`,
+		`{"id":4,"method":"evaluate","params":{}}`,
+		`{"jsonrpc":"2.0","method":"evaluate","params":{"contract_id":"missing"}}`,
+		``,
+	}, "\n"))
+
+	want := map[string]int{
+		"null": codeParseError,
+		"1":    codeMethodNotFound,
+		"2":    codeUnknownContract,
+		"3":    codeInvalidParams,
+		"4":    codeInvalidRequest,
+	}
+	if len(responses) != len(want) {
+		t.Errorf("expected %d responses (none for the notification), got %d: %+v", len(want), len(responses), responses)
+	}
+	for id, code := range want {
+		resp, ok := responses[id]
+		if !ok {
+			t.Errorf("no response for id %s", id)
+			continue
+		}
+		if resp.Error == nil || resp.Error.Code != code {
+			t.Errorf("id %s: expected error code %d, got %+v", id, code, resp.Error)
+		}
+	}
+}
+
+func TestServerLoadAndEvaluate(t *testing.T) {
+	bundle, err := os.ReadFile("../../../conformance/fixtures/escrow-bundle.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, bundle); err != nil {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+	load := []byte(`{"jsonrpc":"2.0","id":1,"method":"load","params":{"bundle":` + compact.String() + `}}`)
+
+	// serve answers concurrently, so drive handle directly to order the calls.
+	s := newServer()
+	defer s.closeAll()
+	if resp := s.handle(load); resp.Error != nil {
+		t.Fatalf("load failed: %v", resp.Error)
+	}
+	if resp := s.handle(load); resp.Error == nil || resp.Error.Code != codeAlreadyLoaded {
+		t.Errorf("expected a second load to fail with %d, got %+v", codeAlreadyLoaded, resp.Error)
+	}
+
+	resp := s.handle([]byte(`{"jsonrpc":"2.0","id":2,"method":"evaluate","params":{"contract_id":"entity_operation_basic","facts":{"is_active":true}}}`))
+	if resp.Error != nil {
+		t.Fatalf("evaluate failed: %v", resp.Error)
+	}
+	data, _ := json.Marshal(resp.Result)
+	if !strings.Contains(string(data), `"account_active"`) {
+		t.Errorf("expected account_active verdict, got %s", data)
+	}
+
+	resp = s.handle([]byte(`{"jsonrpc":"2.0","id":3,"method":"simulateFlow","params":{"contract_id":"entity_operation_basic","flow_id":"approval_flow","facts":{"is_active":true},"entity_states":{"Order":"pending"},"persona":"admin"}}`))
+	if resp.Error != nil {
+		t.Fatalf("simulateFlow failed: %v", resp.Error)
+	}
+	data, _ = json.Marshal(resp.Result)
+	if !strings.Contains(string(data), `"order_approved"`) {
+		t.Errorf("expected order_approved outcome, got %s", data)
+	}
+}
+
+func TestDecodeParamsKeepsLargeInts(t *testing.T) {
+	var p struct {
+		Facts tenor.FactSet `json:"facts"`
+	}
+	if err := decodeParams(json.RawMessage(`{"facts":{"amount":9007199254740993}}`), &p); err != nil {
+		t.Fatalf("decodeParams failed: %v", err)
+	}
+	if n, ok := p.Facts["amount"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("expected the exact json.Number 9007199254740993, got %#v", p.Facts["amount"])
+	}
+}
+
+// TestServerConcurrentContracts interleaves calls on two contracts through
+// serve; run it with -race.
+func TestServerConcurrentContracts(t *testing.T) {
+	fixture, err := os.ReadFile("../../../conformance/fixtures/escrow-bundle.json")
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, fixture); err != nil {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+	bundles := map[string]string{
+		"entity_operation_basic": compact.String(),
+		"entity_operation_copy":  strings.Replace(compact.String(), `"id":"entity_operation_basic"`, `"id":"entity_operation_copy"`, 1),
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := newServer().serve(inR, outW)
+		outW.Close()
+		done <- err
+	}()
+	responses := bufio.NewScanner(outR)
+	responses.Buffer(nil, 1<<20)
+	next := func() response {
+		t.Helper()
+		if !responses.Scan() {
+			t.Fatalf("server stopped answering: %v", responses.Err())
+		}
+		var resp response
+		if err := json.Unmarshal(responses.Bytes(), &resp); err != nil {
+			t.Fatalf("response is not JSON: %q", responses.Text())
+		}
+		return resp
+	}
+
+	// Load both contracts before using them: serve answers out of order.
+	for id, bundle := range bundles {
+		fmt.Fprintf(inW, `{"jsonrpc":"2.0","id":"load-%s","method":"load","params":{"bundle":%s}}`+"\n", id, bundle)
+		if resp := next(); resp.Error != nil {
+			t.Fatalf("load %s failed: %v", id, resp.Error)
+		}
+	}
+
+	const rounds = 20
+	go func() {
+		for i := 0; i < rounds; i++ {
+			for id := range bundles {
+				fmt.Fprintf(inW, `{"jsonrpc":"2.0","id":"%s-evaluate-%d","method":"evaluate","params":{"contract_id":%q,"facts":{"is_active":true}}}`+"\n", id, i, id)
+				fmt.Fprintf(inW, `{"jsonrpc":"2.0","id":"%s-actionSpace-%d","method":"actionSpace","params":{"contract_id":%q,"facts":{"is_active":true},"entity_states":{"Order":"pending"},"persona":"admin"}}`+"\n", id, i, id)
+				fmt.Fprintf(inW, `{"jsonrpc":"2.0","id":"%s-simulateFlow-%d","method":"simulateFlow","params":{"contract_id":%q,"flow_id":"approval_flow","facts":{"is_active":true},"entity_states":{"Order":"pending"},"persona":"admin"}}`+"\n", id, i, id)
+			}
+		}
+		inW.Close()
+	}()
+
+	want := map[string]string{"evaluate": `"account_active"`, "actionSpace": `"approval_flow"`, "simulateFlow": `"order_approved"`}
+	seen := make(map[string]bool)
+	for n := 0; n < rounds*len(bundles)*len(want); n++ {
+		resp := next()
+		var id string
+		_ = json.Unmarshal(resp.ID, &id)
+		if resp.Error != nil {
+			t.Errorf("%s failed: %v", id, resp.Error)
+			continue
+		}
+		parts := strings.Split(id, "-")
+		data, _ := json.Marshal(resp.Result)
+		if !strings.Contains(string(data), want[parts[1]]) {
+			t.Errorf("%s: expected %s in %s", id, want[parts[1]], data)
+		}
+		seen[id] = true
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	if len(seen) != rounds*len(bundles)*len(want) {
+		t.Errorf("expected %d distinct responses, got %d", rounds*len(bundles)*len(want), len(seen))
+	}
+}
@@ -0,0 +1,26 @@
+package tenor
+
+import "fmt"
+
+// Compiler turns .tenor source into interchange bundle JSON.
+//
+// The SDK ships no compiler; elaboration belongs to the Rust toolchain
+// (`tenor elaborate`). Build tooling can implement Compiler, for example by
+// running the tenor CLI, and pass it to NewEvaluatorFromSource.
+type Compiler interface {
+	Compile(source []byte) (bundle []byte, err error)
+}
+
+// NewEvaluatorFromSource compiles source with c and loads the resulting
+// bundle, as NewEvaluatorFromBundle does. It returns ErrNoCompiler if c is
+// nil.
+func NewEvaluatorFromSource(source []byte, c Compiler, opts ...Option) (*Evaluator, error) {
+	if c == nil {
+		return nil, ErrNoCompiler
+	}
+	bundleJSON, err := c.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile contract source: %w", err)
+	}
+	return NewEvaluatorFromBundle(bundleJSON, opts...)
+}
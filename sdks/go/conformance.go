@@ -0,0 +1,135 @@
+package tenor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ConformanceCase is one input of a golden conformance dump: facts are
+// always evaluated, Persona adds an action space computed against States,
+// and FlowID (with Persona) adds a simulation of that flow.
+//
+// States uses the single-instance flat format, as the conformance fixtures
+// do.
+type ConformanceCase struct {
+	Name    string
+	Facts   FactSet
+	States  EntityStateMap
+	Persona string
+	FlowID  string
+}
+
+// ConformanceMismatch is a case whose output differs from the golden dump.
+// Golden or Got is empty when the case is missing from that side.
+type ConformanceMismatch struct {
+	Name   string
+	Golden string
+	Got    string
+}
+
+// DumpConformance runs every case against eval and writes one canonical JSON
+// line per case to w, holding the case name and its verdicts, action space
+// and flow result. A call that fails is recorded by its error message
+// instead, so errors are compared like any other output.
+//
+// The dump is meant to be committed as a golden file and checked with
+// CompareConformance after upgrading the SDK or its evaluator binary. Lines
+// are CanonicalJSON, so a textual diff of two dumps shows only real changes.
+func DumpConformance(eval *Evaluator, cases []ConformanceCase, w io.Writer) error {
+	for _, c := range cases {
+		line, err := conformanceLine(eval, c)
+		if err != nil {
+			return fmt.Errorf("case %q: %w", c.Name, err)
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareConformance runs cases against eval and compares the output, line
+// by line, with a dump written earlier by DumpConformance. It returns one
+// mismatch per case whose output changed, in the order of cases, followed by
+// the golden cases that are no longer run; nil means the output is
+// unchanged. The error reports only failures to read golden or to encode the
+// output.
+func CompareConformance(golden io.Reader, eval *Evaluator, cases []ConformanceCase) ([]ConformanceMismatch, error) {
+	want := make(map[string]string)
+	var order []string
+	scanner := bufio.NewScanner(golden)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var head struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(line, &head); err != nil {
+			return nil, fmt.Errorf("golden dump: %w", err)
+		}
+		if _, ok := want[head.Name]; !ok {
+			order = append(order, head.Name)
+		}
+		want[head.Name] = string(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("golden dump: %w", err)
+	}
+
+	var mismatches []ConformanceMismatch
+	ran := make(map[string]bool, len(cases))
+	for _, c := range cases {
+		ran[c.Name] = true
+		line, err := conformanceLine(eval, c)
+		if err != nil {
+			return nil, fmt.Errorf("case %q: %w", c.Name, err)
+		}
+		if got := string(line); got != want[c.Name] {
+			mismatches = append(mismatches, ConformanceMismatch{Name: c.Name, Golden: want[c.Name], Got: got})
+		}
+	}
+	for _, name := range order {
+		if !ran[name] {
+			mismatches = append(mismatches, ConformanceMismatch{Name: name, Golden: want[name]})
+		}
+	}
+	return mismatches, nil
+}
+
+// conformanceLine runs c against eval and encodes the results canonically.
+func conformanceLine(eval *Evaluator, c ConformanceCase) ([]byte, error) {
+	record := map[string]interface{}{"name": c.Name}
+	result := func(key string, v interface{}, err error) {
+		if err != nil {
+			record[key] = map[string]interface{}{"error": err.Error()}
+			return
+		}
+		record[key] = v
+	}
+
+	vs, err := eval.Evaluate(c.Facts)
+	if err == nil {
+		var canonical []byte
+		if canonical, err = CanonicalVerdictSetJSON(vs); err != nil {
+			return nil, err
+		}
+		result("verdicts", json.RawMessage(canonical), nil)
+	} else {
+		result("verdicts", nil, err)
+	}
+	if c.Persona != "" {
+		space, err := eval.ComputeActionSpace(c.Facts, c.States, c.Persona)
+		result("action_space", space, err)
+		if c.FlowID != "" {
+			flow, err := eval.ExecuteFlow(c.FlowID, c.Facts, c.States, c.Persona)
+			result("flow_result", flow, err)
+		}
+	}
+	return CanonicalJSON(record)
+}
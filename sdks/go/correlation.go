@@ -0,0 +1,44 @@
+package tenor
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// correlationIDKey is the context key for ContextWithCorrelationID.
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying id, a request or
+// correlation ID. The context-aware methods, such as EvaluatePipe, add it as
+// a "correlation_id" attribute to every record they log to the WithLogger
+// logger, so all Tenor activity for one request can be found together.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID ctx carries, if any.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok
+}
+
+// log writes a record to the WithLogger logger, if one is set, adding the
+// correlation ID ctx carries and the WithLabel labels, sorted by key.
+func (o *options) log(ctx context.Context, level slog.Level, msg string, args ...interface{}) {
+	if o.logger == nil {
+		return
+	}
+	if id, ok := CorrelationIDFromContext(ctx); ok {
+		args = append(args, "correlation_id", id)
+	}
+	keys := make([]string, 0, len(o.labels))
+	for k := range o.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, k, o.labels[k])
+	}
+	o.logger.Log(ctx, level, msg, args...)
+}
@@ -0,0 +1,48 @@
+package tenor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader names the columns WriteCSV writes.
+var csvHeader = []string{"verdict_type", "stratum", "rule", "facts_used", "payload"}
+
+// WriteCSV writes vs as CSV for spreadsheet review: a header row, then one
+// row per verdict with its type, stratum, producing rule, the facts used
+// joined with ";", and the payload encoded as JSON in a single cell. Fields
+// containing commas, quotes or newlines are quoted as RFC 4180 requires.
+// Verdicts keep their order in vs.
+func (vs *VerdictSet) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	var payload bytes.Buffer
+	enc := json.NewEncoder(&payload)
+	// Reviewers read the payload; keep "&" and "<" as they are.
+	enc.SetEscapeHTML(false)
+	for _, v := range vs.Verdicts {
+		payload.Reset()
+		if err := enc.Encode(plainNumbers(v.Payload)); err != nil {
+			return fmt.Errorf("verdict %q: failed to encode payload: %w", v.Type, err)
+		}
+		row := []string{
+			v.Type,
+			strconv.Itoa(v.Provenance.Stratum),
+			v.Provenance.Rule,
+			strings.Join(v.Provenance.FactsUsed, ";"),
+			strings.TrimSuffix(payload.String(), "\n"),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
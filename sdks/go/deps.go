@@ -0,0 +1,350 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// VerdictFactDependencies returns the facts verdictType can depend on, found
+// statically by walking the when and produce expressions of every rule that
+// produces it for fact references. Verdicts those rules require are followed
+// transitively, across strata, so the result includes the facts behind them.
+//
+// This is the static counterpart of VerdictProvenance.FactsUsed: it answers
+// "which verdicts could change if this fact were no longer collected" without
+// evaluating anything. The result is sorted and de-duplicated, and is nil if
+// no rule produces verdictType.
+func (b *Bundle) VerdictFactDependencies(verdictType string) []string {
+	_, facts := b.verdictDependencies(verdictType)
+	if len(facts) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(facts))
+	for id := range facts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// verdictDependencies returns the rules that produce verdictType or, through
+// verdict_present, a verdict those rules require, and the facts their when
+// and produce expressions reference.
+func (b *Bundle) verdictDependencies(verdictType string) (rules, facts map[string]bool) {
+	rules = make(map[string]bool)
+	facts = make(map[string]bool)
+	visited := make(map[string]bool)
+
+	var visit func(verdict string)
+	visit = func(verdict string) {
+		if visited[verdict] {
+			return
+		}
+		visited[verdict] = true
+		for i := range b.Rules {
+			rule := &b.Rules[i]
+			if rule.Body.Produce.VerdictType != verdict {
+				continue
+			}
+			rules[rule.ID] = true
+			var verdicts []string
+			for _, raw := range []json.RawMessage{rule.Body.When, rule.Body.Produce.Payload} {
+				var expr interface{}
+				if err := json.Unmarshal(raw, &expr); err == nil {
+					collectRefs(expr, facts, &verdicts)
+				}
+			}
+			for _, v := range verdicts {
+				visit(v)
+			}
+		}
+	}
+	visit(verdictType)
+	return rules, facts
+}
+
+// collectRefs records every fact_ref and verdict_present reference in an
+// expression tree.
+func collectRefs(node interface{}, facts map[string]bool, verdicts *[]string) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if id, ok := n["fact_ref"].(string); ok {
+			facts[id] = true
+		}
+		if v, ok := n["verdict_present"].(string); ok {
+			*verdicts = append(*verdicts, v)
+		}
+		for _, child := range n {
+			collectRefs(child, facts, verdicts)
+		}
+	case []interface{}:
+		for _, child := range n {
+			collectRefs(child, facts, verdicts)
+		}
+	}
+}
+
+// UnusedFacts returns the declared facts that no expression in the contract
+// references, sorted. A fact counts as used if a rule's when or produce
+// expression, an operation precondition or a flow branch condition refers
+// to it; anything else is dead weight, often left behind when a rule was
+// renamed or removed. The result is nil if every fact is used.
+//
+// Where VerdictFactDependencies answers which facts one verdict needs,
+// UnusedFacts answers which facts nothing needs, which makes it suitable as
+// a lint over contracts.
+func (b *Bundle) UnusedFacts() []string {
+	used := b.referencedFacts()
+	var unused []string
+	for _, f := range b.Facts {
+		if !used[f.ID] {
+			unused = append(unused, f.ID)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// referencedFacts returns the IDs of the facts referenced by a rule's when or
+// produce expression, an operation precondition or a flow branch condition.
+func (b *Bundle) referencedFacts() map[string]bool {
+	used := make(map[string]bool)
+	var verdicts []string
+	walk := func(raw json.RawMessage) {
+		var expr interface{}
+		if err := json.Unmarshal(raw, &expr); err == nil {
+			collectRefs(expr, used, &verdicts)
+		}
+	}
+
+	for i := range b.Rules {
+		walk(b.Rules[i].Body.When)
+		walk(b.Rules[i].Body.Produce.Payload)
+	}
+	for i := range b.Operations {
+		walk(b.Operations[i].Precondition)
+	}
+	var steps func([]FlowStep)
+	steps = func(s []FlowStep) {
+		for i := range s {
+			walk(s[i].Condition)
+			for j := range s[i].Branches {
+				steps(s[i].Branches[j].Steps)
+			}
+		}
+	}
+	for i := range b.Flows {
+		steps(b.Flows[i].Steps)
+	}
+	return used
+}
+
+// ValidateReferences checks the contract's referential integrity: every fact
+// and verdict an expression references is declared or produced, every fact
+// source is declared, every operation effect names a declared entity and
+// states of it, and every operation and sub-flow a flow invokes is declared.
+// Each dangling reference is reported as a *ReferenceError. Personas are not
+// checked, since contracts may use personas without declaring them. It
+// returns nil when every reference resolves.
+func (b *Bundle) ValidateReferences() []error {
+	produced := make(map[string]bool)
+	for _, r := range b.Rules {
+		produced[r.Body.Produce.VerdictType] = true
+	}
+
+	var errs []error
+	report := func(kind, id, ref string) {
+		errs = append(errs, &ReferenceError{Kind: kind, ID: id, Ref: ref})
+	}
+	exprs := func(kind, id string, raws ...json.RawMessage) {
+		facts := make(map[string]bool)
+		var verdicts []string
+		for _, raw := range raws {
+			var expr interface{}
+			if err := json.Unmarshal(raw, &expr); err == nil {
+				collectRefs(expr, facts, &verdicts)
+			}
+		}
+		ids := make([]string, 0, len(facts))
+		for f := range facts {
+			ids = append(ids, f)
+		}
+		sort.Strings(ids)
+		for _, f := range ids {
+			if _, ok := b.Fact(f); !ok {
+				report(kind, id, fmt.Sprintf("fact %q", f))
+			}
+		}
+		seen := make(map[string]bool)
+		for _, v := range verdicts {
+			if !produced[v] && !seen[v] {
+				seen[v] = true
+				report(kind, id, fmt.Sprintf("verdict %q", v))
+			}
+		}
+	}
+
+	for _, f := range b.Facts {
+		if src := f.SourceID(); src != "" {
+			if _, ok := b.Source(src); !ok {
+				report("Fact", f.ID, fmt.Sprintf("source %q", src))
+			}
+		}
+	}
+	for _, r := range b.Rules {
+		exprs("Rule", r.ID, r.Body.When, r.Body.Produce.Payload)
+	}
+	for _, op := range b.Operations {
+		exprs("Operation", op.ID, op.Precondition)
+		for _, effect := range op.Effects {
+			entity, ok := b.Entity(effect.EntityID)
+			if !ok {
+				report("Operation", op.ID, fmt.Sprintf("entity %q", effect.EntityID))
+				continue
+			}
+			for _, state := range []string{effect.From, effect.To} {
+				if !contains(entity.States, state) {
+					report("Operation", op.ID, fmt.Sprintf("state %q of entity %q", state, effect.EntityID))
+				}
+			}
+		}
+	}
+	for _, flow := range b.Flows {
+		operation := func(opID string) {
+			if _, ok := b.Operation(opID); !ok {
+				report("Flow", flow.ID, fmt.Sprintf("operation %q", opID))
+			}
+		}
+		var steps func([]FlowStep)
+		steps = func(s []FlowStep) {
+			for i := range s {
+				step := &s[i]
+				switch step.Kind {
+				case "OperationStep":
+					operation(step.Op)
+				case "SubFlowStep":
+					if _, ok := b.Flow(step.Flow); !ok {
+						report("Flow", flow.ID, fmt.Sprintf("flow %q", step.Flow))
+					}
+				case "BranchStep":
+					exprs("Flow", flow.ID, step.Condition)
+				}
+				if step.OnFailure != nil {
+					for _, comp := range step.OnFailure.Steps {
+						operation(comp.Op)
+					}
+				}
+				for j := range step.Branches {
+					steps(step.Branches[j].Steps)
+				}
+			}
+		}
+		steps(flow.Steps)
+	}
+	return errs
+}
+
+// VerdictShadow is a verdict type produced by more than one rule.
+type VerdictShadow struct {
+	VerdictType string
+	// Rules holds the producing rules, ordered by stratum, then ID, and
+	// Strata the stratum of each.
+	Rules  []string
+	Strata []int
+	// CrossStratum reports that the rules sit in different strata, so a
+	// later stratum's verdict may shadow an earlier one's for the rules that
+	// read it. Same-stratum rules race to produce one verdict instead.
+	CrossStratum bool
+}
+
+// ShadowedVerdicts reports every verdict type produced by more than one rule,
+// sorted by verdict type. Tenor requires each verdict type to have a single
+// producing rule, so the elaborator never emits such a bundle; the lint
+// catches accidental double production in hand-written or merged bundles
+// (see MergeBundles), whatever the strata involved. It returns nil when every
+// verdict type is unique.
+func (b *Bundle) ShadowedVerdicts() []VerdictShadow {
+	producers := make(map[string][]*RuleDef)
+	for i := range b.Rules {
+		rule := &b.Rules[i]
+		t := rule.Body.Produce.VerdictType
+		producers[t] = append(producers[t], rule)
+	}
+
+	var shadows []VerdictShadow
+	for t, rules := range producers {
+		if len(rules) < 2 {
+			continue
+		}
+		sort.Slice(rules, func(i, j int) bool {
+			if rules[i].Stratum != rules[j].Stratum {
+				return rules[i].Stratum < rules[j].Stratum
+			}
+			return rules[i].ID < rules[j].ID
+		})
+		s := VerdictShadow{VerdictType: t}
+		for _, rule := range rules {
+			s.Rules = append(s.Rules, rule.ID)
+			s.Strata = append(s.Strata, rule.Stratum)
+		}
+		s.CrossStratum = s.Strata[0] != s.Strata[len(s.Strata)-1]
+		shadows = append(shadows, s)
+	}
+	sort.Slice(shadows, func(i, j int) bool {
+		return shadows[i].VerdictType < shadows[j].VerdictType
+	})
+	return shadows
+}
+
+// OperationDependencies returns the sorted IDs of the operations that must
+// run before opID can: those whose effects move an entity into a state opID
+// transitions from, followed transitively through the states they in turn
+// require. A source state that is the entity's initial state needs no prior
+// operation. Precondition verdicts add nothing here, since rules read only
+// facts and other verdicts and no operation effect can produce either.
+//
+// The result answers "A must happen before B" for documentation; it is
+// static, so a listed operation may be one of several alternatives. It is an
+// empty, non-nil slice when opID has no dependencies or is not declared.
+func (b *Bundle) OperationDependencies(opID string) []string {
+	type entityState struct{ entity, state string }
+	producers := make(map[entityState][]string)
+	for _, op := range b.Operations {
+		for _, eff := range op.Effects {
+			k := entityState{eff.EntityID, eff.To}
+			if !contains(producers[k], op.ID) {
+				producers[k] = append(producers[k], op.ID)
+			}
+		}
+	}
+
+	deps := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		op, ok := b.Operation(id)
+		if !ok {
+			return
+		}
+		for _, eff := range op.Effects {
+			if ent, ok := b.Entity(eff.EntityID); ok && ent.Initial == eff.From {
+				continue
+			}
+			for _, dep := range producers[entityState{eff.EntityID, eff.From}] {
+				if dep == opID || deps[dep] {
+					continue
+				}
+				deps[dep] = true
+				visit(dep)
+			}
+		}
+	}
+	visit(opID)
+
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
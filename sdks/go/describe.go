@@ -0,0 +1,91 @@
+package tenor
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// ContractDescription is a serializable manifest of everything a loaded
+// contract declares, for clients that build their UI from the contract shape.
+// Each construct carries its source provenance.
+type ContractDescription struct {
+	ID           string         `json:"id"`
+	TenorVersion string         `json:"tenor_version"`
+	Facts        []FactDef      `json:"facts"`
+	Entities     []EntityDef    `json:"entities"`
+	Rules        []RuleDef      `json:"rules"`
+	Operations   []OperationDef `json:"operations"`
+	Flows        []FlowDef      `json:"flows"`
+	Sources      []SourceDef    `json:"sources"`
+	// Personas lists the declared personas. Contracts that declare none get
+	// the personas their operations allow, with empty provenance.
+	Personas     []PersonaDef  `json:"personas"`
+	VerdictTypes []VerdictType `json:"verdict_types"`
+}
+
+// VerdictType is a verdict a contract can produce, with the rules producing it.
+type VerdictType struct {
+	Type       string   `json:"type"`
+	ProducedBy []string `json:"produced_by"`
+}
+
+// Describe assembles a ContractDescription of the loaded contract from the
+// parsed bundle. It makes no WASM call.
+func (e *Evaluator) Describe() (*ContractDescription, error) {
+	b := e.bundle
+	d := &ContractDescription{
+		ID:           b.ID,
+		TenorVersion: b.TenorVersion,
+		Facts:        nonNil(b.Facts),
+		Entities:     nonNil(b.Entities),
+		Rules:        nonNil(b.Rules),
+		Operations:   nonNil(b.Operations),
+		Flows:        nonNil(b.Flows),
+		Sources:      nonNil(b.Sources),
+		Personas:     nonNil(b.Personas),
+		VerdictTypes: []VerdictType{},
+	}
+
+	if len(d.Personas) == 0 {
+		seen := make(map[string]bool)
+		for _, op := range b.Operations {
+			for _, p := range op.AllowedPersonas {
+				if !seen[p] {
+					seen[p] = true
+					d.Personas = append(d.Personas, PersonaDef{ID: p})
+				}
+			}
+		}
+		sort.Slice(d.Personas, func(i, j int) bool { return d.Personas[i].ID < d.Personas[j].ID })
+	}
+
+	producers := make(map[string][]string)
+	for _, r := range b.Rules {
+		vt := r.Body.Produce.VerdictType
+		producers[vt] = append(producers[vt], r.ID)
+	}
+	for vt, rules := range producers {
+		d.VerdictTypes = append(d.VerdictTypes, VerdictType{Type: vt, ProducedBy: rules})
+	}
+	sort.Slice(d.VerdictTypes, func(i, j int) bool { return d.VerdictTypes[i].Type < d.VerdictTypes[j].Type })
+
+	return d, nil
+}
+
+// DescribeJSON is Describe serialized as JSON.
+func (e *Evaluator) DescribeJSON() ([]byte, error) {
+	d, err := e.Describe()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(d)
+}
+
+// nonNil returns s, or an empty slice if s is nil, so that the field
+// serializes as [] rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
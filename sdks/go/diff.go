@@ -0,0 +1,185 @@
+package tenor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// VerdictDiff lists how one verdict set differs from another, by verdict
+// type. Each list is sorted.
+type VerdictDiff struct {
+	// Added holds verdict types present only in the second set.
+	Added []string
+	// Removed holds verdict types present only in the first set.
+	Removed []string
+	// Changed holds verdict types present in both with different payloads.
+	Changed []string
+}
+
+// IsEmpty reports whether the two verdict sets agree.
+func (d VerdictDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffVerdicts compares two verdict sets by verdict type. When a set holds
+// several verdicts of one type, the first is compared.
+func DiffVerdicts(from, to *VerdictSet) VerdictDiff {
+	before := verdictsByType(from)
+	after := verdictsByType(to)
+
+	var d VerdictDiff
+	for t, v := range after {
+		prev, ok := before[t]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, t)
+		case !reflect.DeepEqual(prev.Payload, v.Payload):
+			d.Changed = append(d.Changed, t)
+		}
+	}
+	for t := range before {
+		if _, ok := after[t]; !ok {
+			d.Removed = append(d.Removed, t)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// EvaluateDelta evaluates facts and diffs the result against baseline, the
+// verdict set from an earlier evaluation. It returns the new full verdict
+// set, to become the caller's next baseline, along with the changes to emit.
+// A nil baseline reports every verdict as added.
+func (e *Evaluator) EvaluateDelta(baseline *VerdictSet, facts FactSet) (*VerdictSet, VerdictDiff, error) {
+	vs, err := e.Evaluate(facts)
+	if err != nil {
+		return nil, VerdictDiff{}, err
+	}
+	return vs, DiffVerdicts(baseline, vs), nil
+}
+
+func verdictsByType(vs *VerdictSet) map[string]Verdict {
+	byType := make(map[string]Verdict)
+	if vs == nil {
+		return byType
+	}
+	for _, v := range vs.Verdicts {
+		if _, ok := byType[v.Type]; !ok {
+			byType[v.Type] = v
+		}
+	}
+	return byType
+}
+
+// ActionSpaceDiff lists the flows whose availability differs between two
+// action spaces. Each list is sorted.
+type ActionSpaceDiff struct {
+	// Enabled holds flows available only in the second action space.
+	Enabled []string
+	// Disabled holds flows available only in the first action space.
+	Disabled []string
+}
+
+// IsEmpty reports whether the same flows are available in both action
+// spaces.
+func (d ActionSpaceDiff) IsEmpty() bool {
+	return len(d.Enabled) == 0 && len(d.Disabled) == 0
+}
+
+// DiffActionSpaces compares the flows available in two action spaces.
+func DiffActionSpaces(from, to *ActionSpace) ActionSpaceDiff {
+	before := availableFlows(from)
+	after := availableFlows(to)
+
+	var d ActionSpaceDiff
+	for id := range after {
+		if !before[id] {
+			d.Enabled = append(d.Enabled, id)
+		}
+	}
+	for id := range before {
+		if !after[id] {
+			d.Disabled = append(d.Disabled, id)
+		}
+	}
+	sort.Strings(d.Enabled)
+	sort.Strings(d.Disabled)
+	return d
+}
+
+func availableFlows(space *ActionSpace) map[string]bool {
+	flows := make(map[string]bool)
+	if space == nil {
+		return flows
+	}
+	for _, a := range space.Actions {
+		flows[a.FlowID] = true
+	}
+	return flows
+}
+
+// ActionSpaceDeltaForOperation answers "if persona ran opID now, how would
+// the action space change?": it computes persona's action space, applies
+// the operation's effects to entityStates with ApplyTransitions, recomputes,
+// and diffs the two. Facts and verdicts are unchanged, as in a flow's
+// at_initiation snapshot. For an operation with several outcomes, the
+// effects of its first declared outcome are applied.
+//
+// It fails if persona may not perform opID, and with ErrStaleState if an
+// effect's source state does not match entityStates. The operation's
+// precondition is not checked; the action space reports whether it holds.
+func (e *Evaluator) ActionSpaceDeltaForOperation(
+	opID string,
+	facts FactSet,
+	entityStates EntityStateMap,
+	persona string,
+) (*ActionSpaceDiff, error) {
+	op, ok := e.bundle.Operation(opID)
+	if !ok {
+		return nil, fmt.Errorf("operation %q is not declared in the contract", opID)
+	}
+	if !contains(op.AllowedPersonas, persona) {
+		return nil, fmt.Errorf("persona %q may not perform operation %q", persona, opID)
+	}
+
+	entityStates = e.inferStates(entityStates)
+	before, err := e.ComputeActionSpace(facts, entityStates, persona)
+	if err != nil {
+		return nil, err
+	}
+
+	var outcome string
+	if len(op.Outcomes) > 0 {
+		outcome = op.Outcomes[0]
+	}
+	var changes []EntityStateChange
+	for _, effect := range op.Effects {
+		if effect.Outcome != "" && effect.Outcome != outcome {
+			continue
+		}
+		changes = append(changes, EntityStateChange{
+			EntityID:   effect.EntityID,
+			InstanceID: DefaultInstanceID,
+			FromState:  effect.From,
+			ToState:    effect.To,
+		})
+	}
+	applied, err := ApplyTransitions(entityStates.nested(), changes)
+	if err != nil {
+		return nil, fmt.Errorf("operation %q: %w", opID, err)
+	}
+	afterStates := make(EntityStateMap, len(applied))
+	for entityID, instances := range applied {
+		afterStates[entityID] = instances[DefaultInstanceID]
+	}
+
+	after, err := e.ComputeActionSpace(facts, afterStates, persona)
+	if err != nil {
+		return nil, err
+	}
+	d := DiffActionSpaces(before, after)
+	return &d, nil
+}
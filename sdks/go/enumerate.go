@@ -0,0 +1,119 @@
+package tenor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MaxFlowOutcomeCombinations caps the fact combinations
+// EnumerateFlowOutcomes simulates.
+const MaxFlowOutcomeCombinations = 4096
+
+// FlowOutcome is one distinct result of a flow: an outcome together with the
+// entity transitions that lead to it.
+type FlowOutcome struct {
+	Outcome     string
+	Transitions []EntityStateChange
+	// Example is the first fact combination found to reach the outcome.
+	Example FactSet
+	// Count is the number of explored combinations reaching it.
+	Count int
+}
+
+// EnumerateFlowOutcomes simulates flowID as persona for every combination of
+// the values in factDomains, from the contract's initial entity states, and
+// returns the distinct outcomes, sorted by outcome. It answers questions such
+// as "can this flow ever reach outcome X?" within the domains given.
+//
+// Facts without a domain take their declared default. Combinations are
+// explored in order of fact ID, and at most MaxFlowOutcomeCombinations are
+// simulated: when the product of the domain sizes is larger, the outcomes of
+// the explored combinations are returned with an error matching
+// ErrTooManyCombinations, reporting that the enumeration is incomplete.
+func (e *Evaluator) EnumerateFlowOutcomes(flowID, persona string, factDomains map[string][]interface{}) ([]FlowOutcome, error) {
+	ids := make([]string, 0, len(factDomains))
+	total := 1
+	for id, domain := range factDomains {
+		if len(domain) == 0 {
+			return nil, fmt.Errorf("fact %q has an empty domain", id)
+		}
+		ids = append(ids, id)
+		if total <= MaxFlowOutcomeCombinations {
+			total *= len(domain)
+		}
+	}
+	sort.Strings(ids)
+
+	base := make(FactSet, len(e.bundle.Facts))
+	for i := range e.bundle.Facts {
+		if v, ok := e.bundle.Facts[i].DefaultValue(); ok {
+			base[e.bundle.Facts[i].ID] = v
+		}
+	}
+	states := make(EntityStateMap, len(e.bundle.Entities))
+	for _, ent := range e.bundle.Entities {
+		states[ent.ID] = ent.Initial
+	}
+
+	var outcomes []FlowOutcome
+	indexes := make([]int, len(ids))
+	for n := 0; n < MaxFlowOutcomeCombinations; n++ {
+		facts := make(FactSet, len(base)+len(ids))
+		for id, v := range base {
+			facts[id] = v
+		}
+		for i, id := range ids {
+			facts[id] = factDomains[id][indexes[i]]
+		}
+
+		result, err := e.ExecuteFlow(flowID, facts, states, persona)
+		if err != nil {
+			return nil, err
+		}
+		outcomes = addFlowOutcome(outcomes, result, facts)
+
+		// Advance to the next combination, the last fact varying fastest.
+		i := len(ids) - 1
+		for ; i >= 0; i-- {
+			indexes[i]++
+			if indexes[i] < len(factDomains[ids[i]]) {
+				break
+			}
+			indexes[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+
+	sort.SliceStable(outcomes, func(i, j int) bool {
+		return outcomes[i].Outcome < outcomes[j].Outcome
+	})
+	if total > MaxFlowOutcomeCombinations {
+		return outcomes, fmt.Errorf("flow %q: explored only the first %d fact combinations: %w", flowID, MaxFlowOutcomeCombinations, ErrTooManyCombinations)
+	}
+	return outcomes, nil
+}
+
+// addFlowOutcome counts result against the outcome it matches, or appends a
+// new outcome with facts as its example.
+func addFlowOutcome(outcomes []FlowOutcome, result *FlowResult, facts FactSet) []FlowOutcome {
+	for i := range outcomes {
+		o := &outcomes[i]
+		if o.Outcome == result.Outcome && sameTransitions(o.Transitions, result.WouldTransition) {
+			o.Count++
+			return outcomes
+		}
+	}
+	return append(outcomes, FlowOutcome{
+		Outcome:     result.Outcome,
+		Transitions: result.WouldTransition,
+		Example:     facts,
+		Count:       1,
+	})
+}
+
+func sameTransitions(a, b []EntityStateChange) bool {
+	return len(a) == len(b) && (len(a) == 0 || reflect.DeepEqual(a, b))
+}
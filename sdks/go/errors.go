@@ -0,0 +1,390 @@
+package tenor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/riverline-labs/tenor-go/internal/wasm"
+)
+
+// ErrNotSupported is returned when a method is called on a contract that lacks
+// the constructs it needs, such as ComputeActionSpace on a contract without
+// operations.
+var ErrNotSupported = errors.New("not supported by this contract")
+
+// ErrFlowNotAllowed is returned when a flow outside the evaluator's
+// WithFlowAllowList is requested.
+var ErrFlowNotAllowed = errors.New("flow not allowed")
+
+// ErrMultiInstance is returned under WithRequireNestedStates when a
+// single-instance method is called on an evaluator that has been passed
+// several instances of one entity.
+var ErrMultiInstance = errors.New("contract is multi-instance; use the nested or instance-binding variant")
+
+// ErrNoCompiler is returned by NewEvaluatorFromSource when no Compiler is
+// supplied.
+var ErrNoCompiler = errors.New("no compiler configured; compile .tenor source to a bundle with `tenor elaborate`")
+
+// ErrProfilingDisabled is returned by EvaluateProfiled on an evaluator created
+// without WithRuleProfiling.
+var ErrProfilingDisabled = errors.New("rule profiling not enabled; use WithRuleProfiling")
+
+// ErrIncompatibleWASM is returned when the WASM binary lacks functions the SDK
+// requires. The error is a *MissingExportsError naming every missing export.
+var ErrIncompatibleWASM = wasm.ErrIncompatible
+
+// ErrMissingImport is returned when the WASM binary imports functions or
+// memories the runtime does not provide, e.g. host functions of another
+// embedding. The error is a *MissingImportsError naming each one.
+var ErrMissingImport = wasm.ErrMissingImport
+
+// ErrTooManyCombinations is returned by Action.BindingCombinations when the
+// number of concrete bindings exceeds the requested cap.
+var ErrTooManyCombinations = errors.New("too many binding combinations")
+
+// ErrEvaluationTooComplex is returned when a call exhausts the WASM call
+// stack, typically because a contract recurses deeply. It distinguishes a
+// pathological contract from other traps, which point at bridge bugs.
+var ErrEvaluationTooComplex = wasm.ErrStackOverflow
+
+// ErrEvalTimeout is returned when a call outlives the WithWatchdog limit, and
+// by every later call on the same Evaluator, which is unusable from then on.
+var ErrEvalTimeout = wasm.ErrTimeout
+
+// ErrIncompatibleRuntimeConfig is returned when a WithRuntimeConfig
+// configuration disables WebAssembly features the embedded binary uses.
+var ErrIncompatibleRuntimeConfig = wasm.ErrUnsupportedFeatures
+
+// ErrResultTooLarge is returned when a WASM result exceeds the
+// WithMaxResultBytes limit.
+var ErrResultTooLarge = wasm.ErrResultTooLarge
+
+// MissingExportsError lists the required exports a WASM binary lacks. It
+// matches ErrIncompatibleWASM under errors.Is.
+type MissingExportsError = wasm.MissingExportsError
+
+// MissingImportsError lists the imports of a WASM binary the runtime cannot
+// satisfy, with their signatures. It matches ErrMissingImport under
+// errors.Is.
+type MissingImportsError = wasm.MissingImportsError
+
+// UnknownFactsError is returned when WithRejectUnknownFacts is enabled and a
+// FactSet contains fact IDs the contract does not declare.
+type UnknownFactsError struct {
+	FactIDs []string
+}
+
+func (e *UnknownFactsError) Error() string {
+	return fmt.Sprintf("unknown facts not declared in contract: %s", strings.Join(e.FactIDs, ", "))
+}
+
+// MissingFactDefaultsError is returned by InitialActionSpace when facts the
+// contract declares have no default and so need a value from the caller.
+type MissingFactDefaultsError struct {
+	FactIDs []string
+}
+
+func (e *MissingFactDefaultsError) Error() string {
+	return fmt.Sprintf("facts without declared defaults: %s", strings.Join(e.FactIDs, ", "))
+}
+
+// MissingFactsError is returned by Evaluate when the contract declares facts
+// with no default that the FactSet does not supply. FactIDs lists every such
+// fact, in declaration order, not only the first the evaluator hit.
+type MissingFactsError struct {
+	FactIDs []string
+}
+
+func (e *MissingFactsError) Error() string {
+	return fmt.Sprintf("missing required facts: %s", strings.Join(e.FactIDs, ", "))
+}
+
+// PayloadMismatchError is one mismatch reported by
+// VerdictSet.ValidatePayloads.
+type PayloadMismatchError struct {
+	VerdictType string
+	Rule        string
+	// Path locates the mismatch within the verdict, e.g. "payload.fields.amount".
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *PayloadMismatchError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("verdict %q from rule %q: expected %s, got %s", e.VerdictType, e.Rule, e.Expected, e.Actual)
+	}
+	return fmt.Sprintf("verdict %q from rule %q: %s: expected %s, got %s", e.VerdictType, e.Rule, e.Path, e.Expected, e.Actual)
+}
+
+// ErrFlowTooLong is matched by errors.Is for a FlowTooLongError.
+var ErrFlowTooLong = errors.New("flow exceeded maximum step count")
+
+// FlowTooLongError is returned when a flow simulation visits more steps than
+// allowed (see WithMaxFlowSteps).
+type FlowTooLongError struct {
+	FlowID string
+	Limit  int
+	// Path holds the steps visited up to and including the first one past
+	// Limit. It is empty when the bridge's own limit stopped the flow.
+	Path []StepResult
+}
+
+func (e *FlowTooLongError) Error() string {
+	return fmt.Sprintf("flow %q: %v (%d)", e.FlowID, ErrFlowTooLong, e.Limit)
+}
+
+// Is makes errors.Is(err, ErrFlowTooLong) true.
+func (e *FlowTooLongError) Is(target error) bool {
+	return target == ErrFlowTooLong
+}
+
+// ErrStaleState is matched by errors.Is for a StaleStateError.
+var ErrStaleState = errors.New("stale entity state")
+
+// StaleStateError is returned by ApplyTransitions when a change's FromState
+// does not match the instance's current state.
+type StaleStateError struct {
+	EntityID   string
+	InstanceID string
+	// Expected is the change's FromState.
+	Expected string
+	// Actual is the instance's current state; it is empty when Missing.
+	Actual string
+	// Missing reports that the instance has no state at all.
+	Missing bool
+}
+
+func (e *StaleStateError) Error() string {
+	if e.Missing {
+		return fmt.Sprintf("%v: %s/%s has no state, expected %q", ErrStaleState, e.EntityID, e.InstanceID, e.Expected)
+	}
+	return fmt.Sprintf("%v: %s/%s is in %q, expected %q", ErrStaleState, e.EntityID, e.InstanceID, e.Actual, e.Expected)
+}
+
+// Is makes errors.Is(err, ErrStaleState) true.
+func (e *StaleStateError) Is(target error) bool {
+	return target == ErrStaleState
+}
+
+// BatchError reports the items of a batch call that failed. Errors is aligned
+// with the batch input and holds nil for items that succeeded.
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	var failed []string
+	for i, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("item %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d batch items failed: %s", len(failed), len(e.Errors), strings.Join(failed, "; "))
+}
+
+// Unwrap returns the non-nil item errors, so errors.Is and errors.As search
+// them.
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// FailurePathError is one gap reported by Bundle.ValidateFailurePaths.
+type FailurePathError struct {
+	FlowID  string
+	StepID  string
+	Problem string
+}
+
+func (e *FailurePathError) Error() string {
+	return fmt.Sprintf("flow %q step %q: %s", e.FlowID, e.StepID, e.Problem)
+}
+
+// FlowPersonaError is one inconsistency reported by
+// Bundle.ValidateFlowPersonas: a step runs its operation as a persona the
+// operation does not allow.
+type FlowPersonaError struct {
+	FlowID          string
+	StepID          string
+	OperationID     string
+	Persona         string
+	AllowedPersonas []string
+}
+
+func (e *FlowPersonaError) Error() string {
+	return fmt.Sprintf("flow %q step %q: persona %q may not perform operation %q (allowed: %s)",
+		e.FlowID, e.StepID, e.Persona, e.OperationID, strings.Join(e.AllowedPersonas, ", "))
+}
+
+// FlowTransitionError is one conflict reported by
+// Bundle.ValidateFlowTransitions: on some path through the flow, a step's
+// operation expects an entity in From, but an earlier step on that path
+// left it in PriorState.
+type FlowTransitionError struct {
+	FlowID      string
+	StepID      string
+	OperationID string
+	EntityID    string
+	From, To    string
+	PriorStepID string
+	PriorState  string
+}
+
+func (e *FlowTransitionError) Error() string {
+	return fmt.Sprintf("flow %q step %q: operation %q transitions %s %s -> %s, but step %q left it in %q",
+		e.FlowID, e.StepID, e.OperationID, e.EntityID, e.From, e.To, e.PriorStepID, e.PriorState)
+}
+
+// ReferenceError is one dangling reference reported by
+// Bundle.ValidateReferences.
+type ReferenceError struct {
+	// Kind and ID identify the construct holding the reference, e.g. "Rule"
+	// and "check_active".
+	Kind string
+	ID   string
+	// Ref describes the missing target, e.g. `fact "is_active"`.
+	Ref string
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("%s %q references undeclared %s", e.Kind, e.ID, e.Ref)
+}
+
+// ErrDuplicateConstruct is matched by errors.Is for a DuplicateConstructError.
+var ErrDuplicateConstruct = errors.New("duplicate construct")
+
+// DuplicateConstructError is returned by MergeBundles when two bundles
+// declare a construct of the same kind and ID.
+type DuplicateConstructError struct {
+	Kind string
+	ID   string
+	// First and Second are the indexes of the bundles declaring it.
+	First, Second int
+}
+
+func (e *DuplicateConstructError) Error() string {
+	return fmt.Sprintf("%v: %s %q declared in bundles %d and %d", ErrDuplicateConstruct, e.Kind, e.ID, e.First, e.Second)
+}
+
+// Is makes errors.Is(err, ErrDuplicateConstruct) true.
+func (e *DuplicateConstructError) Is(target error) bool {
+	return target == ErrDuplicateConstruct
+}
+
+// ErrDuplicateFactKey is matched by errors.Is for a DuplicateFactKeyError.
+var ErrDuplicateFactKey = errors.New("duplicate key in fact JSON")
+
+// DuplicateFactKeyError is returned by EvaluateJSON under
+// WithRejectDuplicateKeys when the input holds a key twice.
+type DuplicateFactKeyError struct {
+	// Path locates the repeated key: the fact ID for a top-level key, else
+	// the fact ID followed by the nested keys and array indexes, e.g.
+	// "order.items[1].sku".
+	Path string
+}
+
+func (e *DuplicateFactKeyError) Error() string {
+	return fmt.Sprintf("%v: %q", ErrDuplicateFactKey, e.Path)
+}
+
+// Is makes errors.Is(err, ErrDuplicateFactKey) true.
+func (e *DuplicateFactKeyError) Is(target error) bool {
+	return target == ErrDuplicateFactKey
+}
+
+// DecodeError is returned when a WASM bridge result cannot be decoded into
+// the SDK's types, which usually means the bridge and the SDK have drifted.
+type DecodeError struct {
+	// Func is the bridge function that produced the result, e.g. "evaluate".
+	Func string
+	// Target is the Go type being decoded, e.g. "VerdictSet".
+	Target string
+	// Field is the JSON path of the offending field, when known.
+	Field string
+	// Snippet is the start of the raw result, truncated to decodeSnippetLen bytes.
+	Snippet string
+	Err     error
+}
+
+// decodeSnippetLen bounds how much of the raw result a DecodeError carries.
+const decodeSnippetLen = 200
+
+func (e *DecodeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to parse %s from %s", e.Target, e.Func)
+	var typeErr *json.UnmarshalTypeError
+	if e.Field != "" && errors.As(e.Err, &typeErr) {
+		fmt.Fprintf(&b, ", field %s: cannot unmarshal %s into %s", e.Field, typeErr.Value, typeErr.Type)
+	} else {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	fmt.Fprintf(&b, " (result: %s)", e.Snippet)
+	return b.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeResult unmarshals a bridge result into v, wrapping failures in a
+// *DecodeError that names the function, the target type and the field. With
+// strict set, fields v does not model are an error.
+func decodeResult(funcName, target, result string, v interface{}, strict bool) error {
+	dec := json.NewDecoder(strings.NewReader(result))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	err := dec.Decode(v)
+	if err == nil {
+		// Decode stops after the first value, where json.Unmarshal would
+		// reject anything but white space.
+		if dec.Decode(&json.RawMessage{}) == io.EOF {
+			return nil
+		}
+		err = errors.New("invalid data after top-level value")
+	}
+	snippet := result
+	if len(snippet) > decodeSnippetLen {
+		// Cut on a rune boundary so that the snippet stays valid UTF-8.
+		n := decodeSnippetLen
+		for n > 0 && !utf8.RuneStart(snippet[n]) {
+			n--
+		}
+		snippet = snippet[:n] + "..."
+	}
+	decodeErr := &DecodeError{Func: funcName, Target: target, Snippet: snippet, Err: err}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		decodeErr.Field = fieldPath(typeErr.Field)
+	}
+	return decodeErr
+}
+
+// fieldPath rewrites encoding/json's dotted field path ("verdicts.0.stratum")
+// with bracketed indices ("verdicts[0].stratum").
+func fieldPath(field string) string {
+	parts := strings.Split(field, ".")
+	var b strings.Builder
+	for i, part := range parts {
+		if _, err := strconv.Atoi(part); err == nil && i > 0 {
+			fmt.Fprintf(&b, "[%s]", part)
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package tenor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+func TestDecodeErrorNamesField(t *testing.T) {
+	var vs tenor.VerdictSet
+	raw := `{"verdicts":[{"type":"x","payload":null,"provenance":{"rule":"r","stratum":"zero","facts_used":[],"verdicts_used":[]}}]}`
+	jsonErr := json.Unmarshal([]byte(raw), &vs)
+	if jsonErr == nil {
+		t.Fatal("expected a decode error for a string stratum")
+	}
+
+	err := &tenor.DecodeError{
+		Func:    "evaluate",
+		Target:  "VerdictSet",
+		Field:   "verdicts[0].provenance.stratum",
+		Snippet: raw,
+		Err:     jsonErr,
+	}
+	msg := err.Error()
+	for _, want := range []string{"VerdictSet", "evaluate", "field verdicts[0].provenance.stratum", "cannot unmarshal string into int"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in error message, got %q", want, msg)
+		}
+	}
+	if !errors.Is(err, jsonErr) {
+		t.Error("expected DecodeError to unwrap to the json error")
+	}
+}
+
+func TestDecodeResultReportsPathAndSnippet(t *testing.T) {
+	// A long, multi-byte reason puts the snippet cut inside a rune.
+	reason := "a" + strings.Repeat("é", 150)
+	raw := `{"verdicts":[{"type":"x","payload":"` + reason + `","provenance":{"rule":"r","stratum":"zero","facts_used":[],"verdicts_used":[]}}]}`
+
+	var vs tenor.VerdictSet
+	err := tenor.DecodeResult("evaluate", "VerdictSet", raw, &vs)
+	var decodeErr *tenor.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a *DecodeError, got %v", err)
+	}
+	if decodeErr.Field != "verdicts[0].provenance.stratum" {
+		t.Errorf("expected field verdicts[0].provenance.stratum, got %q", decodeErr.Field)
+	}
+	if !strings.HasSuffix(decodeErr.Snippet, "...") || len(decodeErr.Snippet) > 200+len("...") {
+		t.Errorf("expected a truncated snippet, got %d bytes: %q", len(decodeErr.Snippet), decodeErr.Snippet)
+	}
+	if !utf8.ValidString(decodeErr.Snippet) {
+		t.Errorf("expected the snippet to be cut on a rune boundary, got %q", decodeErr.Snippet)
+	}
+	if !strings.Contains(err.Error(), "field verdicts[0].provenance.stratum") {
+		t.Errorf("expected the field in the message, got %q", err.Error())
+	}
+}
+
+func TestDecodeResultRejectsTrailingData(t *testing.T) {
+	var vs tenor.VerdictSet
+	if err := tenor.DecodeResult("evaluate", "VerdictSet", "{\"verdicts\":[]}\n", &vs); err != nil {
+		t.Errorf("expected trailing white space to be accepted, got %v", err)
+	}
+	for _, raw := range []string{`{"verdicts":[]} garbage`, `{"verdicts":[]} {}`, `{"verdicts":[]}}`} {
+		var decodeErr *tenor.DecodeError
+		if err := tenor.DecodeResult("evaluate", "VerdictSet", raw, &vs); !errors.As(err, &decodeErr) {
+			t.Errorf("expected a *DecodeError for %s, got %v", raw, err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BlockedExplanation explains why a flow is blocked for a persona. For
+// PreconditionNotMet it chains each missing verdict down to the rules that
+// would produce it and the fact conditions those rules failed on.
+type BlockedExplanation struct {
+	FlowID           string               `json:"flow_id"`
+	EntryOperationID string               `json:"entry_operation_id"`
+	Persona          string               `json:"persona"`
+	Blocked          bool                 `json:"blocked"`
+	Reason           *BlockedReason       `json:"reason,omitempty"`
+	MissingVerdicts  []VerdictExplanation `json:"missing_verdicts,omitempty"`
+}
+
+// VerdictExplanation lists the rules that could have produced a missing verdict.
+type VerdictExplanation struct {
+	VerdictType string            `json:"verdict_type"`
+	Rules       []RuleExplanation `json:"rules"`
+}
+
+// RuleExplanation describes why one rule did not fire: the fact conditions it
+// tests and any verdicts it requires that are themselves missing.
+type RuleExplanation struct {
+	RuleID          string               `json:"rule_id"`
+	Stratum         int                  `json:"stratum"`
+	Conditions      []FactCondition      `json:"conditions,omitempty"`
+	MissingVerdicts []VerdictExplanation `json:"missing_verdicts,omitempty"`
+}
+
+// FactCondition is a comparison a rule makes against a fact.
+//
+// Satisfied is computed Go-side for Bool, String and numeric comparisons.
+// Conditions the SDK cannot check (for example against Decimal or Money
+// literals, or quantifiers over lists) are reported as unsatisfied.
+type FactCondition struct {
+	FactID    string      `json:"fact_id"`
+	Op        string      `json:"op"`
+	Expected  interface{} `json:"expected,omitempty"`
+	Actual    interface{} `json:"actual,omitempty"`
+	Missing   bool        `json:"missing"`
+	Satisfied bool        `json:"satisfied"`
+}
+
+// String renders the explanation as a single sentence, e.g.
+// "approve_order is blocked because account_active isn't present because
+// is_active is false".
+func (x *BlockedExplanation) String() string {
+	subject := x.EntryOperationID
+	if subject == "" {
+		subject = x.FlowID
+	}
+	if !x.Blocked {
+		return fmt.Sprintf("%s is not blocked for %s", subject, x.Persona)
+	}
+	if len(x.MissingVerdicts) == 0 {
+		return fmt.Sprintf("%s is blocked: %s", subject, x.Reason.Type)
+	}
+	return fmt.Sprintf("%s is blocked because %s", subject, explainVerdicts(x.MissingVerdicts))
+}
+
+func explainVerdicts(verdicts []VerdictExplanation) string {
+	parts := make([]string, 0, len(verdicts))
+	for _, v := range verdicts {
+		var causes []string
+		for _, r := range v.Rules {
+			for _, c := range r.Conditions {
+				if c.Satisfied {
+					continue
+				}
+				if c.Missing {
+					causes = append(causes, fmt.Sprintf("%s is missing", c.FactID))
+				} else {
+					causes = append(causes, fmt.Sprintf("%s is %v", c.FactID, c.Actual))
+				}
+			}
+			if len(r.MissingVerdicts) > 0 {
+				causes = append(causes, explainVerdicts(r.MissingVerdicts))
+			}
+		}
+		part := fmt.Sprintf("%s isn't present", v.VerdictType)
+		if len(causes) > 0 {
+			part += " because " + strings.Join(causes, " and ")
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " and ")
+}
+
+// ExplainBlocked explains why flowID is blocked for persona given facts and
+// entity states. If the flow is available, the returned explanation has
+// Blocked set to false.
+func (e *Evaluator) ExplainBlocked(
+	flowID string,
+	facts FactSet,
+	entityStates EntityStateMap,
+	persona string,
+) (*BlockedExplanation, error) {
+	if err := e.checkFlowAllowed(flowID); err != nil {
+		return nil, err
+	}
+	flow, ok := e.bundle.Flow(flowID)
+	if !ok {
+		return nil, fmt.Errorf("flow %q not found", flowID)
+	}
+
+	space, err := e.ComputeActionSpace(facts, entityStates, persona)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &BlockedExplanation{FlowID: flowID, Persona: persona}
+	if entry, ok := flow.Step(flow.Entry); ok {
+		x.EntryOperationID = entry.Op
+	}
+
+	var blocked *BlockedAction
+	for i := range space.BlockedActions {
+		if space.BlockedActions[i].FlowID == flowID {
+			blocked = &space.BlockedActions[i]
+			break
+		}
+	}
+	if blocked == nil {
+		return x, nil
+	}
+
+	x.Blocked = true
+	x.Reason = &blocked.Reason
+	if blocked.Reason.Type != ReasonPreconditionNotMet {
+		return x, nil
+	}
+
+	present := make(map[string]bool, len(space.CurrentVerdicts))
+	for _, v := range space.CurrentVerdicts {
+		present[v.VerdictType] = true
+	}
+	for _, verdictType := range blocked.Reason.MissingVerdicts {
+		x.MissingVerdicts = append(x.MissingVerdicts,
+			e.explainVerdict(verdictType, facts, present, map[string]bool{}))
+	}
+	return x, nil
+}
+
+// explainVerdict builds the rule chain for a verdict that is not present.
+// seen guards against revisiting a verdict through cyclic references.
+func (e *Evaluator) explainVerdict(
+	verdictType string,
+	facts FactSet,
+	present map[string]bool,
+	seen map[string]bool,
+) VerdictExplanation {
+	x := VerdictExplanation{VerdictType: verdictType}
+	if seen[verdictType] {
+		return x
+	}
+	seen[verdictType] = true
+
+	for _, rule := range e.bundle.Rules {
+		if rule.Body.Produce.VerdictType != verdictType {
+			continue
+		}
+		rx := RuleExplanation{RuleID: rule.ID, Stratum: rule.Stratum}
+
+		var when interface{}
+		if err := json.Unmarshal(rule.Body.When, &when); err == nil {
+			var required []string
+			collectConditions(when, e.factValue(facts), &rx.Conditions, &required)
+			for _, v := range required {
+				if !present[v] {
+					rx.MissingVerdicts = append(rx.MissingVerdicts, e.explainVerdict(v, facts, present, seen))
+				}
+			}
+		}
+		x.Rules = append(x.Rules, rx)
+	}
+	return x
+}
+
+// factValue returns a lookup of fact values that falls back to each fact's
+// declared default when the caller did not provide one.
+func (e *Evaluator) factValue(facts FactSet) func(id string) (interface{}, bool) {
+	return func(id string) (interface{}, bool) {
+		if v, ok := facts[id]; ok {
+			return v, true
+		}
+		if f, ok := e.bundle.Fact(id); ok {
+			return f.DefaultValue()
+		}
+		return nil, false
+	}
+}
+
+// collectConditions walks a predicate expression, recording comparisons
+// against facts and the verdicts the expression requires to be present.
+func collectConditions(
+	node interface{},
+	lookup func(string) (interface{}, bool),
+	conditions *[]FactCondition,
+	verdicts *[]string,
+) {
+	expr, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if v, ok := expr["verdict_present"].(string); ok {
+		*verdicts = append(*verdicts, v)
+		return
+	}
+
+	if quantifier, ok := expr["quantifier"].(string); ok {
+		if domain, ok := expr["domain"].(map[string]interface{}); ok {
+			if id, ok := domain["fact_ref"].(string); ok {
+				*conditions = append(*conditions, newFactCondition(id, quantifier, nil, lookup))
+			}
+		}
+		collectConditions(expr["body"], lookup, conditions, verdicts)
+		return
+	}
+
+	op, _ := expr["op"].(string)
+	switch op {
+	case "and", "or":
+		collectConditions(expr["left"], lookup, conditions, verdicts)
+		collectConditions(expr["right"], lookup, conditions, verdicts)
+	case "not":
+		collectConditions(expr["operand"], lookup, conditions, verdicts)
+	case "=", "!=", "<", "<=", ">", ">=":
+		left, _ := expr["left"].(map[string]interface{})
+		right, _ := expr["right"].(map[string]interface{})
+		if id, ok := left["fact_ref"].(string); ok {
+			*conditions = append(*conditions, newFactCondition(id, op, literalOf(right), lookup))
+		} else if id, ok := right["fact_ref"].(string); ok {
+			*conditions = append(*conditions, newFactCondition(id, flipOp(op), literalOf(left), lookup))
+		} else {
+			collectConditions(left, lookup, conditions, verdicts)
+			collectConditions(right, lookup, conditions, verdicts)
+		}
+	}
+}
+
+func newFactCondition(
+	id, op string,
+	expected interface{},
+	lookup func(string) (interface{}, bool),
+) FactCondition {
+	c := FactCondition{FactID: id, Op: op, Expected: expected}
+	actual, ok := lookup(id)
+	if !ok {
+		c.Missing = true
+		return c
+	}
+	c.Actual = actual
+	c.Satisfied = compareValues(actual, op, expected)
+	return c
+}
+
+// literalOf returns the value of a literal operand, or nil.
+func literalOf(operand map[string]interface{}) interface{} {
+	if operand == nil {
+		return nil
+	}
+	return operand["literal"]
+}
+
+// flipOp mirrors a comparison so the fact is always on the left.
+func flipOp(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// compareValues applies op to a fact value and a literal after normalising
+// both through JSON. It returns false for anything it cannot compare.
+func compareValues(actual interface{}, op string, expected interface{}) bool {
+	if expected == nil {
+		return false
+	}
+	a, err := normalizeJSON(actual)
+	if err != nil {
+		return false
+	}
+	b, err := normalizeJSON(expected)
+	if err != nil {
+		return false
+	}
+
+	switch op {
+	case "=":
+		return isScalar(a) && reflect.DeepEqual(a, b)
+	case "!=":
+		return isScalar(a) && isScalar(b) && !reflect.DeepEqual(a, b)
+	}
+
+	x, ok1 := a.(float64)
+	y, ok2 := b.(float64)
+	if !ok1 || !ok2 {
+		return false
+	}
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	}
+	return false
+}
+
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case bool, string, float64:
+		return true
+	}
+	return false
+}
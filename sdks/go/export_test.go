@@ -0,0 +1,61 @@
+package tenor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/riverline-labs/tenor-go/internal/wasm"
+)
+
+// FailRuntimeCreations makes the next n runtime creations fail with a
+// transient error. The returned function restores normal creation.
+func FailRuntimeCreations(n int) (restore func()) {
+	orig := newRuntime
+	newRuntime = func(ctx context.Context, cfg wasm.Config) (*wasm.Runtime, error) {
+		if n > 0 {
+			n--
+			return nil, errors.New("simulated mmap failure")
+		}
+		return orig(ctx, cfg)
+	}
+	return func() { newRuntime = orig }
+}
+
+// CountRuntimeCreations counts the runtime creations attempted from now on.
+// The returned function restores uncounted creation.
+func CountRuntimeCreations() (count func() int, restore func()) {
+	orig := newRuntime
+	n := 0
+	newRuntime = func(ctx context.Context, cfg wasm.Config) (*wasm.Runtime, error) {
+		n++
+		return orig(ctx, cfg)
+	}
+	return func() int { return n }, func() { newRuntime = orig }
+}
+
+// MemorySize returns the size in bytes of e's WASM linear memory.
+func MemorySize(e *Evaluator) uint32 {
+	return e.runtime.MemorySize()
+}
+
+// AbortsMidCall reports whether an evaluator built with opts aborts a WASM
+// call when the context of a Context method variant is done mid-call.
+func AbortsMidCall(opts ...Option) bool {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.runtimeConfig().Interruptible
+}
+
+// DecodeResult decodes a raw bridge result into v as the evaluator does.
+func DecodeResult(funcName, target, result string, v interface{}) error {
+	return decodeResult(funcName, target, result, v, false)
+}
+
+// AttachFlow attaches the definition of r's flow from b, as the Evaluator
+// does for the flow results it returns.
+func AttachFlow(r *FlowResult, b *Bundle) *FlowResult {
+	r.flow, _ = b.Flow(r.FlowID)
+	return r
+}
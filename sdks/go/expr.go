@@ -0,0 +1,328 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expr is a node of a predicate expression, such as a rule's when clause or an
+// operation's precondition, as serialized in the interchange bundle. Which fields are set depends on the node:
+//
+//   - comparison: Left, Op ("=", "!=", "<", "<=", ">", ">="), Right
+//   - conjunction and disjunction: Left, Op ("and" or "or"), Right
+//   - negation: Op ("not"), Operand
+//   - multiplication: Left, Op ("*"), Literal, ResultType
+//   - quantifier: Quantifier ("forall" or "exists"), Variable, VariableType,
+//     Domain, Body
+//   - operands: FactRef, FieldRef, VerdictPresent, or Literal with Type
+type Expr struct {
+	Op       string `json:"op,omitempty"`
+	Left     *Expr  `json:"left,omitempty"`
+	Right    *Expr  `json:"right,omitempty"`
+	Operand  *Expr  `json:"operand,omitempty"`
+	FactRef  string `json:"fact_ref,omitempty"`
+	FieldRef *struct {
+		Var   string `json:"var"`
+		Field string `json:"field"`
+	} `json:"field_ref,omitempty"`
+	VerdictPresent string `json:"verdict_present,omitempty"`
+	// Literal is the raw literal value: a bool, number or string, or an
+	// object for Money and Decimal.
+	Literal json.RawMessage `json:"literal,omitempty"`
+	// Type is the type spec of a literal operand.
+	Type json.RawMessage `json:"type,omitempty"`
+	// ComparisonType is set on comparisons that need a common type, such as
+	// Money and Int x Decimal comparisons.
+	ComparisonType json.RawMessage `json:"comparison_type,omitempty"`
+	ResultType     json.RawMessage `json:"result_type,omitempty"`
+	Quantifier     string          `json:"quantifier,omitempty"`
+	Variable       string          `json:"variable,omitempty"`
+	VariableType   json.RawMessage `json:"variable_type,omitempty"`
+	Domain         *Expr           `json:"domain,omitempty"`
+	Body           *Expr           `json:"body,omitempty"`
+}
+
+// RuleCondition returns the when expression of ruleID, the condition that
+// gates its verdict. It fails if the rule does not exist or its expression
+// cannot be decoded.
+func (b *Bundle) RuleCondition(ruleID string) (Expr, error) {
+	rule, ok := b.Rule(ruleID)
+	if !ok {
+		return Expr{}, fmt.Errorf("rule %q not found", ruleID)
+	}
+	return rule.Body.condition()
+}
+
+// Condition returns the when expression of the rule that produced the
+// verdict, looked up in b. Together with FactsUsed it explains why the
+// verdict fired.
+func (p VerdictProvenance) Condition(b *Bundle) (Expr, error) {
+	return b.RuleCondition(p.Rule)
+}
+
+func (rb RuleBody) condition() (Expr, error) {
+	var e Expr
+	if err := json.Unmarshal(rb.When, &e); err != nil {
+		return Expr{}, fmt.Errorf("invalid when expression: %w", err)
+	}
+	return e, nil
+}
+
+// PreconditionExpr returns the operation's precondition as an expression
+// tree.
+func (op OperationDef) PreconditionExpr() (Expr, error) {
+	var e Expr
+	if err := json.Unmarshal(op.Precondition, &e); err != nil {
+		return Expr{}, fmt.Errorf("invalid precondition: %w", err)
+	}
+	return e, nil
+}
+
+// RequiredVerdicts returns the verdict types the precondition references, in
+// the order they appear and without duplicates. It is nil for a precondition
+// that references no verdicts or cannot be decoded.
+func (op OperationDef) RequiredVerdicts() []string {
+	expr, err := op.PreconditionExpr()
+	if err != nil {
+		return nil
+	}
+	var required []string
+	seen := make(map[string]bool)
+	for _, v := range verdictRefs(&expr, nil) {
+		if !seen[v] {
+			seen[v] = true
+			required = append(required, v)
+		}
+	}
+	return required
+}
+
+// PreconditionSatisfied reports whether the operation could execute given the
+// verdicts present and the entity states, without a WASM round trip. It
+// checks, in the evaluator's order:
+//
+//  1. the precondition, evaluated as the evaluator evaluates it when
+//     executing the operation: verdict_present tests against verdicts,
+//     combined with and, or and not. If it is false the reason is
+//     PreconditionNotMet, listing the required verdicts that are absent.
+//  2. that every entity the operation affects is in the effect's source
+//     state, under the default instance. If not, the reason is
+//     EntityNotInSourceState.
+//
+// Persona authorization is not checked. Preconditions that compare facts
+// cannot be decided without facts; they report MissingFacts naming the facts
+// involved.
+//
+// ComputeActionSpace uses a simpler test than execution: every verdict the
+// precondition references must be present. The two agree for preconditions
+// that are conjunctions of verdict_present, which is what the elaborator
+// produces from "requires" clauses.
+func (op OperationDef) PreconditionSatisfied(verdicts []VerdictSummary, states EntityStateMap) (bool, BlockedReason) {
+	expr, err := op.PreconditionExpr()
+	if err != nil {
+		return false, BlockedReason{Type: ReasonPreconditionNotMet}
+	}
+	present := make(map[string]bool, len(verdicts))
+	for _, v := range verdicts {
+		present[v.VerdictType] = true
+	}
+
+	var facts []string
+	ok, decided := evalVerdictPredicate(&expr, present, &facts)
+	if !decided {
+		return false, BlockedReason{Type: ReasonMissingFacts, FactIDs: facts}
+	}
+	if !ok {
+		var missing []string
+		for _, v := range op.RequiredVerdicts() {
+			if !present[v] {
+				missing = append(missing, v)
+			}
+		}
+		return false, BlockedReason{Type: ReasonPreconditionNotMet, MissingVerdicts: missing}
+	}
+
+	for _, effect := range op.Effects {
+		current, ok := states[effect.EntityID]
+		if !ok {
+			current = "(unknown)"
+		}
+		if current != effect.From {
+			return false, BlockedReason{
+				Type:          ReasonEntityNotInSourceState,
+				EntityID:      effect.EntityID,
+				CurrentState:  current,
+				RequiredState: effect.From,
+			}
+		}
+	}
+	return true, BlockedReason{}
+}
+
+// evalVerdictPredicate evaluates e over the verdicts in present. decided is
+// false when the result depends on facts, which are then appended to facts.
+// An operand that cannot be decided does not matter when the other operand
+// of an and or or settles the result.
+func evalVerdictPredicate(e *Expr, present map[string]bool, facts *[]string) (result, decided bool) {
+	switch {
+	case e == nil:
+		return false, false
+	case e.VerdictPresent != "":
+		return present[e.VerdictPresent], true
+	case e.Op == "not":
+		v, ok := evalVerdictPredicate(e.Operand, present, facts)
+		return !v, ok
+	case e.Op == "and" || e.Op == "or":
+		left, ok := evalVerdictPredicate(e.Left, present, facts)
+		if ok && left == (e.Op == "or") {
+			return left, true
+		}
+		right, rok := evalVerdictPredicate(e.Right, present, facts)
+		if !ok {
+			if rok && right == (e.Op == "or") {
+				return right, true
+			}
+			return false, false
+		}
+		return right, rok
+	case e.Op == "" && e.Quantifier == "" && len(e.Literal) > 0:
+		var b bool
+		if err := json.Unmarshal(e.Literal, &b); err != nil {
+			return false, false
+		}
+		return b, true
+	}
+	*facts = append(*facts, factRefs(e, nil)...)
+	return false, false
+}
+
+// factRefs appends the facts e references to refs.
+func factRefs(e *Expr, refs []string) []string {
+	if e == nil {
+		return refs
+	}
+	if e.FactRef != "" {
+		refs = append(refs, e.FactRef)
+	}
+	for _, child := range []*Expr{e.Left, e.Right, e.Operand, e.Domain, e.Body} {
+		refs = factRefs(child, refs)
+	}
+	return refs
+}
+
+// Expression precedence, loosest first. A quantifier body extends as far
+// right as possible, so a quantifier binds loosest of all.
+const (
+	precQuantifier = iota
+	precOr
+	precAnd
+	precCompare
+	precMul
+	precNot
+	precAtom
+)
+
+func (e *Expr) precedence() int {
+	switch {
+	case e.Quantifier != "":
+		return precQuantifier
+	case e.Op == "or":
+		return precOr
+	case e.Op == "and":
+		return precAnd
+	case e.Op == "not":
+		return precNot
+	case e.Op == "*":
+		return precMul
+	case e.Op != "":
+		return precCompare
+	}
+	return precAtom
+}
+
+// String renders e in Tenor source notation, e.g.
+// "is_active = true ∧ ¬verdict_present(flagged)". Parentheses are added only
+// where precedence requires them.
+func (e Expr) String() string {
+	var b strings.Builder
+	e.write(&b)
+	return b.String()
+}
+
+func (e *Expr) write(b *strings.Builder) {
+	switch {
+	case e.Quantifier != "":
+		sym := "∀"
+		if e.Quantifier == "exists" {
+			sym = "∃"
+		}
+		fmt.Fprintf(b, "%s %s ∈ ", sym, e.Variable)
+		e.Domain.writeOperand(b, precAtom)
+		b.WriteString(" . ")
+		e.Body.writeOperand(b, precQuantifier)
+	case e.Op == "not":
+		b.WriteString("¬")
+		e.Operand.writeOperand(b, precNot)
+	case e.Op == "*":
+		e.Left.writeOperand(b, precMul)
+		b.WriteString(" * ")
+		b.WriteString(renderLiteral(e.Literal))
+	case e.Op != "":
+		sym, prec := e.Op, precCompare+1
+		switch e.Op {
+		case "and":
+			sym, prec = "∧", precAnd
+		case "or":
+			sym, prec = "∨", precOr
+		}
+		e.Left.writeOperand(b, prec)
+		fmt.Fprintf(b, " %s ", sym)
+		e.Right.writeOperand(b, prec)
+	case e.FactRef != "":
+		b.WriteString(e.FactRef)
+	case e.FieldRef != nil:
+		fmt.Fprintf(b, "%s.%s", e.FieldRef.Var, e.FieldRef.Field)
+	case e.VerdictPresent != "":
+		fmt.Fprintf(b, "verdict_present(%s)", e.VerdictPresent)
+	default:
+		b.WriteString(renderLiteral(e.Literal))
+	}
+}
+
+// writeOperand writes e, parenthesized if it binds looser than min.
+func (e *Expr) writeOperand(b *strings.Builder, min int) {
+	if e == nil {
+		b.WriteString("?")
+		return
+	}
+	if e.precedence() < min {
+		b.WriteByte('(')
+		e.write(b)
+		b.WriteByte(')')
+		return
+	}
+	e.write(b)
+}
+
+// renderLiteral renders a literal value: Money as "<amount> <currency>",
+// Decimal as its value, strings quoted and everything else as JSON.
+func renderLiteral(raw json.RawMessage) string {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case map[string]interface{}:
+		if amount, ok := v["amount"].(map[string]interface{}); ok {
+			return fmt.Sprintf("%v %v", amount["value"], v["currency"])
+		}
+		if value, ok := v["value"]; ok {
+			return fmt.Sprint(value)
+		}
+	}
+	return string(raw)
+}
@@ -0,0 +1,85 @@
+package tenor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// EvaluateJSON evaluates a fact set given as a raw JSON object, as received
+// from an external source. Numbers are decoded as json.Number, so they reach
+// the evaluator exactly as written.
+//
+// encoding/json keeps the last of several duplicate keys, which can hide an
+// upstream bug behind an unexpected value. With WithRejectDuplicateKeys the
+// input is scanned first and a duplicate key, in the fact object or in any
+// object nested in a fact value, fails with a *DuplicateFactKeyError.
+func (e *Evaluator) EvaluateJSON(factsJSON []byte) (*VerdictSet, error) {
+	if e.opts.rejectDuplicateKeys {
+		if err := checkDuplicateKeys(factsJSON); err != nil {
+			return nil, err
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(factsJSON))
+	dec.UseNumber()
+	var facts FactSet
+	if err := dec.Decode(&facts); err != nil {
+		return nil, fmt.Errorf("failed to decode facts: %w", err)
+	}
+	return e.Evaluate(facts)
+}
+
+// checkDuplicateKeys returns a *DuplicateFactKeyError for the first object
+// in data holding a key twice. Malformed JSON is left to the decoder.
+func checkDuplicateKeys(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := scanDuplicateKeys(dec, "")
+	if _, ok := err.(*DuplicateFactKeyError); ok {
+		return err
+	}
+	return nil
+}
+
+// scanDuplicateKeys consumes one JSON value from dec. path locates the value
+// in the fact set, as in DuplicateFactKeyError.
+func scanDuplicateKeys(dec *json.Decoder, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := tok.(string)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if seen[key] {
+				return &DuplicateFactKeyError{Path: keyPath}
+			}
+			seen[key] = true
+			if err := scanDuplicateKeys(dec, keyPath); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := scanDuplicateKeys(dec, path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+	return nil
+}
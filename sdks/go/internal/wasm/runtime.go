@@ -4,15 +4,19 @@
 //
 // The WASM binary is produced by crates/tenor-wasm-bridge (wasm32-wasip1).
 // It uses an alloc/dealloc/get_result_ptr/get_result_len memory protocol for
-// passing strings without wasm-bindgen or JS glue code.
+// passing strings without wasm-bindgen or JS glue code; the optional
+// free_result export releases the result buffer once it has been read.
 // WASI imports are satisfied by wazero's built-in WASI snapshot_preview1 module.
 package wasm
 
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
@@ -25,6 +29,208 @@ import (
 //go:embed tenor_eval.wasm
 var wasmBinary []byte
 
+// requiredExports lists every function the SDK calls on the bridge module.
+var requiredExports = []string{
+	"alloc",
+	"dealloc",
+	"get_result_ptr",
+	"get_result_len",
+	"load_contract",
+	"evaluate",
+	"compute_action_space",
+	"simulate_flow",
+	"simulate_flow_with_bindings",
+	"list_flows",
+	"list_personas",
+	"list_facts",
+	"list_entities",
+}
+
+// ErrIncompatible is matched by errors.Is for a MissingExportsError.
+var ErrIncompatible = errors.New("incompatible WASM binary")
+
+// ErrStackOverflow is returned when a call exhausts the WASM call stack, which
+// wazero reports as a "stack overflow" trap. wazero's call-stack ceiling is
+// fixed; the bridge's own shadow stack is sized when the binary is linked
+// (see scripts/build-wasm.sh).
+var ErrStackOverflow = errors.New("evaluation too complex: WASM call stack exhausted")
+
+// classifyCallError maps known wazero traps to sentinel errors.
+func classifyCallError(funcName string, err error) error {
+	if strings.Contains(err.Error(), "stack overflow") {
+		return fmt.Errorf("WASM call %q failed: %w (%v)", funcName, ErrStackOverflow, err)
+	}
+	return fmt.Errorf("WASM call %q failed: %w", funcName, err)
+}
+
+// ErrTimeout is returned when a call outlives Config.Watchdog. The runtime is
+// closed by then, and every later call fails with ErrTimeout too.
+var ErrTimeout = errors.New("WASM call timed out")
+
+// ErrUnsupportedFeatures is returned when the binary uses WebAssembly
+// features the runtime configuration disables.
+var ErrUnsupportedFeatures = errors.New("runtime configuration disables WebAssembly features the binary uses")
+
+// ErrResultTooLarge is returned when a call's result exceeds
+// Config.MaxResultBytes. The result is not read.
+var ErrResultTooLarge = errors.New("WASM result too large")
+
+// MissingExportsError reports every required export a WASM module lacks.
+type MissingExportsError struct {
+	Missing []string
+}
+
+func (e *MissingExportsError) Error() string {
+	return fmt.Sprintf("%v: missing exports: %s", ErrIncompatible, strings.Join(e.Missing, ", "))
+}
+
+// Is makes errors.Is(err, ErrIncompatible) true.
+func (e *MissingExportsError) Is(target error) bool {
+	return target == ErrIncompatible
+}
+
+// ErrMissingImport is matched by errors.Is for a MissingImportsError.
+var ErrMissingImport = errors.New("unsatisfied WASM import")
+
+// MissingImportsError reports every import of a WASM module that the runtime
+// cannot satisfy, e.g. a host function the binary expects but the SDK does
+// not provide.
+type MissingImportsError struct {
+	// Imports describes each unsatisfied import, e.g.
+	// "env.log: func(i32, i32) (module not available)".
+	Imports []string
+}
+
+func (e *MissingImportsError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrMissingImport, strings.Join(e.Imports, "; "))
+}
+
+// Is makes errors.Is(err, ErrMissingImport) true.
+func (e *MissingImportsError) Is(target error) bool {
+	return target == ErrMissingImport
+}
+
+// checkImports verifies that every function and memory compiled imports is
+// exported, with a matching signature, by a module already instantiated in r.
+func checkImports(r wazero.Runtime, compiled wazero.CompiledModule) error {
+	var missing []string
+	for _, def := range compiled.ImportedFunctions() {
+		moduleName, name, _ := def.Import()
+		desc := fmt.Sprintf("%s.%s: %s", moduleName, name, signature(def))
+		host := r.Module(moduleName)
+		if host == nil {
+			missing = append(missing, desc+" (module not available)")
+			continue
+		}
+		exported, ok := host.ExportedFunctionDefinitions()[name]
+		if !ok {
+			missing = append(missing, desc+" (not exported)")
+			continue
+		}
+		if signature(exported) != signature(def) {
+			missing = append(missing, fmt.Sprintf("%s (host provides %s)", desc, signature(exported)))
+		}
+	}
+	for _, def := range compiled.ImportedMemories() {
+		moduleName, name, _ := def.Import()
+		desc := fmt.Sprintf("%s.%s: memory", moduleName, name)
+		host := r.Module(moduleName)
+		if host == nil {
+			missing = append(missing, desc+" (module not available)")
+			continue
+		}
+		if _, ok := host.ExportedMemoryDefinitions()[name]; !ok {
+			missing = append(missing, desc+" (not exported)")
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingImportsError{Imports: missing}
+	}
+	return nil
+}
+
+// signature formats a function type as "func(i32, i32) i32".
+func signature(def api.FunctionDefinition) string {
+	names := func(types []api.ValueType) []string {
+		out := make([]string, len(types))
+		for i, t := range types {
+			out[i] = api.ValueTypeName(t)
+		}
+		return out
+	}
+	sig := "func(" + strings.Join(names(def.ParamTypes()), ", ") + ")"
+	switch results := names(def.ResultTypes()); len(results) {
+	case 0:
+	case 1:
+		sig += " " + results[0]
+	default:
+		sig += " (" + strings.Join(results, ", ") + ")"
+	}
+	return sig
+}
+
+// checkExports verifies that mod exports every function in requiredExports.
+func checkExports(mod api.Module) error {
+	var missing []string
+	for _, name := range requiredExports {
+		if mod.ExportedFunction(name) == nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingExportsError{Missing: missing}
+	}
+	return nil
+}
+
+// AllocStrategy selects how string arguments are copied into WASM memory.
+type AllocStrategy int
+
+const (
+	// AllocArena makes a single alloc call large enough for every argument of
+	// a call, writes the arguments contiguously, and frees the block with a
+	// single dealloc. This is the default.
+	AllocArena AllocStrategy = iota
+	// AllocPerArg allocates and frees each argument separately.
+	AllocPerArg
+)
+
+// Config holds runtime settings chosen by the caller.
+type Config struct {
+	AllocStrategy AllocStrategy
+	// Watchdog, if positive, bounds every call: a call still running after
+	// Watchdog is abandoned, the runtime is closed to interrupt it, and the
+	// Runtime is poisoned.
+	Watchdog time.Duration
+	// Interruptible makes a call abort when the context passed to
+	// CallHandleContext is done mid-call. The runtime is closed by then, and
+	// every later call fails.
+	Interruptible bool
+	// MaxResultBytes, if positive, is the largest result a call may return.
+	MaxResultBytes int
+	// RuntimeConfig, if set, replaces the default wazero configuration. The
+	// settings the runtime depends on (see NewRuntime) are applied on top.
+	RuntimeConfig wazero.RuntimeConfig
+	// Cache, if set, shares compiled modules between runtimes, so only the
+	// first runtime created with it compiles the binary.
+	Cache Cache
+}
+
+// Cache holds compiled modules for reuse across runtimes. Closing a Runtime
+// does not close its Cache.
+type Cache = wazero.CompilationCache
+
+// NewCache returns an empty in-memory Cache.
+func NewCache() Cache {
+	return wazero.NewCompilationCache()
+}
+
+// NewDirCache returns a Cache that also persists compiled modules in dir,
+// creating it if needed, so they survive process restarts.
+func NewDirCache(dir string) (Cache, error) {
+	return wazero.NewCompilationCacheWithDir(dir)
+}
+
 // Runtime manages the wazero WASM runtime and the loaded Tenor module instance.
 // It is safe for concurrent use; all WASM calls are serialised by a mutex
 // because the WASM module is single-threaded.
@@ -33,82 +239,116 @@ type Runtime struct {
 	runtime wazero.Runtime
 	module  api.Module
 	ctx     context.Context
+	cfg     Config
+	closed  bool
+	// poisoned is set when the watchdog closed the runtime under a call.
+	poisoned bool
+	// cancelled is the context error that closed the runtime under a call.
+	cancelled error
+
+	// Call counters, updated under mu by callLocked.
+	calls    map[string]int64
+	wasmTime time.Duration
+	lastCall time.Time
+}
+
+// Stats is a snapshot of a Runtime's call counters.
+type Stats struct {
+	// Calls counts the calls made to each exported function, including
+	// calls that failed.
+	Calls map[string]int64
+	// WASMTime is the total wall time spent in calls.
+	WASMTime time.Duration
+	// LastCall is when the most recent call finished; zero if none has.
+	LastCall time.Time
 }
 
 // NewRuntime creates a new wazero runtime and instantiates the Tenor WASM module.
 // The caller must call Close() when done.
-func NewRuntime(ctx context.Context) (*Runtime, error) {
-	r := wazero.NewRuntime(ctx)
+//
+// The runtime is configured from cfg.RuntimeConfig, or wazero's default
+// configuration, with two settings overridden: close-on-context-done is
+// enabled when cfg.Watchdog or cfg.Interruptible is set, and cfg.Cache, when set, replaces the
+// compilation cache.
+func NewRuntime(ctx context.Context, cfg Config) (*Runtime, error) {
+	rc := cfg.RuntimeConfig
+	if rc == nil {
+		rc = wazero.NewRuntimeConfig()
+	}
+	// Closing a module only interrupts a running call when the runtime was
+	// configured for it, which costs a little on every call; the watchdog
+	// depends on it.
+	if cfg.Watchdog > 0 || cfg.Interruptible {
+		rc = rc.WithCloseOnContextDone(true)
+	}
+	if cfg.Cache != nil {
+		rc = rc.WithCompilationCache(cfg.Cache)
+	}
+	r := wazero.NewRuntimeWithConfig(ctx, rc)
 
 	// The WASM bridge is compiled for wasm32-wasip1, so it imports WASI
 	// functions. Instantiate the WASI snapshot_preview1 host module first.
 	wasi_snapshot_preview1.MustInstantiate(ctx, r)
 
-	// Instantiate the Tenor evaluator module. WithStartFunctions("") prevents
-	// wazero from calling _start (the WASI entry point), since our module is
-	// a library, not a CLI program — it has no _start function.
-	mod, err := r.InstantiateWithConfig(ctx, wasmBinary,
-		wazero.NewModuleConfig().
-			WithName("tenor-eval").
-			WithStartFunctions()) // empty = don't call _start
+	mod, err := instantiate(ctx, r, wasmBinary)
 	if err != nil {
 		_ = r.Close(ctx)
-		return nil, fmt.Errorf("failed to instantiate Tenor WASM module: %w", err)
+		return nil, err
+	}
+
+	// Fail fast on a mismatched binary rather than on the first call that
+	// needs a missing export.
+	if err := checkExports(mod); err != nil {
+		_ = r.Close(ctx)
+		return nil, err
 	}
 
 	return &Runtime{
 		runtime: r,
 		module:  mod,
 		ctx:     ctx,
+		cfg:     cfg,
+		calls:   make(map[string]int64),
 	}, nil
 }
 
-// CallOneArg calls a WASM function that takes a single string argument (ptr, len)
-// and writes its result to the result buffer.
-// Returns the JSON result string from get_result_ptr/get_result_len.
-func (rt *Runtime) CallOneArg(funcName string, arg string) (string, error) {
-	rt.mu.Lock()
-	defer rt.mu.Unlock()
-
-	ptr, free, err := rt.writeString(arg)
+// instantiate compiles binary and instantiates it in r, reporting every
+// import r cannot satisfy before attempting instantiation.
+func instantiate(ctx context.Context, r wazero.Runtime, binary []byte) (api.Module, error) {
+	compiled, err := r.CompileModule(ctx, binary)
 	if err != nil {
-		return "", err
+		if strings.Contains(err.Error(), "is disabled") {
+			return nil, fmt.Errorf("failed to compile Tenor WASM module: %w: %v", ErrUnsupportedFeatures, err)
+		}
+		return nil, fmt.Errorf("failed to compile Tenor WASM module: %w", err)
 	}
-	defer free()
-
-	fn := rt.module.ExportedFunction(funcName)
-	if fn == nil {
-		return "", fmt.Errorf("WASM function %q not found", funcName)
+	if err := checkImports(r, compiled); err != nil {
+		return nil, err
 	}
 
-	if _, err := fn.Call(rt.ctx, uint64(ptr), uint64(len(arg))); err != nil {
-		return "", fmt.Errorf("WASM call %q failed: %w", funcName, err)
+	// Instantiate the Tenor evaluator module. WithStartFunctions("") prevents
+	// wazero from calling _start (the WASI entry point), since our module is
+	// a library, not a CLI program — it has no _start function.
+	mod, err := r.InstantiateModule(ctx, compiled,
+		wazero.NewModuleConfig().
+			WithName("tenor-eval").
+			WithStartFunctions()) // empty = don't call _start
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate Tenor WASM module: %w", err)
 	}
+	return mod, nil
+}
 
-	return rt.readResult()
+// CallOneArg calls a WASM function that takes a single string argument (ptr, len)
+// and writes its result to the result buffer.
+// Returns the JSON result string from get_result_ptr/get_result_len.
+func (rt *Runtime) CallOneArg(funcName string, arg string) (string, error) {
+	return rt.call(funcName, nil, arg)
 }
 
 // CallHandleOneArg calls a WASM function with (handle u32, arg_ptr, arg_len).
 func (rt *Runtime) CallHandleOneArg(funcName string, handle uint32, arg string) (string, error) {
-	rt.mu.Lock()
-	defer rt.mu.Unlock()
-
-	ptr, free, err := rt.writeString(arg)
-	if err != nil {
-		return "", err
-	}
-	defer free()
-
-	fn := rt.module.ExportedFunction(funcName)
-	if fn == nil {
-		return "", fmt.Errorf("WASM function %q not found", funcName)
-	}
-
-	if _, err := fn.Call(rt.ctx, uint64(handle), uint64(ptr), uint64(len(arg))); err != nil {
-		return "", fmt.Errorf("WASM call %q failed: %w", funcName, err)
-	}
-
-	return rt.readResult()
+	return rt.call(funcName, []uint64{uint64(handle)}, arg)
 }
 
 // CallHandleThreeArgs calls a WASM function with
@@ -119,43 +359,7 @@ func (rt *Runtime) CallHandleThreeArgs(
 	handle uint32,
 	arg1, arg2, arg3 string,
 ) (string, error) {
-	rt.mu.Lock()
-	defer rt.mu.Unlock()
-
-	ptr1, free1, err := rt.writeString(arg1)
-	if err != nil {
-		return "", err
-	}
-	defer free1()
-
-	ptr2, free2, err := rt.writeString(arg2)
-	if err != nil {
-		return "", err
-	}
-	defer free2()
-
-	ptr3, free3, err := rt.writeString(arg3)
-	if err != nil {
-		return "", err
-	}
-	defer free3()
-
-	fn := rt.module.ExportedFunction(funcName)
-	if fn == nil {
-		return "", fmt.Errorf("WASM function %q not found", funcName)
-	}
-
-	params := []uint64{
-		uint64(handle),
-		uint64(ptr1), uint64(len(arg1)),
-		uint64(ptr2), uint64(len(arg2)),
-		uint64(ptr3), uint64(len(arg3)),
-	}
-	if _, err := fn.Call(rt.ctx, params...); err != nil {
-		return "", fmt.Errorf("WASM call %q failed: %w", funcName, err)
-	}
-
-	return rt.readResult()
+	return rt.call(funcName, []uint64{uint64(handle)}, arg1, arg2, arg3)
 }
 
 // CallHandleFiveArgs calls a WASM function with
@@ -166,112 +370,286 @@ func (rt *Runtime) CallHandleFiveArgs(
 	handle uint32,
 	arg1, arg2, arg3, arg4, arg5 string,
 ) (string, error) {
+	return rt.call(funcName, []uint64{uint64(handle)}, arg1, arg2, arg3, arg4, arg5)
+}
+
+// CallHandleFourArgs calls a WASM function with
+// (handle, a1_ptr, a1_len, a2_ptr, a2_len, a3_ptr, a3_len, a4_ptr, a4_len).
+// This is used for simulate_flow (no instance_bindings).
+func (rt *Runtime) CallHandleFourArgs(
+	funcName string,
+	handle uint32,
+	arg1, arg2, arg3, arg4 string,
+) (string, error) {
+	return rt.call(funcName, []uint64{uint64(handle)}, arg1, arg2, arg3, arg4)
+}
+
+// CallHandleContext calls a WASM function with (handle u32, arg ptr/len
+// pairs...), like the fixed-arity CallHandle methods, under ctx. A ctx that
+// is already done fails the call before it starts; with
+// Config.Interruptible, one done mid-call aborts it. Either way the error
+// wraps ctx.Err().
+func (rt *Runtime) CallHandleContext(ctx context.Context, funcName string, handle uint32, args ...string) (string, error) {
 	rt.mu.Lock()
 	defer rt.mu.Unlock()
+	return rt.callLocked(ctx, funcName, []uint64{uint64(handle)}, args...)
+}
 
-	args := []string{arg1, arg2, arg3, arg4, arg5}
-	ptrs := make([]uint32, len(args))
-	frees := make([]func(), len(args))
+// call copies args into WASM memory, invokes funcName with the leading params
+// followed by a (ptr, len) pair per argument, and returns the result buffer.
+func (rt *Runtime) call(funcName string, leading []uint64, args ...string) (string, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.callLocked(rt.ctx, funcName, leading, args...)
+}
 
-	for i, arg := range args {
-		ptr, free, err := rt.writeStringUnlocked(arg)
-		if err != nil {
-			// Free already-allocated buffers
-			for j := 0; j < i; j++ {
-				frees[j]()
-			}
-			return "", err
+// CallHandleBatch calls funcName with (handle, arg ptr/len pairs...) once per
+// argument set, holding the runtime lock for the whole batch so that other
+// callers cannot interleave. Results and errors are aligned with argSets; a
+// nil argument set is skipped and leaves both entries zero.
+func (rt *Runtime) CallHandleBatch(funcName string, handle uint32, argSets [][]string) ([]string, []error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	results := make([]string, len(argSets))
+	errs := make([]error, len(argSets))
+	for i, args := range argSets {
+		if args == nil {
+			continue
 		}
-		ptrs[i] = ptr
-		frees[i] = free
+		results[i], errs[i] = rt.callLocked(rt.ctx, funcName, []uint64{uint64(handle)}, args...)
+	}
+	return results, errs
+}
+
+// callLocked is call without locking, under ctx and the watchdog if one is
+// configured. Must be called while holding rt.mu.
+func (rt *Runtime) callLocked(ctx context.Context, funcName string, leading []uint64, args ...string) (string, error) {
+	if rt.poisoned {
+		return "", fmt.Errorf("WASM call %q: runtime closed by an earlier timeout: %w", funcName, ErrTimeout)
+	}
+	if rt.cancelled != nil {
+		return "", fmt.Errorf("WASM call %q: runtime closed by an earlier cancellation: %w", funcName, rt.cancelled)
 	}
+	if rt.closed {
+		return "", fmt.Errorf("WASM call %q: runtime closed", funcName)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("WASM call %q: %w", funcName, err)
+	}
+	start := time.Now()
 	defer func() {
-		for _, free := range frees {
-			free()
+		rt.lastCall = time.Now()
+		rt.wasmTime += rt.lastCall.Sub(start)
+		rt.calls[funcName]++
+	}()
+	var result string
+	var err error
+	if rt.cfg.Watchdog <= 0 {
+		result, err = rt.invoke(ctx, funcName, leading, args...)
+	} else {
+		result, err = runWatched(rt.cfg.Watchdog, func() (string, error) {
+			return rt.invoke(ctx, funcName, leading, args...)
+		}, func() {
+			rt.poisoned = true
+			rt.closed = true
+			_ = rt.runtime.Close(rt.ctx)
+		})
+		if errors.Is(err, ErrTimeout) {
+			return "", fmt.Errorf("WASM call %q exceeded %v: %w", funcName, rt.cfg.Watchdog, err)
 		}
+	}
+	// With close-on-context-done, wazero closes the module when ctx is done
+	// mid-call, so the module is unusable from here on. The runtime itself
+	// is left for Close.
+	if err != nil && ctx.Err() != nil && (rt.cfg.Interruptible || rt.cfg.Watchdog > 0) {
+		rt.cancelled = ctx.Err()
+		return "", fmt.Errorf("WASM call %q aborted: %w", funcName, ctx.Err())
+	}
+	return result, err
+}
+
+// Stats returns a snapshot of the call counters.
+func (rt *Runtime) Stats() Stats {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	calls := make(map[string]int64, len(rt.calls))
+	for name, n := range rt.calls {
+		calls[name] = n
+	}
+	return Stats{Calls: calls, WASMTime: rt.wasmTime, LastCall: rt.lastCall}
+}
+
+// runWatched runs call on a new goroutine and waits up to d for it to finish.
+// If it does not, runWatched calls kill and returns ErrTimeout without
+// waiting further; the goroutine lingers until kill makes call return.
+func runWatched(d time.Duration, call func() (string, error), kill func()) (string, error) {
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := call()
+		done <- outcome{result, err}
 	}()
 
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-timer.C:
+		kill()
+		return "", ErrTimeout
+	}
+}
+
+// invoke copies args into WASM memory, calls funcName and reads the result.
+func (rt *Runtime) invoke(ctx context.Context, funcName string, leading []uint64, args ...string) (string, error) {
+	ptrs, free, err := rt.writeArgs(args)
+	if err != nil {
+		return "", err
+	}
+	defer free()
+
 	fn := rt.module.ExportedFunction(funcName)
 	if fn == nil {
 		return "", fmt.Errorf("WASM function %q not found", funcName)
 	}
 
-	params := []uint64{uint64(handle)}
+	params := append([]uint64(nil), leading...)
 	for i, ptr := range ptrs {
 		params = append(params, uint64(ptr), uint64(len(args[i])))
 	}
 
-	if _, err := fn.Call(rt.ctx, params...); err != nil {
-		return "", fmt.Errorf("WASM call %q failed: %w", funcName, err)
+	if _, err := fn.Call(ctx, params...); err != nil {
+		return "", classifyCallError(funcName, err)
 	}
 
 	return rt.readResult()
 }
 
-// CallHandleFourArgs calls a WASM function with
-// (handle, a1_ptr, a1_len, a2_ptr, a2_len, a3_ptr, a3_len, a4_ptr, a4_len).
-// This is used for simulate_flow (no instance_bindings).
-func (rt *Runtime) CallHandleFourArgs(
-	funcName string,
-	handle uint32,
-	arg1, arg2, arg3, arg4 string,
-) (string, error) {
+// Close releases all WASM runtime resources. It is idempotent: calls after
+// the first return nil without touching the runtime again.
+func (rt *Runtime) Close() error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if rt.closed {
+		return nil
+	}
+	rt.closed = true
+	return rt.runtime.Close(rt.ctx)
+}
+
+// CloseHandles calls freeFunc(handle) for each handle, then closes the
+// runtime like Close. The handles are only freed while the module is still
+// usable; a failed free is ignored because closing the runtime reclaims its
+// memory anyway. Calls after the first return nil without freeing again.
+func (rt *Runtime) CloseHandles(freeFunc string, handles ...uint32) error {
 	rt.mu.Lock()
 	defer rt.mu.Unlock()
 
-	args := []string{arg1, arg2, arg3, arg4}
+	if rt.closed {
+		return nil
+	}
+	if !rt.poisoned && rt.cancelled == nil {
+		for _, h := range handles {
+			_, _ = rt.callLocked(rt.ctx, freeFunc, []uint64{uint64(h)})
+		}
+	}
+	rt.closed = true
+	return rt.runtime.Close(rt.ctx)
+}
+
+// writeArgs copies args into WASM memory using the configured strategy and
+// returns a pointer per argument plus a function that frees them all.
+// Must be called while holding rt.mu.
+func (rt *Runtime) writeArgs(args []string) ([]uint32, func(), error) {
+	if rt.cfg.AllocStrategy == AllocPerArg {
+		return rt.writeArgsPerArg(args)
+	}
+	return rt.writeArgsArena(args)
+}
+
+// writeArgsArena writes all args into one contiguous allocation: one alloc
+// and one dealloc per call regardless of the argument count.
+func (rt *Runtime) writeArgsArena(args []string) ([]uint32, func(), error) {
+	total := 0
+	for _, arg := range args {
+		total += len(arg)
+	}
+
+	arena, free, err := rt.allocUnlocked(total)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mem := rt.module.Memory()
+	ptrs := make([]uint32, len(args))
+	offset := arena
+	for i, arg := range args {
+		if len(arg) == 0 {
+			continue
+		}
+		if ok := mem.WriteString(offset, arg); !ok {
+			free()
+			return nil, nil, fmt.Errorf("failed to write %d bytes to WASM memory at offset %d", len(arg), offset)
+		}
+		ptrs[i] = offset
+		offset += uint32(len(arg))
+	}
+	return ptrs, free, nil
+}
+
+// writeArgsPerArg allocates each argument separately.
+func (rt *Runtime) writeArgsPerArg(args []string) ([]uint32, func(), error) {
 	ptrs := make([]uint32, len(args))
 	frees := make([]func(), len(args))
 
 	for i, arg := range args {
 		ptr, free, err := rt.writeStringUnlocked(arg)
 		if err != nil {
+			// Free already-allocated buffers
 			for j := 0; j < i; j++ {
 				frees[j]()
 			}
-			return "", err
+			return nil, nil, err
 		}
 		ptrs[i] = ptr
 		frees[i] = free
 	}
-	defer func() {
+
+	return ptrs, func() {
 		for _, free := range frees {
 			free()
 		}
-	}()
-
-	fn := rt.module.ExportedFunction(funcName)
-	if fn == nil {
-		return "", fmt.Errorf("WASM function %q not found", funcName)
-	}
+	}, nil
+}
 
-	params := []uint64{uint64(handle)}
-	for i, ptr := range ptrs {
-		params = append(params, uint64(ptr), uint64(len(args[i])))
+// writeStringUnlocked allocates memory in the WASM module for arg, writes the
+// bytes, and returns a pointer, a cleanup function, and any error.
+// Call only when rt.mu is held.
+func (rt *Runtime) writeStringUnlocked(arg string) (uint32, func(), error) {
+	ptr, free, err := rt.allocUnlocked(len(arg))
+	if err != nil || len(arg) == 0 {
+		return ptr, free, err
 	}
 
-	if _, err := fn.Call(rt.ctx, params...); err != nil {
-		return "", fmt.Errorf("WASM call %q failed: %w", funcName, err)
+	mem := rt.module.Memory()
+	if ok := mem.WriteString(ptr, arg); !ok {
+		free()
+		return 0, nil, fmt.Errorf("failed to write %d bytes to WASM memory at offset %d", len(arg), ptr)
 	}
-
-	return rt.readResult()
-}
-
-// Close releases all WASM runtime resources.
-func (rt *Runtime) Close() error {
-	return rt.runtime.Close(rt.ctx)
-}
-
-// writeString allocates memory in the WASM module for arg, writes the bytes,
-// and returns a pointer, a cleanup function, and any error.
-// Acquires the mutex — do not call from within a locked region.
-func (rt *Runtime) writeString(arg string) (uint32, func(), error) {
-	return rt.writeStringUnlocked(arg)
+	return ptr, free, nil
 }
 
-// writeStringUnlocked is the unlocked version — call only when rt.mu is held.
-func (rt *Runtime) writeStringUnlocked(arg string) (uint32, func(), error) {
-	if len(arg) == 0 {
+// allocUnlocked reserves n bytes of WASM memory and returns the pointer and a
+// function that releases it. Call only when rt.mu is held.
+func (rt *Runtime) allocUnlocked(n int) (uint32, func(), error) {
+	if n == 0 {
 		// Return a valid pointer of length 0. The WASM alloc(0) behaviour is
 		// unspecified; use offset 0 (safe because len is 0, so the pointer
 		// is never dereferenced).
@@ -284,20 +662,15 @@ func (rt *Runtime) writeStringUnlocked(arg string) (uint32, func(), error) {
 		return 0, nil, fmt.Errorf("WASM function \"alloc\" not found")
 	}
 
-	results, err := allocFn.Call(rt.ctx, uint64(len(arg)))
+	results, err := allocFn.Call(rt.ctx, uint64(n))
 	if err != nil {
-		return 0, nil, fmt.Errorf("WASM alloc(%d) failed: %w", len(arg), err)
+		return 0, nil, fmt.Errorf("WASM alloc(%d) failed: %w", n, err)
 	}
 	ptr := uint32(results[0])
 
-	mem := rt.module.Memory()
-	if ok := mem.Write(ptr, []byte(arg)); !ok {
-		return 0, nil, fmt.Errorf("failed to write %d bytes to WASM memory at offset %d", len(arg), ptr)
-	}
-
 	free := func() {
 		if deallocFn != nil {
-			_, _ = deallocFn.Call(rt.ctx, uint64(ptr), uint64(len(arg)))
+			_, _ = deallocFn.Call(rt.ctx, uint64(ptr), uint64(n))
 		}
 	}
 	return ptr, free, nil
@@ -324,10 +697,16 @@ func (rt *Runtime) readResult() (string, error) {
 
 	resultPtr := uint32(ptrResult[0])
 	resultLen := uint32(lenResult[0])
+	defer rt.freeResult()
 
 	if resultLen == 0 {
 		return "", nil
 	}
+	// Check before reading: copying an enormous result out of WASM memory is
+	// what would exhaust the host.
+	if max := rt.cfg.MaxResultBytes; max > 0 && uint64(resultLen) > uint64(max) {
+		return "", fmt.Errorf("%w: %d bytes, limit %d", ErrResultTooLarge, resultLen, max)
+	}
 
 	mem := rt.module.Memory()
 	bytes, ok := mem.Read(resultPtr, resultLen)
@@ -340,3 +719,20 @@ func (rt *Runtime) readResult() (string, error) {
 	copy(result, bytes)
 	return string(result), nil
 }
+
+// freeResult asks the bridge to release the result buffer once it has been
+// read. Binaries without the free_result export keep the buffer until the
+// next call overwrites it, and so does a failed call, which is why its error
+// is ignored: either way the memory is reused rather than leaked.
+func (rt *Runtime) freeResult() {
+	if free := rt.module.ExportedFunction("free_result"); free != nil {
+		_, _ = free.Call(rt.ctx)
+	}
+}
+
+// MemorySize returns the size in bytes of the module's linear memory.
+func (rt *Runtime) MemorySize() uint32 {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.module.Memory().Size()
+}
@@ -0,0 +1,381 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+func TestCheckExportsReportsEveryMissingExport(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// The smallest valid module: magic number and version, no exports.
+	empty := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	mod, err := r.Instantiate(ctx, empty)
+	if err != nil {
+		t.Fatalf("failed to instantiate empty module: %v", err)
+	}
+
+	err = checkExports(mod)
+	if !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+	var missing *MissingExportsError
+	if !errors.As(err, &missing) || len(missing.Missing) != len(requiredExports) {
+		t.Errorf("expected all %d exports reported missing, got %v", len(requiredExports), err)
+	}
+}
+
+func TestStackOverflowIsClassified(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// (module (func (export "f") call 0)): unbounded recursion.
+	recursive := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: () -> ()
+		0x03, 0x02, 0x01, 0x00, // function section
+		0x07, 0x05, 0x01, 0x01, 'f', 0x00, 0x00, // export "f"
+		0x0a, 0x06, 0x01, 0x04, 0x00, 0x10, 0x00, 0x0b, // code: call 0
+	}
+	mod, err := r.Instantiate(ctx, recursive)
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+
+	_, err = mod.ExportedFunction("f").Call(ctx)
+	if err == nil {
+		t.Fatal("expected unbounded recursion to trap")
+	}
+	if got := classifyCallError("f", err); !errors.Is(got, ErrStackOverflow) {
+		t.Errorf("expected ErrStackOverflow, got %v", got)
+	}
+}
+
+func TestInstantiateReportsMissingImports(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	// Imports env.missing, which no module provides, and
+	// wasi_snapshot_preview1.proc_exit with the wrong type, both as func().
+	binary := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: () -> ()
+		0x02, 0x32, 0x02, // import section, two entries
+		0x03, 'e', 'n', 'v', 0x07, 'm', 'i', 's', 's', 'i', 'n', 'g', 0x00, 0x00,
+		0x16, 'w', 'a', 's', 'i', '_', 's', 'n', 'a', 'p', 's', 'h', 'o', 't', '_',
+		'p', 'r', 'e', 'v', 'i', 'e', 'w', '1',
+		0x09, 'p', 'r', 'o', 'c', '_', 'e', 'x', 'i', 't', 0x00, 0x00,
+	}
+
+	_, err := instantiate(ctx, r, binary)
+	if !errors.Is(err, ErrMissingImport) {
+		t.Fatalf("expected ErrMissingImport, got %v", err)
+	}
+	var missing *MissingImportsError
+	if !errors.As(err, &missing) || len(missing.Imports) != 2 {
+		t.Fatalf("expected 2 unsatisfied imports, got %v", err)
+	}
+	if !strings.Contains(missing.Imports[0], "env.missing: func() (module not available)") {
+		t.Errorf("unexpected report for env.missing: %q", missing.Imports[0])
+	}
+	if !strings.Contains(missing.Imports[1], "proc_exit: func() (host provides func(i32))") {
+		t.Errorf("unexpected report for proc_exit: %q", missing.Imports[1])
+	}
+}
+
+func TestRunWatchedClosesHungCall(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer r.Close(ctx)
+
+	// (module (func (export "f") (loop br 0))): never returns.
+	looping := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: () -> ()
+		0x03, 0x02, 0x01, 0x00, // function section
+		0x07, 0x05, 0x01, 0x01, 'f', 0x00, 0x00, // export "f"
+		0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b, // code: loop br 0 end
+	}
+	mod, err := r.Instantiate(ctx, looping)
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+
+	returned := make(chan struct{})
+	_, err = runWatched(50*time.Millisecond, func() (string, error) {
+		defer close(returned)
+		_, err := mod.ExportedFunction("f").Call(ctx)
+		return "", err
+	}, func() {
+		_ = mod.Close(ctx)
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	// Closing the module interrupts the abandoned call.
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hung call was not interrupted by closing the module")
+	}
+}
+
+// loopingRuntime returns an interruptible Runtime whose module exports a
+// function "f" that never returns.
+func loopingRuntime(t *testing.T) *Runtime {
+	t.Helper()
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	t.Cleanup(func() { r.Close(ctx) })
+
+	// (module (func (export "f") (loop br 0))): never returns.
+	looping := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00, // type section: () -> ()
+		0x03, 0x02, 0x01, 0x00, // function section
+		0x07, 0x05, 0x01, 0x01, 'f', 0x00, 0x00, // export "f"
+		0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b, // code: loop br 0 end
+	}
+	mod, err := r.Instantiate(ctx, looping)
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	// AllocPerArg needs no alloc export for a call without arguments.
+	return &Runtime{runtime: r, module: mod, ctx: ctx, calls: make(map[string]int64),
+		cfg: Config{Interruptible: true, AllocStrategy: AllocPerArg}}
+}
+
+func TestCallAbortsOnContextDone(t *testing.T) {
+	ctx := context.Background()
+	rt := loopingRuntime(t)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	rt.mu.Lock()
+	_, err := rt.callLocked(cancelled, "f", nil)
+	rt.mu.Unlock()
+	if !errors.Is(err, context.Canceled) || rt.Stats().Calls["f"] != 0 {
+		t.Fatalf("expected the call to fail before starting, got %v", err)
+	}
+
+	deadline, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	rt.mu.Lock()
+	_, err = rt.callLocked(deadline, "f", nil)
+	rt.mu.Unlock()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the call to abort with DeadlineExceeded, got %v", err)
+	}
+
+	if _, err := rt.call("f", nil); !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "earlier cancellation") {
+		t.Errorf("expected later calls to fail, got %v", err)
+	}
+}
+
+func TestCallAbortsOnCancelMidCall(t *testing.T) {
+	rt := loopingRuntime(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	returned := make(chan error, 1)
+	go func() {
+		rt.mu.Lock()
+		defer rt.mu.Unlock()
+		_, err := rt.callLocked(ctx, "f", nil)
+		returned <- err
+	}()
+	select {
+	case err := <-returned:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the call to abort with context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("running call was not aborted by cancelling its context")
+	}
+	if rt.Stats().Calls["f"] != 1 {
+		t.Errorf("expected the aborted call to have started, got %+v", rt.Stats())
+	}
+
+	if _, err := rt.call("f", nil); !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "earlier cancellation") {
+		t.Errorf("expected later calls to fail, got %v", err)
+	}
+}
+
+func TestStatsCountsCalls(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// An empty module: every call fails with a missing function, which
+	// still counts.
+	mod, err := r.Instantiate(ctx, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	rt := &Runtime{runtime: r, module: mod, ctx: ctx, calls: make(map[string]int64)}
+
+	if stats := rt.Stats(); len(stats.Calls) != 0 || !stats.LastCall.IsZero() {
+		t.Fatalf("expected no calls, got %+v", stats)
+	}
+	before := time.Now()
+	_, _ = rt.call("evaluate", nil)
+	_, _ = rt.call("evaluate", nil)
+	_, _ = rt.CallHandleBatch("compute_action_space", 1, [][]string{{}, nil, {}})
+
+	stats := rt.Stats()
+	if stats.Calls["evaluate"] != 2 || stats.Calls["compute_action_space"] != 2 {
+		t.Errorf("unexpected call counts: %v", stats.Calls)
+	}
+	if stats.LastCall.Before(before) {
+		t.Errorf("LastCall = %v, want after %v", stats.LastCall, before)
+	}
+
+	// The snapshot is a copy.
+	stats.Calls["evaluate"] = 100
+	if rt.Stats().Calls["evaluate"] != 2 {
+		t.Error("modifying a snapshot changed the runtime's counters")
+	}
+}
+
+func TestCloseHandlesFreesOnce(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+
+	// An empty module: the frees fail with a missing function, which is
+	// ignored but still counted.
+	mod, err := r.Instantiate(ctx, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00})
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	rt := &Runtime{runtime: r, module: mod, ctx: ctx, calls: make(map[string]int64)}
+
+	if err := rt.CloseHandles("free_contract", 1, 2); err != nil {
+		t.Fatalf("first CloseHandles failed: %v", err)
+	}
+	if err := rt.CloseHandles("free_contract", 1, 2); err != nil {
+		t.Errorf("second CloseHandles returned error: %v", err)
+	}
+	if err := rt.Close(); err != nil {
+		t.Errorf("Close after CloseHandles returned error: %v", err)
+	}
+	if n := rt.Stats().Calls["free_contract"]; n != 2 {
+		t.Errorf("expected each handle to be freed once, got %d calls", n)
+	}
+}
+
+func TestReadResultEnforcesMaxResultBytes(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// A module whose result buffer is always the first 100 bytes of memory:
+	//   (memory (export "memory") 1)
+	//   (func (export "get_result_ptr") (result i32) i32.const 0)
+	//   (func (export "get_result_len") (result i32) i32.const 100)
+	binary := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f, // type section: () -> i32
+		0x03, 0x03, 0x02, 0x00, 0x00, // function section
+		0x05, 0x03, 0x01, 0x00, 0x01, // memory section: 1 page
+		0x07, 0x2c, 0x03, // export section
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x0e, 'g', 'e', 't', '_', 'r', 'e', 's', 'u', 'l', 't', '_', 'p', 't', 'r', 0x00, 0x00,
+		0x0e, 'g', 'e', 't', '_', 'r', 'e', 's', 'u', 'l', 't', '_', 'l', 'e', 'n', 0x00, 0x01,
+		0x0a, 0x0c, 0x02, // code section
+		0x04, 0x00, 0x41, 0x00, 0x0b, // i32.const 0
+		0x05, 0x00, 0x41, 0xe4, 0x00, 0x0b, // i32.const 100
+	}
+	mod, err := r.Instantiate(ctx, binary)
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+
+	for _, tc := range []struct {
+		max     int
+		wantErr bool
+	}{{0, false}, {100, false}, {99, true}} {
+		rt := &Runtime{module: mod, ctx: ctx, cfg: Config{MaxResultBytes: tc.max}}
+		result, err := rt.readResult()
+		if tc.wantErr {
+			if !errors.Is(err, ErrResultTooLarge) {
+				t.Errorf("max %d: expected ErrResultTooLarge, got %v", tc.max, err)
+			}
+			continue
+		}
+		if err != nil || len(result) != 100 {
+			t.Errorf("max %d: expected a 100-byte result, got %d bytes, %v", tc.max, len(result), err)
+		}
+	}
+}
+
+func TestReadResultFreesResult(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	// A module whose 4-byte result buffer starts at offset 0, and whose
+	// free_result marks byte 0 with 'X':
+	//   (memory (export "memory") 1)
+	//   (func (export "get_result_ptr") (result i32) i32.const 0)
+	//   (func (export "get_result_len") (result i32) i32.const 4)
+	//   (func (export "free_result") i32.const 0 i32.const 88 i32.store8)
+	binary := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x08, 0x02, 0x60, 0x00, 0x01, 0x7f, 0x60, 0x00, 0x00, // type section: () -> i32, () -> ()
+		0x03, 0x04, 0x03, 0x00, 0x00, 0x01, // function section
+		0x05, 0x03, 0x01, 0x00, 0x01, // memory section: 1 page
+		0x07, 0x3a, 0x04, // export section
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x0e, 'g', 'e', 't', '_', 'r', 'e', 's', 'u', 'l', 't', '_', 'p', 't', 'r', 0x00, 0x00,
+		0x0e, 'g', 'e', 't', '_', 'r', 'e', 's', 'u', 'l', 't', '_', 'l', 'e', 'n', 0x00, 0x01,
+		0x0b, 'f', 'r', 'e', 'e', '_', 'r', 'e', 's', 'u', 'l', 't', 0x00, 0x02,
+		0x0a, 0x16, 0x03, // code section
+		0x04, 0x00, 0x41, 0x00, 0x0b, // i32.const 0
+		0x04, 0x00, 0x41, 0x04, 0x0b, // i32.const 4
+		0x0a, 0x00, 0x41, 0x00, 0x41, 0xd8, 0x00, 0x3a, 0x00, 0x00, 0x0b, // i32.store8 [0] = 88
+	}
+	mod, err := r.Instantiate(ctx, binary)
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+
+	rt := &Runtime{module: mod, ctx: ctx}
+	result, err := rt.readResult()
+	if err != nil || result != "\x00\x00\x00\x00" {
+		t.Fatalf("expected the result to be copied before it was freed, got %q, %v", result, err)
+	}
+	if b, _ := mod.Memory().ReadByte(0); b != 'X' {
+		t.Errorf("expected free_result to have been called, memory[0] = %d", b)
+	}
+}
+
+func TestInstantiateReportsDisabledFeatures(t *testing.T) {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV1))
+	defer r.Close(ctx)
+
+	// (func (result i32) i32.const 1 i32.extend8_s): sign-extension ops are
+	// a WebAssembly 2.0 feature.
+	binary := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f, // type section: () -> i32
+		0x03, 0x02, 0x01, 0x00, // function section
+		0x0a, 0x07, 0x01, 0x05, 0x00, 0x41, 0x01, 0xc0, 0x0b, // code section
+	}
+	if _, err := instantiate(ctx, r, binary); !errors.Is(err, ErrUnsupportedFeatures) {
+		t.Fatalf("expected ErrUnsupportedFeatures, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package tenor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Invariant is a property every verdict set of a contract should have, such
+// as two verdicts never being present together. Check returns nil when vs
+// has the property, and otherwise an error describing the violation.
+type Invariant struct {
+	Name  string
+	Check func(vs *VerdictSet) error
+}
+
+// InvariantViolation is an invariant a verdict set failed.
+type InvariantViolation struct {
+	Name string
+	Err  error
+}
+
+// MutuallyExclusive returns an invariant that at most one of verdictTypes is
+// present.
+func MutuallyExclusive(verdictTypes ...string) Invariant {
+	return Invariant{
+		Name: "mutually exclusive: " + strings.Join(verdictTypes, ", "),
+		Check: func(vs *VerdictSet) error {
+			present := verdictsByType(vs)
+			var both []string
+			for _, t := range verdictTypes {
+				if _, ok := present[t]; ok {
+					both = append(both, t)
+				}
+			}
+			if len(both) > 1 {
+				return fmt.Errorf("verdicts %s are all present", strings.Join(both, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// CheckInvariants evaluates facts once and checks the verdict set against
+// each invariant, returning the violations in the order of invariants. It
+// returns an error only if evaluation fails.
+func (e *Evaluator) CheckInvariants(facts FactSet, invariants []Invariant) ([]InvariantViolation, error) {
+	vs, err := e.Evaluate(facts)
+	if err != nil {
+		return nil, err
+	}
+	var violations []InvariantViolation
+	for _, inv := range invariants {
+		if err := inv.Check(vs); err != nil {
+			violations = append(violations, InvariantViolation{Name: inv.Name, Err: err})
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,111 @@
+package tenor
+
+import (
+	"context"
+	"fmt"
+)
+
+// FlowInfo describes one flow of the loaded contract.
+type FlowInfo struct {
+	ID       string `json:"id"`
+	Entry    string `json:"entry"`
+	Snapshot string `json:"snapshot"`
+	// AllowedPersonas are the personas allowed to perform the entry step's
+	// operation. It is empty if the flow is entered by a step of another
+	// kind.
+	AllowedPersonas []string `json:"allowed_personas"`
+}
+
+// ListFlows returns the flows declared in the loaded contract, in
+// declaration order, as listed by the bridge's list_flows export.
+func (e *Evaluator) ListFlows() ([]FlowInfo, error) {
+	var result struct {
+		Flows []FlowInfo `json:"flows"`
+	}
+	if err := e.list("list_flows", "flow list", &result); err != nil {
+		return nil, err
+	}
+	return result.Flows, nil
+}
+
+// list calls the bridge listing export funcName on the contract handle and
+// decodes its result into v, naming target in a *DecodeError.
+func (e *Evaluator) list(funcName, target string, v interface{}) error {
+	result, err := e.runtime.CallHandleContext(context.Background(), funcName, e.handle)
+	if err != nil {
+		return fmt.Errorf("%s WASM call failed: %w", funcName, err)
+	}
+	if errMsg := extractError(result); errMsg != "" {
+		return fmt.Errorf("%s error: %s", funcName, errMsg)
+	}
+	return decodeResult(funcName, target, result, v, e.opts.strictDecoding)
+}
+
+// ListPersonas returns the sorted, de-duplicated personas that some
+// operation of the loaded contract allows, as listed by the bridge's
+// list_personas export. It reflects what the contract declares, not who is
+// authorized at runtime: whether a persona can act still depends on facts,
+// entity states and the flow being run, which ComputeActionSpace reports.
+// The result is never nil.
+func (e *Evaluator) ListPersonas() ([]string, error) {
+	var result struct {
+		Personas []string `json:"personas"`
+	}
+	if err := e.list("list_personas", "persona list", &result); err != nil {
+		return nil, err
+	}
+	if result.Personas == nil {
+		result.Personas = []string{}
+	}
+	return result.Personas, nil
+}
+
+// FactInfo describes one fact of the loaded contract, for generating input
+// forms.
+type FactInfo struct {
+	ID string `json:"id"`
+	// Type is the declared base type, such as "Bool", "Text" or "Money".
+	Type string `json:"type"`
+	// Source is where the fact's value comes from; nil if none is declared.
+	Source *FactSource `json:"source,omitempty"`
+	// Required reports that some rule references the fact.
+	Required bool `json:"required"`
+}
+
+// FactSource is a fact's binding: a legacy System and Field, or the
+// SourceID of a declared Source and a Path within it. A legacy binding
+// without a "system." prefix has only a Field.
+type FactSource struct {
+	System   string `json:"system,omitempty"`
+	Field    string `json:"field,omitempty"`
+	SourceID string `json:"source_id,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// ListFacts returns the facts declared in the loaded contract, in
+// declaration order, as listed by the bridge's list_facts export.
+func (e *Evaluator) ListFacts() ([]FactInfo, error) {
+	var result struct {
+		Facts []FactInfo `json:"facts"`
+	}
+	if err := e.list("list_facts", "fact list", &result); err != nil {
+		return nil, err
+	}
+	return result.Facts, nil
+}
+
+// ListEntities returns the entities declared in the loaded contract, in
+// declaration order, with their states and transitions, as listed by the
+// bridge's list_entities export. Unlike the EntitySummary of an action
+// space, it needs no facts or entity states, so it suits rendering
+// state-machine diagrams up front. Provenance is not listed and is left
+// zero.
+func (e *Evaluator) ListEntities() ([]EntityDef, error) {
+	var result struct {
+		Entities []EntityDef `json:"entities"`
+	}
+	if err := e.list("list_entities", "entity list", &result); err != nil {
+		return nil, err
+	}
+	return result.Entities, nil
+}
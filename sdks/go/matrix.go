@@ -0,0 +1,128 @@
+package tenor
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Matrix is a capability matrix: for each flow and persona, whether the
+// persona can start the flow under given facts and entity states.
+type Matrix struct {
+	// Flows are the flow IDs in any persona's action space, sorted.
+	Flows []string
+	// Personas are the personas the matrix was computed for, in the order
+	// requested.
+	Personas []string
+	cells    map[string]map[string]MatrixCell
+}
+
+// MatrixCell is one flow/persona entry of a Matrix. When the flow is neither
+// available nor blocked for the persona, as when WithFlowAllowList hides it,
+// both fields are zero.
+type MatrixCell struct {
+	Available bool
+	// Blocked is why the flow is blocked, if it is. For a multi-instance
+	// contract the flow counts as available if any instance binding is, and
+	// otherwise carries the first blocked binding's reason.
+	Blocked *BlockedReason
+}
+
+// String renders c as "available", "blocked: <reason>" or "-".
+func (c MatrixCell) String() string {
+	switch {
+	case c.Available:
+		return "available"
+	case c.Blocked != nil:
+		return "blocked: " + c.Blocked.String()
+	}
+	return "-"
+}
+
+// CapabilityMatrix computes each persona's action space once and assembles
+// the results into a Matrix, for access reviews that need every persona's
+// capabilities in one table.
+func (e *Evaluator) CapabilityMatrix(facts FactSet, entityStates EntityStateMap, personas []string) (*Matrix, error) {
+	m := &Matrix{
+		Personas: append([]string(nil), personas...),
+		cells:    make(map[string]map[string]MatrixCell),
+	}
+	cell := func(flowID, persona string) MatrixCell {
+		if m.cells[flowID] == nil {
+			m.cells[flowID] = make(map[string]MatrixCell)
+			m.Flows = append(m.Flows, flowID)
+		}
+		return m.cells[flowID][persona]
+	}
+	for _, persona := range personas {
+		space, err := e.ComputeActionSpace(facts, entityStates, persona)
+		if err != nil {
+			return nil, fmt.Errorf("persona %q: %w", persona, err)
+		}
+		for _, a := range space.Actions {
+			c := cell(a.FlowID, persona)
+			c.Available, c.Blocked = true, nil
+			m.cells[a.FlowID][persona] = c
+		}
+		for _, b := range space.BlockedActions {
+			c := cell(b.FlowID, persona)
+			if !c.Available && c.Blocked == nil {
+				reason := b.Reason
+				c.Blocked = &reason
+			}
+			m.cells[b.FlowID][persona] = c
+		}
+	}
+	sort.Strings(m.Flows)
+	return m, nil
+}
+
+// Cell returns the entry for flowID and persona.
+func (m *Matrix) Cell(flowID, persona string) MatrixCell {
+	return m.cells[flowID][persona]
+}
+
+// CSV writes m as CSV: a header row of "flow" and the personas, then one row
+// per flow with each cell rendered by MatrixCell.String.
+func (m *Matrix) CSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"flow"}, m.Personas...)); err != nil {
+		return err
+	}
+	for _, row := range m.rows() {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Table renders m as aligned plain text, with the same rows and columns as
+// CSV.
+func (m *Matrix) Table() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FLOW\t"+strings.Join(m.Personas, "\t"))
+	for _, row := range m.rows() {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// rows returns one row per flow: the flow ID, then one cell per persona.
+func (m *Matrix) rows() [][]string {
+	rows := make([][]string, 0, len(m.Flows))
+	for _, flowID := range m.Flows {
+		row := []string{flowID}
+		for _, persona := range m.Personas {
+			row = append(row, m.Cell(flowID, persona).String())
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
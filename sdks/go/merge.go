@@ -0,0 +1,74 @@
+package tenor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MergeBundles combines the constructs of several interchange bundles into
+// one, for composing a base contract with overlay contracts at load time. The
+// merged bundle takes its top-level fields, such as its id, from the first
+// bundle; constructs keep their order, bundle by bundle.
+//
+// Two constructs of the same kind and ID are an error, never resolved by
+// letting one win: every collision is reported as a
+// *DuplicateConstructError, matching ErrDuplicateConstruct. The bundles must
+// share a tenor_version, and the merged bundle must be referentially intact
+// (see Bundle.ValidateReferences), so an overlay may reference facts and
+// verdicts of the base but not constructs no bundle declares.
+func MergeBundles(bundles ...[]byte) ([]byte, error) {
+	if len(bundles) == 0 {
+		return nil, errors.New("no bundles to merge")
+	}
+
+	var merged *Bundle
+	declared := make(map[[2]string]int)
+	var dups []error
+	for i, data := range bundles {
+		b, err := ParseBundle(data)
+		if err != nil {
+			return nil, fmt.Errorf("bundle %d: %w", i, err)
+		}
+		if merged == nil {
+			merged = &Bundle{ID: b.ID, TenorVersion: b.TenorVersion, header: b.header}
+		} else if b.TenorVersion != merged.TenorVersion {
+			return nil, fmt.Errorf("bundle %d: tenor_version %q differs from %q", i, b.TenorVersion, merged.TenorVersion)
+		}
+		for _, c := range b.constructs {
+			key := [2]string{c.Kind, c.ID}
+			if first, ok := declared[key]; ok {
+				dups = append(dups, &DuplicateConstructError{Kind: c.Kind, ID: c.ID, First: first, Second: i})
+				continue
+			}
+			declared[key] = i
+			merged.constructs = append(merged.constructs, c)
+		}
+	}
+	if len(dups) > 0 {
+		return nil, errors.Join(dups...)
+	}
+
+	data, err := merged.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	// Re-parse to check the merged contract as a whole.
+	check, err := ParseBundle(data)
+	if err != nil {
+		return nil, fmt.Errorf("merged bundle: %w", err)
+	}
+	if errs := check.ValidateReferences(); len(errs) > 0 {
+		return nil, fmt.Errorf("merged bundle: %w", errors.Join(errs...))
+	}
+	return data, nil
+}
+
+// NewEvaluatorFromBundles merges bundles with MergeBundles and loads the
+// result with NewEvaluatorFromBundle.
+func NewEvaluatorFromBundles(bundles [][]byte, opts ...Option) (*Evaluator, error) {
+	merged, err := MergeBundles(bundles...)
+	if err != nil {
+		return nil, err
+	}
+	return NewEvaluatorFromBundle(merged, opts...)
+}
@@ -0,0 +1,243 @@
+package tenor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MermaidEntity renders an entity's state machine as a Mermaid
+// stateDiagram-v2, for embedding in documentation. The initial state is
+// entered from [*], each transition is an edge labelled with the operations
+// whose effects perform it, and states with no outgoing transition lead to
+// [*]. It fails if entityID is not declared.
+func (b *Bundle) MermaidEntity(entityID string) (string, error) {
+	ent, ok := b.Entity(entityID)
+	if !ok {
+		return "", fmt.Errorf("entity %q is not declared in the contract", entityID)
+	}
+
+	ids := make(map[string]string, len(ent.States))
+	var w strings.Builder
+	w.WriteString("stateDiagram-v2\n")
+	for i, state := range ent.States {
+		if plainMermaidID.MatchString(state) {
+			ids[state] = state
+			continue
+		}
+		ids[state] = fmt.Sprintf("state%d", i)
+		fmt.Fprintf(&w, "    state \"%s\" as %s\n", mermaidEscape(state), ids[state])
+	}
+	id := func(state string) string {
+		if s, ok := ids[state]; ok {
+			return s
+		}
+		return state
+	}
+
+	if ent.Initial != "" {
+		fmt.Fprintf(&w, "    [*] --> %s\n", id(ent.Initial))
+	}
+	exits := make(map[string]bool)
+	for _, t := range ent.Transitions {
+		exits[t.From] = true
+		var ops []string
+		for _, op := range b.Operations {
+			for _, eff := range op.Effects {
+				if eff.EntityID == entityID && eff.From == t.From && eff.To == t.To && !contains(ops, op.ID) {
+					ops = append(ops, op.ID)
+				}
+			}
+		}
+		fmt.Fprintf(&w, "    %s --> %s", id(t.From), id(t.To))
+		if len(ops) > 0 {
+			sort.Strings(ops)
+			fmt.Fprintf(&w, ": %s", mermaidEscape(strings.Join(ops, ", ")))
+		}
+		w.WriteString("\n")
+	}
+	for _, state := range ent.States {
+		if !exits[state] {
+			fmt.Fprintf(&w, "    %s --> [*]\n", id(state))
+		}
+	}
+	return w.String(), nil
+}
+
+// MermaidFlow renders a flow's step graph as a Mermaid flowchart, for
+// embedding in documentation. Steps are nodes shaped by kind (operations as
+// boxes, branches as diamonds, sub-flows as subroutines), terminal outcomes
+// are rounded nodes, and edges carry the outcome, branch or join condition
+// that takes them. Failure handling is drawn with dotted edges. The steps of
+// a parallel branch are grouped in a subgraph. It fails if flowID is not
+// declared.
+func (b *Bundle) MermaidFlow(flowID string) (string, error) {
+	flow, ok := b.Flow(flowID)
+	if !ok {
+		return "", fmt.Errorf("flow %q is not declared in the contract", flowID)
+	}
+	m := &mermaidFlow{steps: make(map[string]string), outcomes: make(map[string]string)}
+	m.w.WriteString("flowchart TD\n")
+	m.w.WriteString("    start((start))\n")
+	m.nodes(flow.Steps, "    ")
+	if flow.Entry != "" {
+		m.edge("start", m.step(flow.Entry), "", false)
+	}
+	m.edges(flow.Steps)
+	return m.w.String(), nil
+}
+
+// mermaidFlow accumulates a flowchart, assigning node IDs to steps and
+// outcomes, which may contain characters Mermaid IDs cannot.
+type mermaidFlow struct {
+	w        strings.Builder
+	steps    map[string]string
+	outcomes map[string]string
+}
+
+func (m *mermaidFlow) step(stepID string) string {
+	if id, ok := m.steps[stepID]; ok {
+		return id
+	}
+	id := fmt.Sprintf("step%d", len(m.steps))
+	m.steps[stepID] = id
+	return id
+}
+
+// target returns the node for t, declaring outcome nodes on first use.
+func (m *mermaidFlow) target(t StepTarget) string {
+	if !t.IsTerminal() {
+		return m.step(t.StepID)
+	}
+	if id, ok := m.outcomes[t.Outcome]; ok {
+		return id
+	}
+	id := fmt.Sprintf("outcome%d", len(m.outcomes))
+	m.outcomes[t.Outcome] = id
+	fmt.Fprintf(&m.w, "    %s([\"%s\"])\n", id, mermaidEscape(t.Outcome))
+	return id
+}
+
+func (m *mermaidFlow) nodes(steps []FlowStep, indent string) {
+	for _, s := range steps {
+		id := m.step(s.ID)
+		switch s.Kind {
+		case "OperationStep":
+			fmt.Fprintf(&m.w, "%s%s[\"%s\"]\n", indent, id, mermaidEscape(fmt.Sprintf("%s: %s as %s", s.ID, s.Op, s.Persona)))
+		case "BranchStep":
+			fmt.Fprintf(&m.w, "%s%s{\"%s\"}\n", indent, id, mermaidEscape(s.ID))
+		case "SubFlowStep":
+			fmt.Fprintf(&m.w, "%s%s[[\"%s\"]]\n", indent, id, mermaidEscape(fmt.Sprintf("%s: %s", s.ID, s.Flow)))
+		case "ParallelStep":
+			fmt.Fprintf(&m.w, "%s%s[/\"%s\"/]\n", indent, id, mermaidEscape(s.ID))
+			for i, br := range s.Branches {
+				fmt.Fprintf(&m.w, "%ssubgraph %s_branch%d [\"%s\"]\n", indent, id, i, mermaidEscape(br.ID))
+				m.nodes(br.Steps, indent+"    ")
+				fmt.Fprintf(&m.w, "%send\n", indent)
+			}
+		default:
+			fmt.Fprintf(&m.w, "%s%s[\"%s\"]\n", indent, id, mermaidEscape(s.ID))
+		}
+	}
+}
+
+func (m *mermaidFlow) edge(from, to, label string, failure bool) {
+	arrow := "-->"
+	if failure {
+		arrow = "-.->"
+	}
+	if label == "" {
+		fmt.Fprintf(&m.w, "    %s %s %s\n", from, arrow, to)
+		return
+	}
+	fmt.Fprintf(&m.w, "    %s %s|\"%s\"| %s\n", from, arrow, mermaidEscape(label), to)
+}
+
+func (m *mermaidFlow) edges(steps []FlowStep) {
+	for _, s := range steps {
+		from := m.step(s.ID)
+		switch s.Kind {
+		case "OperationStep":
+			outcomes := make([]string, 0, len(s.Outcomes))
+			for o := range s.Outcomes {
+				outcomes = append(outcomes, o)
+			}
+			sort.Strings(outcomes)
+			for _, o := range outcomes {
+				m.edge(from, m.target(s.Outcomes[o]), o, false)
+			}
+		case "BranchStep":
+			if s.IfTrue != nil {
+				m.edge(from, m.target(*s.IfTrue), "true", false)
+			}
+			if s.IfFalse != nil {
+				m.edge(from, m.target(*s.IfFalse), "false", false)
+			}
+		case "SubFlowStep":
+			if s.OnSuccess != nil {
+				m.edge(from, m.target(*s.OnSuccess), "success", false)
+			}
+		case "HandoffStep":
+			if s.Next != "" {
+				m.edge(from, m.step(s.Next), "handoff to "+s.ToPersona, false)
+			}
+		case "ParallelStep":
+			for _, br := range s.Branches {
+				if br.Entry != "" {
+					m.edge(from, m.step(br.Entry), br.ID, false)
+				}
+				m.edges(br.Steps)
+			}
+			if j := s.Join; j != nil {
+				if j.OnAllSuccess != nil {
+					m.edge(from, m.target(*j.OnAllSuccess), "all success", false)
+				}
+				if j.OnAllComplete != nil {
+					m.edge(from, m.target(*j.OnAllComplete), "all complete", false)
+				}
+				m.failure(from, "any failure", j.OnAnyFailure)
+			}
+		}
+		m.failure(from, "failure", s.OnFailure)
+	}
+}
+
+// failure draws the dotted edge of a failure handler.
+func (m *mermaidFlow) failure(from, label string, h *FailureHandler) {
+	if h == nil {
+		return
+	}
+	switch h.Kind {
+	case "Terminate":
+		m.edge(from, m.target(StepTarget{Outcome: h.Outcome}), label, true)
+	case "Compensate":
+		if h.Then == nil {
+			return
+		}
+		ops := make([]string, len(h.Steps))
+		for i, c := range h.Steps {
+			ops[i] = c.Op
+		}
+		m.edge(from, m.target(*h.Then), label+": compensate "+strings.Join(ops, ", "), true)
+	case "Escalate":
+		if h.Next != "" {
+			m.edge(from, m.step(h.Next), label+": escalate to "+h.ToPersona, true)
+		}
+	}
+}
+
+// plainMermaidID matches names usable as Mermaid IDs without an alias.
+var plainMermaidID = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// mermaidEscape makes s safe inside a quoted Mermaid label, using Mermaid's
+// entity codes for the characters that would end or break it.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(
+		"#", "#35;",
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"\n", " ",
+	).Replace(s)
+}
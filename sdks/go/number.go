@@ -0,0 +1,131 @@
+package tenor
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MarshalJSON encodes fs like a plain map, except that numbers are always
+// written in plain notation, never in scientific notation.
+//
+// encoding/json writes float64 values of 1e21 and above, and below 1e-6, with
+// an exponent (1e+21), and json.Number values as given. The Rust evaluator
+// reads any number with an exponent or a fraction as a float, and Int and
+// Duration facts accept only integers, so "1e3" is rejected where "1000" is
+// not. The representation chosen matches what the evaluator reads and writes:
+//
+//   - integral values are written as plain integers: 1e21 becomes
+//     1000000000000000000000, 5e3 becomes 5000;
+//   - fractional values are written in the shortest fixed-point notation that
+//     round-trips: 1e-7 becomes 0.0000001;
+//   - json.Number values with an exponent are rewritten exactly, without a
+//     round trip through float64, so json.Number("9.007199254740993e15")
+//     becomes 9007199254740993.
+//
+// Numbers held directly in fs or nested in map[string]interface{} and
+// []interface{} values are rewritten; other types are encoded as
+// encoding/json encodes them. Decimal and Money amounts are strings and are
+// unaffected.
+func (fs FactSet) MarshalJSON() ([]byte, error) {
+	if fs == nil {
+		return []byte("null"), nil
+	}
+	plain := make(map[string]interface{}, len(fs))
+	for id, v := range fs {
+		plain[id] = plainNumbers(v)
+	}
+	return json.Marshal(plain)
+}
+
+// plainNumbers returns v with its numbers rewritten as plain-notation
+// json.Numbers. Maps and slices are copied rather than modified.
+func plainNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		return plainFloat(v, 64)
+	case float32:
+		return plainFloat(float64(v), 32)
+	case json.Number:
+		if s, ok := plainDecimal(string(v)); ok {
+			return json.Number(s)
+		}
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			copied[k] = plainNumbers(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = plainNumbers(item)
+		}
+		return copied
+	}
+	return v
+}
+
+// plainFloat formats f in fixed-point notation. NaN and infinities are left
+// as floats for encoding/json to reject.
+func plainFloat(f float64, bitSize int) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	return json.Number(strconv.FormatFloat(f, 'f', -1, bitSize))
+}
+
+// maxPlainExponent bounds the exponents plainDecimal expands, so that a
+// hostile json.Number such as "1e999999999" is passed through rather than
+// expanded into a huge string. It is above float64's range.
+const maxPlainExponent = 400
+
+// plainDecimal rewrites a JSON number with an exponent in plain notation,
+// exactly. It reports false for numbers without an exponent, which need no
+// rewriting, and for malformed ones.
+func plainDecimal(s string) (string, bool) {
+	i := strings.IndexAny(s, "eE")
+	if i < 0 {
+		return "", false
+	}
+	exp, err := strconv.Atoi(strings.TrimPrefix(s[i+1:], "+"))
+	if err != nil || exp > maxPlainExponent || exp < -maxPlainExponent {
+		return "", false
+	}
+	mantissa, sign := s[:i], ""
+	if strings.HasPrefix(mantissa, "-") {
+		mantissa, sign = mantissa[1:], "-"
+	}
+	intPart, fracPart := mantissa, ""
+	if dot := strings.IndexByte(mantissa, '.'); dot >= 0 {
+		intPart, fracPart = mantissa[:dot], mantissa[dot+1:]
+	}
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", false
+	}
+
+	// point is the position of the decimal point within digits.
+	point := len(intPart) + exp
+	switch {
+	case point <= 0:
+		intPart, fracPart = "0", strings.Repeat("0", -point)+digits
+	case point >= len(digits):
+		intPart, fracPart = digits+strings.Repeat("0", point-len(digits)), ""
+	default:
+		intPart, fracPart = digits[:point], digits[point:]
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	fracPart = strings.TrimRight(fracPart, "0")
+	if intPart == "0" && fracPart == "" {
+		sign = ""
+	}
+	if fracPart == "" {
+		return sign + intPart, true
+	}
+	return sign + intPart + "." + fracPart, true
+}
@@ -0,0 +1,290 @@
+package tenor
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+
+	"github.com/riverline-labs/tenor-go/internal/wasm"
+)
+
+// Option configures an Evaluator. Options are passed to NewEvaluatorFromBundle.
+type Option func(*options)
+
+// options holds the configuration assembled from Option values.
+type options struct {
+	stepStates          bool
+	rejectUnknownFacts  bool
+	rejectDuplicateKeys bool
+	allocStrategy       AllocStrategy
+	flowAllowList       map[string]bool
+	inferInitialStates  bool
+	strictDecoding      bool
+	coercionTrace       func([]FactCoercion)
+	maxFlowSteps        int
+	labels              map[string]string
+	watchdog            time.Duration
+	verdictOverlay      *verdictOverlay
+	cache               *CompilationCache
+	maxResultBytes      int
+	wazeroConfig        wazero.RuntimeConfig
+	requireNested       bool
+	logger              *slog.Logger
+	sourceLocations     bool
+	verdictStore        VerdictStore
+	factMarshaler       func(id string, v interface{}) (json.RawMessage, error)
+	ruleProfiling       bool
+}
+
+// WithStepStates makes ExecuteFlow and ExecuteFlowWithBindings populate
+// StepResult.StateAfter with the full entity-state map as it stands once each
+// step's transitions have been applied.
+//
+// Flows use the at_initiation snapshot policy: facts and verdicts are frozen
+// when the flow starts, while entity state evolves step by step. StateAfter
+// therefore reflects only the accumulated transitions; it never implies that
+// verdicts were re-evaluated between steps.
+func WithStepStates() Option {
+	return func(o *options) {
+		o.stepStates = true
+	}
+}
+
+// WithRejectUnknownFacts makes every call that takes a FactSet fail with an
+// *UnknownFactsError when the set contains facts the contract does not
+// declare. By default extra facts are ignored so that callers can send facts
+// ahead of the contracts that use them.
+func WithRejectUnknownFacts() Option {
+	return func(o *options) {
+		o.rejectUnknownFacts = true
+	}
+}
+
+// WithRejectDuplicateKeys makes EvaluateJSON fail with a
+// *DuplicateFactKeyError when its input holds a key twice, rather than
+// evaluating with the last value as encoding/json would. It costs an extra
+// pass over the input.
+func WithRejectDuplicateKeys() Option {
+	return func(o *options) {
+		o.rejectDuplicateKeys = true
+	}
+}
+
+// WithInferInitialStates treats every entity declared in the contract but
+// absent from a call's entity states as being in its declared initial state,
+// under the default instance. Without it, ComputeActionSpace reports actions
+// on unlisted entities as blocked with an unknown current state.
+func WithInferInitialStates() Option {
+	return func(o *options) {
+		o.inferInitialStates = true
+	}
+}
+
+// WithRequireNestedStates makes the single-instance methods
+// (ComputeActionSpace, ExecuteFlow and the methods built on them) fail with
+// ErrMultiInstance once Evaluator.IsMultiInstance reports that the nested
+// methods have been passed several instances of one entity, steering callers
+// to ComputeActionSpaceNested and ExecuteFlowWithBindings. Use it in services
+// that run entities with several instances, where the flat methods would
+// silently address only the default instance.
+func WithRequireNestedStates() Option {
+	return func(o *options) {
+		o.requireNested = true
+	}
+}
+
+// WithLogger sets the logger the SDK reports to. It logs only events the
+// caller cannot see in returned errors, such as the retries of
+// NewEvaluatorFromBundleWithRetry and EvaluatePipe stopping on cancellation.
+// Records logged under a context carrying a correlation ID include it (see
+// ContextWithCorrelationID). Without it the SDK logs nothing.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithSourceLocations makes the evaluator fill VerdictProvenance.File and
+// Line with the source location of each verdict's producing rule, so results
+// link back to the contract source. Without it they are left empty; use
+// Verdict.SourceLocation to look one up on demand.
+func WithSourceLocations() Option {
+	return func(o *options) {
+		o.sourceLocations = true
+	}
+}
+
+// WithStrictDecoding makes the evaluator reject bridge results that contain
+// fields the SDK's VerdictSet, ActionSpace and FlowResult types do not model,
+// failing with a *DecodeError.
+//
+// It is meant as a tripwire in tests, to catch the Rust bridge and the Go
+// types drifting apart. Leave it off in production: by default unknown fields
+// are ignored so that an SDK keeps working against a newer bridge that adds
+// fields.
+func WithStrictDecoding() Option {
+	return func(o *options) {
+		o.strictDecoding = true
+	}
+}
+
+// DefaultMaxFlowSteps is the step limit the WASM bridge enforces on every
+// flow simulation.
+const DefaultMaxFlowSteps = 1000
+
+// WithMaxFlowSteps makes ExecuteFlow and ExecuteFlowWithBindings fail with a
+// *FlowTooLongError (matching ErrFlowTooLong) when a simulation visits more
+// than n steps, guarding against runaway simulations of misauthored flows.
+//
+// The bridge always stops a flow after DefaultMaxFlowSteps steps, so n only
+// tightens that limit; values of zero or less, or above the default, leave
+// the bridge limit alone. A limit of n is checked in Go against the returned
+// path, which the error carries for debugging. When the bridge limit trips no
+// path is returned, so the error's Path is empty.
+func WithMaxFlowSteps(n int) Option {
+	return func(o *options) {
+		o.maxFlowSteps = n
+	}
+}
+
+// WithLabel attaches a constant label, such as contract=escrow or
+// tenant=acme, to the Evaluator; Evaluator.Labels returns them, and every
+// record logged to the WithLogger logger carries them as attributes. It may
+// be given several times, and a later value for the same key wins.
+//
+// The SDK emits no metrics or spans itself. Labels exist so that the
+// instrumentation wrapping an Evaluator can tag its observations without a
+// side table. Every distinct label set becomes its own series in most
+// metrics backends, so keeping cardinality bounded (no request IDs or user
+// IDs) is the caller's responsibility.
+func WithLabel(key, value string) Option {
+	return func(o *options) {
+		if o.labels == nil {
+			o.labels = make(map[string]string)
+		}
+		o.labels[key] = value
+	}
+}
+
+// WithFlowAllowList restricts the evaluator to the given flows.
+// ExecuteFlow, ExecuteFlowWithBindings and ExplainBlocked reject other flows
+// with ErrFlowNotAllowed, and action spaces omit them from both the available
+// and the blocked actions.
+//
+// The filter is applied in Go after the WASM bridge returns. It is an
+// authorization convenience for callers sharing one contract, not a security
+// boundary: the bridge itself still evaluates the whole contract.
+func WithFlowAllowList(flowIDs ...string) Option {
+	return func(o *options) {
+		o.flowAllowList = make(map[string]bool, len(flowIDs))
+		for _, id := range flowIDs {
+			o.flowAllowList[id] = true
+		}
+	}
+}
+
+// flowAllowed reports whether flowID passes the allow-list, if one is set.
+func (o options) flowAllowed(flowID string) bool {
+	return o.flowAllowList == nil || o.flowAllowList[flowID]
+}
+
+// AllocStrategy selects how call arguments are copied into WASM memory.
+type AllocStrategy int
+
+const (
+	// AllocArena copies all arguments of a call into a single WASM allocation,
+	// so each call costs one alloc and one dealloc regardless of how many
+	// arguments it takes. This is the default.
+	AllocArena AllocStrategy = iota
+	// AllocPerArg allocates and frees each argument separately. It is kept as
+	// a fallback for WASM binaries whose allocator cannot serve one large
+	// block efficiently.
+	AllocPerArg
+)
+
+// WithAllocStrategy selects how call arguments are copied into WASM memory.
+func WithAllocStrategy(s AllocStrategy) Option {
+	return func(o *options) {
+		o.allocStrategy = s
+	}
+}
+
+// WithWatchdog bounds every WASM call to d, as a last-resort guard when
+// evaluating untrusted contracts. Each call runs on its own goroutine; one
+// still running after d is abandoned, the WASM runtime is closed out from
+// under it, and the call fails with ErrEvalTimeout.
+//
+// A timeout poisons the Evaluator: every later call fails with
+// ErrEvalTimeout too, and a new Evaluator must be created. Closing the
+// runtime interrupts a call executing WASM code, after which its goroutine
+// exits; until then, or for good if the call is stuck somewhere closing does
+// not reach, the goroutine leaks. Enabling the watchdog also makes every call
+// slightly slower, since each starts a goroutine and a timer.
+func WithWatchdog(d time.Duration) Option {
+	return func(o *options) {
+		o.watchdog = d
+	}
+}
+
+// DefaultMaxResultBytes is the result size limit used without
+// WithMaxResultBytes. It is far above what real contracts produce.
+const DefaultMaxResultBytes = 256 << 20
+
+// WithMaxResultBytes makes every call fail with ErrResultTooLarge when the
+// WASM bridge's result exceeds n bytes, before the result is copied out of
+// WASM memory or decoded. It guards the host against a pathological contract
+// or bridge bug producing an enormous verdict set or action space. The
+// default, also used for n = 0, is DefaultMaxResultBytes; n < 0 removes the
+// limit.
+func WithMaxResultBytes(n int) Option {
+	return func(o *options) {
+		o.maxResultBytes = n
+	}
+}
+
+// WithRuntimeConfig makes the evaluator build its wazero runtime from cfg
+// instead of wazero.NewRuntimeConfig(), as an escape hatch for tuning wazero
+// beyond the SDK's options, e.g. wazero.NewRuntimeConfigInterpreter() where
+// the compiler is unavailable.
+//
+// The SDK depends on these settings:
+//
+//   - core features: the embedded binary needs the WebAssembly 2.0 features
+//     it was built with. A cfg that disables one makes construction fail
+//     with ErrIncompatibleRuntimeConfig.
+//   - memory limit: WithMemoryLimitPages must leave room for the contract
+//     and the largest result; too low a limit makes calls fail.
+//   - close on context done: always forced on, so that WithWatchdog and the
+//     Context method variants can interrupt a call.
+//   - compilation cache: replaced by the one given to WithCompilationCache,
+//     if any.
+//
+// Other settings, including the engine, are the caller's choice.
+func WithRuntimeConfig(cfg wazero.RuntimeConfig) Option {
+	return func(o *options) {
+		o.wazeroConfig = cfg
+	}
+}
+
+// runtimeConfig translates the options that concern the WASM runtime.
+func (o options) runtimeConfig() wasm.Config {
+	cfg := wasm.Config{
+		AllocStrategy:  wasm.AllocArena,
+		Watchdog:       o.watchdog,
+		Interruptible:  true,
+		MaxResultBytes: DefaultMaxResultBytes,
+	}
+	if o.maxResultBytes != 0 {
+		cfg.MaxResultBytes = o.maxResultBytes
+	}
+	if o.cache != nil {
+		cfg.Cache = o.cache.cache
+	}
+	cfg.RuntimeConfig = o.wazeroConfig
+	if o.allocStrategy == AllocPerArg {
+		cfg.AllocStrategy = wasm.AllocPerArg
+	}
+	return cfg
+}
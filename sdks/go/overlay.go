@@ -0,0 +1,292 @@
+package tenor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// verdictOverlay is the configuration set by WithVerdictOverlay.
+type verdictOverlay struct {
+	suppress map[string]bool
+	inject   []VerdictSummary
+}
+
+// WithVerdictOverlay makes the evaluator pretend that the verdict types in
+// suppress never fire and that the verdicts in inject always do, without
+// editing the contract. It is meant for testing and experimentation, such as
+// staged rollouts that check downstream behaviour with a verdict switched
+// off; never use it to make decisions in production.
+//
+// Evaluate and EvaluateAsOf results have suppressed verdicts removed and
+// injected ones added, replacing any real verdict of the same type. Action
+// spaces are re-derived Go-side from the overlaid verdicts, the way the
+// evaluator derives them: an action whose entry operation requires a
+// suppressed verdict is blocked with PreconditionNotMet, and an action
+// blocked only on injected verdicts becomes available if its entity states
+// allow it. Flow simulations (ExecuteFlow and friends) are not affected;
+// they still see the contract's own verdicts.
+//
+// A verdict type that is both suppressed and injected is injected.
+func WithVerdictOverlay(suppress []string, inject []VerdictSummary) Option {
+	return func(o *options) {
+		overlay := &verdictOverlay{
+			suppress: make(map[string]bool, len(suppress)),
+			inject:   append([]VerdictSummary(nil), inject...),
+		}
+		for _, v := range suppress {
+			overlay.suppress[v] = true
+		}
+		o.verdictOverlay = overlay
+	}
+}
+
+// hides reports whether the overlay removes real verdicts of verdictType,
+// either suppressing them or replacing them with an injected one.
+func (o *verdictOverlay) hides(verdictType string) bool {
+	if o.suppress[verdictType] {
+		return true
+	}
+	for _, v := range o.inject {
+		if v.VerdictType == verdictType {
+			return true
+		}
+	}
+	return false
+}
+
+// overlayVerdicts applies the overlay to an evaluation result.
+func (e *Evaluator) overlayVerdicts(vs *VerdictSet) {
+	o := e.opts.verdictOverlay
+	if o == nil {
+		return
+	}
+	verdicts := vs.Verdicts[:0]
+	for _, v := range vs.Verdicts {
+		if !o.hides(v.Type) {
+			verdicts = append(verdicts, v)
+		}
+	}
+	for _, v := range o.inject {
+		verdicts = append(verdicts, Verdict{
+			Type:    v.VerdictType,
+			Payload: v.Payload,
+			Provenance: VerdictProvenance{
+				Rule:    v.ProducingRule,
+				Stratum: v.Stratum,
+			},
+		})
+	}
+	vs.Verdicts = verdicts
+}
+
+// overlayActionSpace applies the overlay to an action space computed by the
+// bridge against states, re-deriving the actions whose availability depends
+// on an overlaid verdict.
+func (e *Evaluator) overlayActionSpace(space *ActionSpace, states EntityStateMapNested) {
+	o := e.opts.verdictOverlay
+	if o == nil {
+		return
+	}
+
+	current := space.CurrentVerdicts[:0]
+	for _, v := range space.CurrentVerdicts {
+		if !o.hides(v.VerdictType) {
+			current = append(current, v)
+		}
+	}
+	current = append(current, o.inject...)
+	space.CurrentVerdicts = current
+	present := make(map[string]VerdictSummary, len(current))
+	for _, v := range current {
+		present[v.VerdictType] = v
+	}
+
+	var actions []Action
+	var blocked []BlockedAction
+	for _, a := range space.Actions {
+		missing, enabling := e.checkPrecondition(a.EntryOperationID, present)
+		if len(missing) > 0 {
+			blocked = append(blocked, BlockedAction{
+				FlowID:           a.FlowID,
+				Reason:           BlockedReason{Type: ReasonPreconditionNotMet, MissingVerdicts: missing},
+				InstanceBindings: map[string][]string{},
+			})
+			continue
+		}
+		a.EnablingVerdicts = enabling
+		actions = append(actions, a)
+	}
+	for _, b := range space.BlockedActions {
+		if b.Reason.Type != ReasonPreconditionNotMet {
+			blocked = append(blocked, b)
+			continue
+		}
+		opID := e.entryOperation(b.FlowID)
+		missing, enabling := e.checkPrecondition(opID, present)
+		if len(missing) > 0 {
+			b.Reason.MissingVerdicts = missing
+			blocked = append(blocked, b)
+			continue
+		}
+		if a, bb := e.checkEntityStates(b.FlowID, opID, space.PersonaID, states); a != nil {
+			a.EnablingVerdicts = enabling
+			actions = append(actions, *a)
+		} else {
+			blocked = append(blocked, *bb)
+		}
+	}
+
+	// The evaluator lists flows in contract order; keep that order.
+	order := make(map[string]int, len(e.bundle.Flows))
+	for i, f := range e.bundle.Flows {
+		order[f.ID] = i
+	}
+	sort.SliceStable(actions, func(i, j int) bool { return order[actions[i].FlowID] < order[actions[j].FlowID] })
+	sort.SliceStable(blocked, func(i, j int) bool { return order[blocked[i].FlowID] < order[blocked[j].FlowID] })
+	if actions == nil {
+		actions = []Action{}
+	}
+	if blocked == nil {
+		blocked = []BlockedAction{}
+	}
+	space.Actions = actions
+	space.BlockedActions = blocked
+}
+
+// entryOperation returns the operation of flowID's entry step, or "" if the
+// entry is not an operation step.
+func (e *Evaluator) entryOperation(flowID string) string {
+	flow, ok := e.bundle.Flow(flowID)
+	if !ok {
+		return ""
+	}
+	step, ok := flow.Step(flow.Entry)
+	if !ok || step.Kind != "OperationStep" {
+		return ""
+	}
+	return step.Op
+}
+
+// checkPrecondition checks the verdicts opID's precondition requires against
+// present, as the evaluator does for action spaces: every verdict the
+// precondition references must be present. It returns the missing verdicts
+// and, if none are, the enabling ones.
+func (e *Evaluator) checkPrecondition(opID string, present map[string]VerdictSummary) (missing []string, enabling []VerdictSummary) {
+	op, ok := e.bundle.Operation(opID)
+	if !ok {
+		return nil, nil
+	}
+	expr, err := op.PreconditionExpr()
+	if err != nil {
+		return nil, nil
+	}
+	enabling = []VerdictSummary{}
+	for _, v := range verdictRefs(&expr, nil) {
+		if summary, ok := present[v]; ok {
+			enabling = append(enabling, summary)
+		} else {
+			missing = append(missing, v)
+		}
+	}
+	return missing, enabling
+}
+
+// verdictRefs appends the verdict_present references of e to refs in
+// evaluation order.
+func verdictRefs(e *Expr, refs []string) []string {
+	if e == nil {
+		return refs
+	}
+	if e.VerdictPresent != "" {
+		refs = append(refs, e.VerdictPresent)
+	}
+	for _, child := range []*Expr{e.Left, e.Right, e.Operand, e.Body} {
+		refs = verdictRefs(child, refs)
+	}
+	return refs
+}
+
+// checkEntityStates performs the evaluator's entity-state check for an
+// action whose precondition holds: every entity opID affects needs at least
+// one instance in the effect's source state. It returns the available action,
+// or the blocked action if the check fails.
+func (e *Evaluator) checkEntityStates(flowID, opID, persona string, states EntityStateMapNested) (*Action, *BlockedAction) {
+	op, _ := e.bundle.Operation(opID)
+	valid := make(map[string][]string)
+	blocking := make(map[string][]string)
+	var transitions []string
+	for _, effect := range op.Effects {
+		transitions = append(transitions, fmt.Sprintf("%s from %s to %s", effect.EntityID, effect.From, effect.To))
+		instances := states[effect.EntityID]
+		if len(instances) == 0 {
+			blocking[effect.EntityID] = []string{DefaultInstanceID}
+			return nil, &BlockedAction{
+				FlowID: flowID,
+				Reason: BlockedReason{
+					Type:          ReasonEntityNotInSourceState,
+					EntityID:      effect.EntityID,
+					CurrentState:  "(unknown)",
+					RequiredState: effect.From,
+				},
+				InstanceBindings: blocking,
+			}
+		}
+
+		ids := make([]string, 0, len(instances))
+		for id := range instances {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		var ok, notOK []string
+		for _, id := range ids {
+			if instances[id] == effect.From {
+				ok = append(ok, id)
+			} else {
+				notOK = append(notOK, id)
+			}
+		}
+		if len(ok) == 0 {
+			blocking[effect.EntityID] = append(blocking[effect.EntityID], ids[0])
+			return nil, &BlockedAction{
+				FlowID: flowID,
+				Reason: BlockedReason{
+					Type:          ReasonEntityNotInSourceState,
+					EntityID:      effect.EntityID,
+					CurrentState:  instances[ids[0]],
+					RequiredState: effect.From,
+				},
+				InstanceBindings: blocking,
+			}
+		}
+		valid[effect.EntityID] = ok
+		if len(notOK) > 0 {
+			blocking[effect.EntityID] = notOK
+		}
+	}
+
+	action := &Action{
+		FlowID:           flowID,
+		PersonaID:        persona,
+		EntryOperationID: opID,
+		AffectedEntities: []EntitySummary{},
+		Description:      fmt.Sprintf("Execute %s: %s", flowID, opID),
+		InstanceBindings: valid,
+	}
+	if len(transitions) > 0 {
+		action.Description += " transitions " + strings.Join(transitions, ", ")
+	}
+	for _, effect := range op.Effects {
+		current := states[effect.EntityID][valid[effect.EntityID][0]]
+		summary := EntitySummary{EntityID: effect.EntityID, CurrentState: current, PossibleTransitions: []string{}}
+		if entity, ok := e.bundle.Entity(effect.EntityID); ok {
+			for _, t := range entity.Transitions {
+				if t.From == current {
+					summary.PossibleTransitions = append(summary.PossibleTransitions, t.To)
+				}
+			}
+		}
+		action.AffectedEntities = append(action.AffectedEntities, summary)
+	}
+	return action, nil
+}
@@ -0,0 +1,193 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// payloadType is the subset of an interchange type spec that determines a
+// value's JSON shape.
+type payloadType struct {
+	Base        string                 `json:"base"`
+	Values      []string               `json:"values,omitempty"`
+	Fields      map[string]payloadType `json:"fields,omitempty"`
+	ElementType *payloadType           `json:"element_type,omitempty"`
+	Variants    map[string]payloadType `json:"variants,omitempty"`
+}
+
+// payloadKinds maps a base type to the kind tag of its interchange value.
+var payloadKinds = map[string]string{
+	"Bool":        "bool_value",
+	"Int":         "int_value",
+	"Decimal":     "decimal_value",
+	"Text":        "text_value",
+	"Date":        "date_value",
+	"DateTime":    "datetime_value",
+	"Enum":        "enum_value",
+	"Money":       "money_value",
+	"Duration":    "duration_value",
+	"Record":      "record_value",
+	"List":        "list_value",
+	"TaggedUnion": "tagged_union_value",
+}
+
+// ValidatePayloads checks that each verdict's payload has the JSON shape of
+// the payload type declared by the rule that produced it (a Bool payload is
+// a bool_value holding a bool, a Record has exactly its declared fields, and
+// so on), returning one *PayloadMismatchError per mismatch. It is a tripwire
+// for bridge serialization bugs, meant for tests; the evaluator does not need
+// it.
+//
+// Verdicts whose producing rule is not in b, or whose declared type cannot
+// be read, are reported too. Shapes are checked, not contents: an Int is not
+// checked against its declared range.
+func (vs *VerdictSet) ValidatePayloads(b *Bundle) []error {
+	var errs []error
+	for _, v := range vs.Verdicts {
+		mismatch := func(path, expected, actual string) {
+			errs = append(errs, &PayloadMismatchError{
+				VerdictType: v.Type,
+				Rule:        v.Provenance.Rule,
+				Path:        path,
+				Expected:    expected,
+				Actual:      actual,
+			})
+		}
+
+		rule, ok := b.Rule(v.Provenance.Rule)
+		if !ok {
+			mismatch("", "a rule declared in the contract", "unknown rule")
+			continue
+		}
+		var declared struct {
+			Type *payloadType `json:"type"`
+		}
+		if err := json.Unmarshal(rule.Body.Produce.Payload, &declared); err != nil || declared.Type == nil {
+			mismatch("", "a declared payload type", "unreadable produce clause")
+			continue
+		}
+		checkPayload(*declared.Type, v.Payload, "payload", mismatch)
+	}
+	return errs
+}
+
+// checkPayload compares value against t, calling mismatch for each
+// difference found.
+func checkPayload(t payloadType, value interface{}, path string, mismatch func(path, expected, actual string)) {
+	kind, ok := payloadKinds[t.Base]
+	if !ok {
+		return
+	}
+	obj, isObj := value.(map[string]interface{})
+	if !isObj || obj["kind"] != kind {
+		mismatch(path, kind, jsonShape(value))
+		return
+	}
+
+	field := func(name, want string) {
+		got := jsonShape(obj[name])
+		if got != want {
+			mismatch(path+"."+name, want, got)
+		}
+	}
+
+	switch t.Base {
+	case "Bool":
+		field("value", "bool")
+	case "Int":
+		if n, ok := obj["value"].(float64); !ok || n != math.Trunc(n) {
+			mismatch(path+".value", "integer", jsonShape(obj["value"]))
+		}
+	case "Decimal", "Text", "Date", "DateTime":
+		field("value", "string")
+	case "Enum":
+		field("value", "string")
+		if s, ok := obj["value"].(string); ok && len(t.Values) > 0 && !contains(t.Values, s) {
+			mismatch(path+".value", fmt.Sprintf("one of %v", t.Values), fmt.Sprintf("%q", s))
+		}
+	case "Money":
+		field("amount", "string")
+		field("currency", "string")
+	case "Duration":
+		field("value", "number")
+		field("unit", "string")
+	case "Record":
+		fields, ok := obj["fields"].(map[string]interface{})
+		if !ok {
+			mismatch(path+".fields", "object", jsonShape(obj["fields"]))
+			return
+		}
+		names := make([]string, 0, len(t.Fields))
+		for name := range t.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fv, ok := fields[name]
+			if !ok {
+				mismatch(path+".fields."+name, "declared field", "missing")
+				continue
+			}
+			checkPayload(t.Fields[name], fv, path+".fields."+name, mismatch)
+		}
+		var extra []string
+		for name := range fields {
+			if _, ok := t.Fields[name]; !ok {
+				extra = append(extra, name)
+			}
+		}
+		sort.Strings(extra)
+		for _, name := range extra {
+			mismatch(path+".fields."+name, "no such field", "undeclared field")
+		}
+	case "List":
+		elements, ok := obj["elements"].([]interface{})
+		if !ok {
+			mismatch(path+".elements", "array", jsonShape(obj["elements"]))
+			return
+		}
+		if t.ElementType == nil {
+			return
+		}
+		for i, e := range elements {
+			checkPayload(*t.ElementType, e, fmt.Sprintf("%s.elements[%d]", path, i), mismatch)
+		}
+	case "TaggedUnion":
+		tag, ok := obj["tag"].(string)
+		if !ok {
+			mismatch(path+".tag", "string", jsonShape(obj["tag"]))
+			return
+		}
+		variant, ok := t.Variants[tag]
+		if !ok {
+			mismatch(path+".tag", "a declared variant", fmt.Sprintf("%q", tag))
+			return
+		}
+		checkPayload(variant, obj["payload"], path+".payload", mismatch)
+	}
+}
+
+// jsonShape describes a decoded JSON value for mismatch reports: the kind tag
+// of an interchange value, or the JSON type otherwise.
+func jsonShape(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		if kind, ok := v["kind"].(string); ok {
+			return kind
+		}
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
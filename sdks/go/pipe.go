@@ -0,0 +1,52 @@
+package tenor
+
+import (
+	"context"
+	"log/slog"
+)
+
+// EvalResult is the outcome of evaluating one fact set in EvaluatePipe.
+// Exactly one of VerdictSet and Err is set.
+type EvalResult struct {
+	VerdictSet *VerdictSet
+	Err        error
+}
+
+// EvaluatePipe evaluates each fact set received on in and sends its result on
+// out, in the order the fact sets arrived. It returns, closing out, when in is
+// closed or ctx is cancelled; run it in its own goroutine.
+//
+// Cancellation is checked between fact sets and while waiting to send: an
+// evaluation already in progress completes, so that cancelling the pipe
+// leaves e usable, and its result is dropped if ctx is cancelled before out
+// accepts it.
+//
+// With WithLogger, stopping on cancellation is logged at debug level and a
+// dropped result at warn level, with the correlation ID ctx carries (see
+// ContextWithCorrelationID).
+//
+// Evaluations are still serialised per Evaluator. For parallelism, run one
+// EvaluatePipe per Evaluator in a pool and merge their outputs.
+func (e *Evaluator) EvaluatePipe(ctx context.Context, in <-chan FactSet, out chan<- EvalResult) {
+	defer close(out)
+	sent := 0
+	for {
+		select {
+		case <-ctx.Done():
+			e.opts.log(ctx, slog.LevelDebug, "tenor: evaluate pipe cancelled", "sent", sent)
+			return
+		case facts, ok := <-in:
+			if !ok {
+				return
+			}
+			verdicts, err := e.EvaluateContext(context.WithoutCancel(ctx), facts)
+			select {
+			case out <- EvalResult{VerdictSet: verdicts, Err: err}:
+				sent++
+			case <-ctx.Done():
+				e.opts.log(ctx, slog.LevelWarn, "tenor: evaluate pipe cancelled, dropping a result", "sent", sent)
+				return
+			}
+		}
+	}
+}
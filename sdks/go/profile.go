@@ -0,0 +1,167 @@
+package tenor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/riverline-labs/tenor-go/internal/wasm"
+)
+
+// RuleTiming is the evaluation cost attributed to one rule.
+type RuleTiming struct {
+	// Duration is the wall time attributed to the rule over Count
+	// evaluations.
+	Duration time.Duration
+	// Count is how many evaluations the timing covers: 1 in a profile
+	// returned by EvaluateProfiled.
+	Count int
+}
+
+// RuleProfile maps rule IDs to their evaluation cost.
+type RuleProfile map[string]RuleTiming
+
+// Merge adds other's timings to p, so profiles of many EvaluateProfiled
+// calls can be summed into one.
+func (p RuleProfile) Merge(other RuleProfile) {
+	for id, t := range other {
+		sum := p[id]
+		sum.Duration += t.Duration
+		sum.Count += t.Count
+		p[id] = sum
+	}
+}
+
+// WithRuleProfiling enables EvaluateProfiled. The evaluator bridge reports
+// no per-rule timing, so the profile is measured from outside: at load time
+// the evaluator also loads, into the same runtime, one reduced contract per
+// rule holding that rule and every rule before it in stratum order, plus one
+// holding no rules at all. Loading takes correspondingly longer and the
+// runtime holds one extra contract per rule.
+func WithRuleProfiling() Option {
+	return func(o *options) {
+		o.ruleProfiling = true
+	}
+}
+
+// profilePrefix is a reduced contract loaded under WithRuleProfiling: the
+// contract's facts and sources with the rules up to and including ruleID.
+// The baseline prefix, holding no rules, has an empty ruleID.
+type profilePrefix struct {
+	ruleID string
+	handle uint32
+}
+
+// loadProfilePrefixes loads the reduced contracts WithRuleProfiling needs
+// into rt, baseline first.
+func loadProfilePrefixes(rt *wasm.Runtime, b *Bundle) ([]profilePrefix, error) {
+	rules := make([]RuleDef, len(b.Rules))
+	copy(rules, b.Rules)
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Stratum < rules[j].Stratum })
+
+	included := make(map[string]bool, len(rules))
+	prefixes := make([]profilePrefix, 0, len(rules)+1)
+	for i := -1; i < len(rules); i++ {
+		var ruleID string
+		if i >= 0 {
+			ruleID = rules[i].ID
+			included[ruleID] = true
+		}
+		bundleJSON, err := b.withRules(included).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		result, err := rt.CallOneArg("load_contract", string(bundleJSON))
+		if err != nil {
+			return nil, fmt.Errorf("failed to call load_contract: %w", err)
+		}
+		var loadResult struct {
+			Handle *uint32 `json:"handle"`
+			Error  *string `json:"error"`
+		}
+		if err := decodeResult("load_contract", "load result", result, &loadResult, false); err != nil {
+			return nil, err
+		}
+		if loadResult.Error != nil {
+			return nil, fmt.Errorf("profiling contract load error: %s", *loadResult.Error)
+		}
+		if loadResult.Handle == nil {
+			return nil, fmt.Errorf("load_contract returned neither handle nor error")
+		}
+		prefixes = append(prefixes, profilePrefix{ruleID: ruleID, handle: *loadResult.Handle})
+	}
+	return prefixes, nil
+}
+
+// withRules returns a copy of b reduced to its facts, sources and the rules
+// in rules, which must be closed under verdict dependencies for the result
+// to load.
+func (b *Bundle) withRules(rules map[string]bool) *Bundle {
+	reduced := &Bundle{
+		ID:           b.ID,
+		TenorVersion: b.TenorVersion,
+		Facts:        b.Facts,
+		Sources:      b.Sources,
+		header:       b.header,
+	}
+	for _, r := range b.Rules {
+		if rules[r.ID] {
+			reduced.Rules = append(reduced.Rules, r)
+		}
+	}
+	for _, c := range b.constructs {
+		if c.Kind == "Fact" || c.Kind == "Source" || (c.Kind == "Rule" && rules[c.ID]) {
+			reduced.constructs = append(reduced.constructs, c)
+		}
+	}
+	return reduced
+}
+
+// EvaluateProfiled evaluates facts as Evaluate does and also returns the
+// wall time spent on each rule. It fails with ErrProfilingDisabled unless
+// the evaluator was created with WithRuleProfiling.
+//
+// The times are approximations. Each reduced contract loaded by
+// WithRuleProfiling is evaluated once against facts, and a rule is charged
+// the difference between its contract and the one before it, so call
+// overhead cancels out and a negative difference is clamped to zero. Timer
+// resolution and noise dominate for cheap rules: profile over many fact sets
+// and Merge the results before drawing conclusions. A call costs one full
+// evaluation plus one evaluation per rule prefix, so roughly as many
+// evaluations as the contract has rules.
+func (e *Evaluator) EvaluateProfiled(facts FactSet) (*VerdictSet, RuleProfile, error) {
+	if !e.opts.ruleProfiling {
+		return nil, nil, ErrProfilingDisabled
+	}
+	vs, err := e.Evaluate(facts)
+	if err != nil {
+		return nil, nil, err
+	}
+	factsJSON, err := e.marshalFacts(facts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal facts: %w", err)
+	}
+
+	profile := make(RuleProfile, len(e.profilePrefixes))
+	var previous time.Duration
+	for _, p := range e.profilePrefixes {
+		start := time.Now()
+		result, err := e.runtime.CallHandleOneArg("evaluate", p.handle, string(factsJSON))
+		elapsed := time.Since(start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("evaluate WASM call failed: %w", err)
+		}
+		if errMsg := extractError(result); errMsg != "" {
+			return nil, nil, fmt.Errorf("evaluation error: %s", errMsg)
+		}
+		if p.ruleID != "" {
+			cost := elapsed - previous
+			if cost < 0 {
+				cost = 0
+			}
+			profile[p.ruleID] = RuleTiming{Duration: cost, Count: 1}
+		}
+		previous = elapsed
+	}
+	return vs, profile, nil
+}
@@ -0,0 +1,181 @@
+package tenor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/riverline-labs/tenor-go/internal/wasm"
+)
+
+// ErrRegistryClosed is returned by Registry.Register after Close.
+var ErrRegistryClosed = errors.New("registry closed")
+
+// CompilationCache shares the compiled WASM module between evaluators, so
+// that only the first evaluator created with it pays for compilation. Pass it
+// to each evaluator with WithCompilationCache. Evaluators created without one
+// share a process-wide cache, unless WithRuntimeConfig is used, in which case
+// each compiles the embedded binary afresh.
+//
+// A CompilationCache is safe for concurrent use. Close it once no evaluator
+// will be created with it; evaluators already created are unaffected.
+type CompilationCache struct {
+	cache wasm.Cache
+}
+
+// NewCompilationCache returns an empty in-memory CompilationCache.
+func NewCompilationCache() *CompilationCache {
+	return &CompilationCache{cache: wasm.NewCache()}
+}
+
+// NewCompilationCacheDir returns a CompilationCache that also stores the
+// compiled module in dir, creating the directory if needed. A process that
+// starts with the same dir, SDK version and platform loads the module from
+// disk instead of compiling it, which shortens the first evaluator's
+// creation after a restart.
+func NewCompilationCacheDir(dir string) (*CompilationCache, error) {
+	cache, err := wasm.NewDirCache(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compilation cache in %s: %w", dir, err)
+	}
+	return &CompilationCache{cache: cache}, nil
+}
+
+// defaultCompilationCache returns the process-wide cache used by evaluators
+// created without WithCompilationCache, creating it on first use.
+func defaultCompilationCache() *CompilationCache {
+	defaultCacheOnce.Do(func() { defaultCache = NewCompilationCache() })
+	return defaultCache
+}
+
+var (
+	defaultCacheOnce sync.Once
+	defaultCache     *CompilationCache
+)
+
+// Close releases the cached compilation.
+func (c *CompilationCache) Close() error {
+	return c.cache.Close(context.Background())
+}
+
+// WithCompilationCache makes the evaluator take its compiled WASM module
+// from c, compiling and storing it there on first use, instead of from the
+// process-wide cache. Use it to share compilation under WithRuntimeConfig,
+// to persist it with NewCompilationCacheDir, or to release it with Close.
+func WithCompilationCache(c *CompilationCache) Option {
+	return func(o *options) {
+		o.cache = c
+	}
+}
+
+// Registry hosts evaluators by name, for services that serve many contracts.
+// It owns the evaluators it creates: Unregister and Close close them. It is
+// safe for concurrent use.
+//
+// An Evaluator returned by Get stays valid until its name is unregistered or
+// the registry is closed; calls made on it after that fail.
+type Registry struct {
+	opts []Option
+	// cache is the registry's own compilation cache, closed with it.
+	cache *CompilationCache
+
+	mu         sync.RWMutex
+	evaluators map[string]*Evaluator
+	closed     bool
+}
+
+// NewRegistry returns an empty Registry that creates evaluators with opts.
+//
+// Evaluators already share the process-wide compilation cache unless opts
+// include WithRuntimeConfig or WithCompilationCache, so shareModule only
+// matters in those cases. With it set, the registry's evaluators instead
+// share one compiled WASM module through a CompilationCache the registry
+// owns, replacing any given with WithCompilationCache, and Close releases
+// it. Under WithRuntimeConfig, that makes every registration after the first
+// much cheaper.
+func NewRegistry(shareModule bool, opts ...Option) *Registry {
+	r := &Registry{evaluators: make(map[string]*Evaluator)}
+	if shareModule {
+		r.cache = NewCompilationCache()
+		opts = append(append([]Option(nil), opts...), WithCompilationCache(r.cache))
+	}
+	r.opts = opts
+	return r
+}
+
+// Register loads bundleJSON and hosts it under name. It fails if name is
+// already registered; unregister it first to replace it.
+func (r *Registry) Register(name string, bundleJSON []byte) error {
+	// Loading is slow; do it without holding the lock.
+	eval, err := NewEvaluatorFromBundle(bundleJSON, r.opts...)
+	if err != nil {
+		return fmt.Errorf("register %q: %w", name, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		_ = eval.Close()
+		return fmt.Errorf("register %q: %w", name, ErrRegistryClosed)
+	}
+	if _, ok := r.evaluators[name]; ok {
+		_ = eval.Close()
+		return fmt.Errorf("register %q: name already registered", name)
+	}
+	r.evaluators[name] = eval
+	return nil
+}
+
+// Get returns the evaluator registered under name.
+func (r *Registry) Get(name string) (*Evaluator, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	eval, ok := r.evaluators[name]
+	return eval, ok
+}
+
+// Names returns the registered names, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.evaluators))
+	for name := range r.evaluators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Unregister removes name from the registry and closes its evaluator. It
+// does nothing if name is not registered.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	eval, ok := r.evaluators[name]
+	delete(r.evaluators, name)
+	r.mu.Unlock()
+	if ok {
+		_ = eval.Close()
+	}
+}
+
+// Close unregisters every evaluator, closing them, and releases the shared
+// module. Register fails afterwards. Calling Close more than once is safe.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	evaluators := r.evaluators
+	r.evaluators = make(map[string]*Evaluator)
+	alreadyClosed := r.closed
+	r.closed = true
+	r.mu.Unlock()
+
+	var errs []error
+	for _, eval := range evaluators {
+		errs = append(errs, eval.Close())
+	}
+	if r.cache != nil && !alreadyClosed {
+		errs = append(errs, r.cache.Close())
+	}
+	return errors.Join(errs...)
+}
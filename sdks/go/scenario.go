@@ -0,0 +1,77 @@
+package tenor
+
+// Scenario is one point in an exploration of a contract: the facts and entity
+// states an evaluation starts from. The Evaluator is stateless, so a Scenario
+// is the whole state of a simulation; fork it to branch a tree search and
+// apply flow results to advance a branch.
+//
+// A Scenario owns its maps. NewScenario and Fork copy deeply, so no fork ever
+// shares a map or slice with another. A Scenario is not safe for concurrent
+// mutation; fork it per goroutine instead.
+type Scenario struct {
+	Facts  FactSet
+	States EntityStateMapNested
+}
+
+// NewScenario returns a Scenario holding deep copies of facts and states.
+func NewScenario(facts FactSet, states EntityStateMapNested) *Scenario {
+	return &Scenario{
+		Facts:  copyFacts(facts),
+		States: states.clone(),
+	}
+}
+
+// Fork returns a deep copy of s. Changes to either afterwards do not affect
+// the other.
+func (s *Scenario) Fork() *Scenario {
+	return NewScenario(s.Facts, s.States)
+}
+
+// Apply advances s by the transitions of result. It uses ApplyTransitions, so
+// a result computed against other states fails with ErrStaleState and leaves
+// s unchanged.
+func (s *Scenario) Apply(result *FlowResult) error {
+	states, err := ApplyTransitions(s.States, result.WouldTransition)
+	if err != nil {
+		return err
+	}
+	s.States = states
+	return nil
+}
+
+// ActionSpace computes the action space for persona at this point of the
+// exploration.
+func (s *Scenario) ActionSpace(eval *Evaluator, persona string) (*ActionSpace, error) {
+	return eval.ComputeActionSpaceNested(s.Facts, s.States, persona)
+}
+
+// copyFacts deep-copies facts, including the maps and slices of structured
+// values.
+func copyFacts(facts FactSet) FactSet {
+	if facts == nil {
+		return nil
+	}
+	copied := make(FactSet, len(facts))
+	for id, v := range facts {
+		copied[id] = copyValue(v)
+	}
+	return copied
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			copied[k] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+	return v
+}
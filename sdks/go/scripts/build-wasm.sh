@@ -9,6 +9,14 @@ REPO_ROOT="$(cd "$(dirname "$0")/../../../" && pwd)"
 OUT_DIR="$(cd "$(dirname "$0")/.." && pwd)/internal/wasm"
 BRIDGE_DIR="$REPO_ROOT/sdks/go/wasm-bridge"
 
+# TENOR_WASM_STACK_SIZE sets the bridge's shadow stack size in bytes (the
+# Rust default for wasm32 is 1 MiB). Raise it for deeply recursive contracts;
+# every instance reserves the full stack in linear memory, so larger stacks
+# cost memory per Evaluator.
+if [ -n "${TENOR_WASM_STACK_SIZE:-}" ]; then
+  export RUSTFLAGS="${RUSTFLAGS:-} -C link-arg=-zstack-size=${TENOR_WASM_STACK_SIZE}"
+fi
+
 echo "Building Tenor WASM bridge (wasm32-wasip1)..."
 cargo build \
   --manifest-path "$BRIDGE_DIR/Cargo.toml" \
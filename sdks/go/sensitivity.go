@@ -0,0 +1,133 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// MaxSensitivityAlternatives caps the alternative values SensitivityOf
+// explores for one fact. Larger domains are truncated.
+const MaxSensitivityAlternatives = 32
+
+// SensitivityReport describes how verdicts and actions respond to changing
+// one fact.
+type SensitivityReport struct {
+	FactID string
+	// Baseline is the fact's value in the facts given, or its declared
+	// default.
+	Baseline interface{}
+	// Alternatives holds one entry per alternative value explored, in the
+	// order of the fact's domain.
+	Alternatives []FactAlternative
+	// Truncated reports that the domain had more than
+	// MaxSensitivityAlternatives alternative values.
+	Truncated bool
+}
+
+// FactAlternative is the effect of giving a fact one alternative value,
+// relative to the baseline.
+type FactAlternative struct {
+	Value    interface{}
+	Verdicts VerdictDiff
+	// Actions is empty for contracts without operations.
+	Actions ActionSpaceDiff
+}
+
+// SensitivityOf answers "why does this input matter?": it evaluates facts
+// with factID at its baseline value and at each alternative value, and diffs
+// the verdict sets and the persona's action spaces against the baseline.
+//
+// Bool facts have one alternative, the flipped value; Enum facts have one
+// per other value of the enum, up to MaxSensitivityAlternatives. Other fact
+// types have no enumerable domain and return an error wrapping
+// ErrNotSupported.
+func (e *Evaluator) SensitivityOf(
+	factID string,
+	facts FactSet,
+	entityStates EntityStateMap,
+	persona string,
+) (*SensitivityReport, error) {
+	decl, ok := e.bundle.Fact(factID)
+	if !ok {
+		return nil, fmt.Errorf("fact %q is not declared in the contract", factID)
+	}
+	baseline, ok := facts[factID]
+	if !ok {
+		if baseline, ok = decl.DefaultValue(); !ok {
+			return nil, fmt.Errorf("fact %q has no value and no declared default", factID)
+		}
+	}
+
+	domain, err := factDomain(decl)
+	if err != nil {
+		return nil, err
+	}
+	report := &SensitivityReport{FactID: factID, Baseline: baseline}
+	var values []interface{}
+	for _, v := range domain {
+		if reflect.DeepEqual(v, baseline) {
+			continue
+		}
+		if len(values) == MaxSensitivityAlternatives {
+			report.Truncated = true
+			break
+		}
+		values = append(values, v)
+	}
+
+	withActions := e.bundle.Capabilities().HasOperations
+	run := func(facts FactSet) (*VerdictSet, *ActionSpace, error) {
+		verdicts, err := e.Evaluate(facts)
+		if err != nil || !withActions {
+			return verdicts, nil, err
+		}
+		space, err := e.ComputeActionSpace(facts, entityStates, persona)
+		return verdicts, space, err
+	}
+
+	baseVerdicts, baseSpace, err := run(facts)
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %w", err)
+	}
+	for _, v := range values {
+		alt := make(FactSet, len(facts)+1)
+		for id, value := range facts {
+			alt[id] = value
+		}
+		alt[factID] = v
+
+		verdicts, space, err := run(alt)
+		if err != nil {
+			return nil, fmt.Errorf("%s = %v: %w", factID, v, err)
+		}
+		report.Alternatives = append(report.Alternatives, FactAlternative{
+			Value:    v,
+			Verdicts: DiffVerdicts(baseVerdicts, verdicts),
+			Actions:  DiffActionSpaces(baseSpace, space),
+		})
+	}
+	return report, nil
+}
+
+// factDomain returns every value a Bool or Enum fact can take.
+func factDomain(decl *FactDef) ([]interface{}, error) {
+	switch base := decl.BaseType(); base {
+	case "Bool":
+		return []interface{}{false, true}, nil
+	case "Enum":
+		var t struct {
+			Values []string `json:"values"`
+		}
+		if err := json.Unmarshal(decl.Type, &t); err != nil {
+			return nil, fmt.Errorf("fact %q: invalid Enum type: %w", decl.ID, err)
+		}
+		domain := make([]interface{}, len(t.Values))
+		for i, v := range t.Values {
+			domain[i] = v
+		}
+		return domain, nil
+	default:
+		return nil, fmt.Errorf("fact %q has type %s with no enumerable values: %w", decl.ID, base, ErrNotSupported)
+	}
+}
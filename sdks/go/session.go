@@ -0,0 +1,68 @@
+package tenor
+
+import "sync"
+
+// Session maintains the current facts and verdicts of a long-lived
+// evaluation, for clients that push fact updates one at a time. Each Update
+// merges a partial fact set into the session's facts, re-evaluates, and
+// reports what changed.
+//
+// A Session owns its facts: NewSession and Update copy what they are given,
+// and Facts returns a copy. It is safe for concurrent use; updates are
+// applied one at a time.
+type Session struct {
+	eval *Evaluator
+
+	mu       sync.Mutex
+	facts    FactSet
+	verdicts *VerdictSet
+}
+
+// NewSession returns a Session on e starting from a copy of facts. It has no
+// verdicts until the first Update, which reports every verdict as added.
+func (e *Evaluator) NewSession(facts FactSet) *Session {
+	s := &Session{eval: e, facts: copyFacts(facts)}
+	if s.facts == nil {
+		s.facts = make(FactSet)
+	}
+	return s
+}
+
+// Update merges partial into the session's facts, a nil value removing the
+// fact, evaluates the result and returns the new verdict set with its diff
+// from the previous one. If evaluation fails the session is left unchanged.
+func (s *Session) Update(partial FactSet) (*VerdictSet, VerdictDiff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	facts := copyFacts(s.facts)
+	for id, v := range partial {
+		if v == nil {
+			delete(facts, id)
+			continue
+		}
+		facts[id] = copyValue(v)
+	}
+	verdicts, diff, err := s.eval.EvaluateDelta(s.verdicts, facts)
+	if err != nil {
+		return nil, VerdictDiff{}, err
+	}
+	s.facts = facts
+	s.verdicts = verdicts
+	return verdicts, diff, nil
+}
+
+// Facts returns a copy of the session's current facts.
+func (s *Session) Facts() FactSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyFacts(s.facts)
+}
+
+// Verdicts returns the verdict set of the last successful Update, or nil
+// before the first.
+func (s *Session) Verdicts() *VerdictSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.verdicts
+}
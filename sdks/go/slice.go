@@ -0,0 +1,82 @@
+package tenor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Slice returns the smallest bundle that still produces verdictType: the
+// rules that produce it, the rules producing the verdicts those rules
+// require, transitively, the facts all of them reference and the sources
+// those facts are bound to. Entities, operations, flows, personas and system
+// constructs are dropped. Constructs keep their order and content, so the
+// slice marshals (see MarshalJSON) to a bundle that loads with
+// NewEvaluatorFromBundle and, given the same facts, produces the same
+// verdict.
+//
+// It is meant for bug reports: a slice can be shared without the rest of the
+// contract. Slice fails if no rule produces verdictType or b was not created
+// by ParseBundle.
+func (b *Bundle) Slice(verdictType string) (*Bundle, error) {
+	if b.header == nil {
+		return nil, errors.New("bundle was not created by ParseBundle")
+	}
+
+	rules, facts := b.verdictDependencies(verdictType)
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rule produces verdict %q", verdictType)
+	}
+
+	sources := make(map[string]bool)
+	slice := &Bundle{ID: b.ID, TenorVersion: b.TenorVersion, header: b.header}
+	for _, f := range b.Facts {
+		if facts[f.ID] {
+			slice.Facts = append(slice.Facts, f)
+			if id := f.SourceID(); id != "" {
+				sources[id] = true
+			}
+		}
+	}
+	for _, r := range b.Rules {
+		if rules[r.ID] {
+			slice.Rules = append(slice.Rules, r)
+		}
+	}
+	for _, src := range b.Sources {
+		if sources[src.ID] {
+			slice.Sources = append(slice.Sources, src)
+		}
+	}
+
+	keep := map[string]map[string]bool{"Fact": facts, "Rule": rules, "Source": sources}
+	for _, c := range b.constructs {
+		if keep[c.Kind][c.ID] {
+			slice.constructs = append(slice.constructs, c)
+		}
+	}
+	return slice, nil
+}
+
+// MarshalJSON encodes b as an interchange bundle. The constructs are written
+// as ParseBundle read them, so changes made to b's fields afterwards are not
+// reflected. It fails for a bundle not created by ParseBundle or Slice.
+func (b *Bundle) MarshalJSON() ([]byte, error) {
+	if b.header == nil {
+		return nil, errors.New("bundle was not created by ParseBundle")
+	}
+	out := make(map[string]json.RawMessage, len(b.header)+1)
+	for k, v := range b.header {
+		out[k] = v
+	}
+	constructs := make([]json.RawMessage, len(b.constructs))
+	for i, c := range b.constructs {
+		constructs[i] = c.JSON
+	}
+	raw, err := json.Marshal(constructs)
+	if err != nil {
+		return nil, err
+	}
+	out["constructs"] = raw
+	return json.Marshal(out)
+}
@@ -0,0 +1,215 @@
+package tenor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// InitialStates returns every declared entity at its initial state under the
+// default instance, matching how the evaluator seeds entity state before
+// applying caller-provided states.
+func (b *Bundle) InitialStates() EntityStateMapNested {
+	states := make(EntityStateMapNested, len(b.Entities))
+	for _, e := range b.Entities {
+		states[e.ID] = map[string]string{DefaultInstanceID: e.Initial}
+	}
+	return states
+}
+
+// EntityStateRow is one entity instance's state as typically stored in a
+// database, for NewEntityStateMapNested.
+type EntityStateRow struct {
+	EntityID   string
+	InstanceID string
+	State      string
+}
+
+// NewEntityStateMapNested groups rows into the multi-instance state format.
+// A row may repeat an (entity, instance) pair with the same state; repeating
+// it with a different state is an error. With no rows the result is an empty,
+// non-nil map.
+func NewEntityStateMapNested(rows []EntityStateRow) (EntityStateMapNested, error) {
+	states := make(EntityStateMapNested)
+	for _, row := range rows {
+		if current, ok := states[row.EntityID][row.InstanceID]; ok && current != row.State {
+			return nil, fmt.Errorf("entity %q instance %q has conflicting states %q and %q",
+				row.EntityID, row.InstanceID, current, row.State)
+		}
+		states.set(row.EntityID, row.InstanceID, row.State)
+	}
+	return states, nil
+}
+
+// inferStates fills in, when WithInferInitialStates is set, every declared
+// entity missing from states with its initial state. states is not modified.
+func (e *Evaluator) inferStates(states EntityStateMap) EntityStateMap {
+	if !e.opts.inferInitialStates {
+		return states
+	}
+	filled := make(EntityStateMap, len(e.bundle.Entities))
+	for _, ent := range e.bundle.Entities {
+		filled[ent.ID] = ent.Initial
+	}
+	for entityID, state := range states {
+		filled[entityID] = state
+	}
+	return filled
+}
+
+// inferStatesNested is inferStates for the multi-instance format. Missing
+// entities get a single default instance in their initial state; entities
+// with at least one instance are left as given.
+func (e *Evaluator) inferStatesNested(states EntityStateMapNested) EntityStateMapNested {
+	if !e.opts.inferInitialStates {
+		return states
+	}
+	filled := e.bundle.InitialStates()
+	for entityID, instances := range states {
+		filled[entityID] = instances
+	}
+	return filled
+}
+
+// ApplyTransitions applies changes, in order, to a copy of states and returns
+// the copy. Each change is applied only if the instance is currently in its
+// FromState, so a change computed against a state that has since moved on is
+// rejected instead of overwriting it. Changes see the effect of earlier ones,
+// which lets a flow's WouldTransition be applied as reported.
+//
+// Application is all or nothing: if any change is stale, ApplyTransitions
+// returns nil and a *StaleStateError (matching ErrStaleState) for the first
+// conflicting change. states itself is never modified.
+func ApplyTransitions(states EntityStateMapNested, changes []EntityStateChange) (EntityStateMapNested, error) {
+	applied := states.clone()
+	for i, c := range changes {
+		current, ok := applied[c.EntityID][c.InstanceID]
+		if !ok || current != c.FromState {
+			return nil, fmt.Errorf("change %d: %w", i, &StaleStateError{
+				EntityID:   c.EntityID,
+				InstanceID: c.InstanceID,
+				Expected:   c.FromState,
+				Actual:     current,
+				Missing:    !ok,
+			})
+		}
+		applied.set(c.EntityID, c.InstanceID, c.ToState)
+	}
+	return applied, nil
+}
+
+// nested converts single-instance states to the multi-instance format,
+// placing each entity under the default instance.
+func (m EntityStateMap) nested() EntityStateMapNested {
+	states := make(EntityStateMapNested, len(m))
+	for entityID, state := range m {
+		states[entityID] = map[string]string{DefaultInstanceID: state}
+	}
+	return states
+}
+
+// clone returns a deep copy of the state map.
+func (m EntityStateMapNested) clone() EntityStateMapNested {
+	states := make(EntityStateMapNested, len(m))
+	for entityID, instances := range m {
+		copied := make(map[string]string, len(instances))
+		for instanceID, state := range instances {
+			copied[instanceID] = state
+		}
+		states[entityID] = copied
+	}
+	return states
+}
+
+// set records state for one entity instance, creating the entity entry if needed.
+func (m EntityStateMapNested) set(entityID, instanceID, state string) {
+	instances, ok := m[entityID]
+	if !ok {
+		instances = make(map[string]string)
+		m[entityID] = instances
+	}
+	instances[instanceID] = state
+}
+
+// replayStepStates fills in StateAfter for each step of result by applying
+// result.WouldTransition incrementally, starting from the declared initial
+// states overlaid with states.
+//
+// The bridge reports transitions as one flat list in execution order, so each
+// step's share is recovered from the contract: a successful operation or
+// compensation step applies one transition per effect of its operation.
+// Sub-flow steps do not appear in the path individually; a sub-flow step is
+// credited with every transition not claimed by later steps (if a flow invokes
+// several sub-flows, the first one absorbs them all).
+func (b *Bundle) replayStepStates(result *FlowResult, states EntityStateMapNested) {
+	flow, ok := b.Flow(result.FlowID)
+	if !ok {
+		return
+	}
+
+	counts := make([]int, len(result.Path))
+	for i, step := range result.Path {
+		counts[i] = b.stepTransitionCount(flow, step)
+	}
+
+	current := b.InitialStates()
+	for entityID, instances := range states {
+		for instanceID, state := range instances {
+			current.set(entityID, instanceID, state)
+		}
+	}
+
+	changes := result.WouldTransition
+	cursor := 0
+	for i := range result.Path {
+		n := counts[i]
+		if n < 0 {
+			n = len(changes) - cursor
+			for _, later := range counts[i+1:] {
+				if later > 0 {
+					n -= later
+				}
+			}
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n > len(changes)-cursor {
+			n = len(changes) - cursor
+		}
+		for _, c := range changes[cursor : cursor+n] {
+			current.set(c.EntityID, c.InstanceID, c.ToState)
+		}
+		cursor += n
+		result.Path[i].StateAfter = current.clone()
+	}
+}
+
+// stepTransitionCount returns how many transitions a path step applied, or -1
+// for a successful sub-flow step whose share cannot be derived statically.
+func (b *Bundle) stepTransitionCount(flow *FlowDef, step StepResult) int {
+	switch step.StepType {
+	case "operation":
+		if strings.HasPrefix(step.Result, "error") {
+			return 0
+		}
+		fs, ok := flow.Step(step.StepID)
+		if !ok {
+			return 0
+		}
+		if op, ok := b.Operation(fs.Op); ok {
+			return len(op.Effects)
+		}
+	case "compensation":
+		if strings.HasPrefix(step.Result, "error") {
+			return 0
+		}
+		if op, ok := b.Operation(strings.TrimPrefix(step.StepID, "comp:")); ok {
+			return len(op.Effects)
+		}
+	case "sub_flow":
+		if step.Result != "error" {
+			return -1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package tenor
+
+import (
+	"fmt"
+	"time"
+)
+
+// EvaluatorStats reports how an Evaluator has been used since it was loaded.
+type EvaluatorStats struct {
+	// LoadedAt is when the contract was loaded.
+	LoadedAt time.Time
+	// Evaluations counts evaluate calls: Evaluate, EvaluateAsOf and
+	// EvaluatePipe items.
+	Evaluations int64
+	// ActionSpaceCalls counts action space computations; a batch counts once
+	// per item computed.
+	ActionSpaceCalls int64
+	// FlowCalls counts flow simulations: ExecuteFlow and
+	// ExecuteFlowWithBindings.
+	FlowCalls int64
+	// WASMTime is the total time spent in WASM calls, including loading the
+	// contract.
+	WASMTime time.Duration
+	// LastUsed is when the most recent WASM call finished. It is the load
+	// time for an evaluator that has not been called since.
+	LastUsed time.Time
+}
+
+// Stats returns the evaluator's usage counters, for spotting hot or idle
+// evaluators in a pool. Calls that fail in WASM are counted; calls rejected
+// Go-side before reaching WASM, such as unknown facts, are not.
+func (e *Evaluator) Stats() EvaluatorStats {
+	rs := e.runtime.Stats()
+	return EvaluatorStats{
+		LoadedAt:         e.loadedAt,
+		Evaluations:      rs.Calls["evaluate"],
+		ActionSpaceCalls: rs.Calls["compute_action_space"],
+		FlowCalls:        rs.Calls["simulate_flow"] + rs.Calls["simulate_flow_with_bindings"],
+		WASMTime:         rs.WASMTime,
+		LastUsed:         rs.LastCall,
+	}
+}
+
+// RuleFiringStats evaluates each of factSets with eval and counts, per rule
+// ID, the verdicts the rule produced across them, from each verdict's
+// Provenance.Rule. Every rule in the contract has an entry, zero for rules
+// that never fired, so hot and dead rules both show up when run over a
+// representative dataset. It stops at the first fact set that fails to
+// evaluate.
+func RuleFiringStats(eval *Evaluator, factSets []FactSet) (map[string]int, error) {
+	counts := make(map[string]int, len(eval.bundle.Rules))
+	for _, rule := range eval.bundle.Rules {
+		counts[rule.ID] = 0
+	}
+	for i, facts := range factSets {
+		vs, err := eval.Evaluate(facts)
+		if err != nil {
+			return nil, fmt.Errorf("fact set %d: %w", i, err)
+		}
+		for _, v := range vs.Verdicts {
+			counts[v.Provenance.Rule]++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,121 @@
+package tenor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// VerdictStore persists verdict sets across evaluations, for contracts whose
+// verdicts are expensive to recompute. Implementations may be backed by
+// anything, such as Redis or disk; concurrency, eviction and staleness are
+// theirs to handle.
+//
+// Keys are "<bundle hash>:<facts hash>": the hex SHA-256 of the bundle JSON
+// the evaluator loaded, then FactsHash of the fact set. A loaded contract
+// never changes, so an entry stays valid for as long as any evaluator of the
+// same bundle, and evaluators of different bundles can share a store. Keys do
+// not identify the evaluator's options, however: share a store only between
+// evaluators that agree on options shaping verdicts, such as
+// WithVerdictOverlay and WithSourceLocations, or namespace the keys in the
+// implementation.
+type VerdictStore interface {
+	// Get returns the verdict set stored under key, if any.
+	Get(key string) (*VerdictSet, bool)
+	// Put stores vs under key.
+	Put(key string, vs *VerdictSet)
+}
+
+// WithVerdictStore makes Evaluate, and the methods built on it, look the fact
+// set up in s before calling into WASM, and store each verdict set it
+// computes. A hit skips the WASM call, so WithFactCoercionTrace reports nothing
+// for it. Fact sets that have no FactsHash bypass the store.
+//
+// The store receives and returns copies, so neither it nor the caller sees
+// the other's changes to a verdict set.
+func WithVerdictStore(s VerdictStore) Option {
+	return func(o *options) {
+		o.verdictStore = s
+	}
+}
+
+// FactsHash returns the part of a VerdictStore key that identifies facts: the
+// hex SHA-256 of the canonical JSON of facts (see CanonicalJSON), so fact sets that
+// differ only in map order or number representation share a key. It fails
+// for fact sets CanonicalJSON cannot encode, such as fractional numbers.
+func FactsHash(facts FactSet) (string, error) {
+	data, err := CanonicalJSON(facts)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cloneVerdictSet deep-copies vs, including payloads and provenance.
+func cloneVerdictSet(vs *VerdictSet) *VerdictSet {
+	if vs == nil {
+		return nil
+	}
+	cloned := &VerdictSet{Verdicts: make([]Verdict, len(vs.Verdicts))}
+	for i, v := range vs.Verdicts {
+		v.Payload = copyValue(v.Payload)
+		p := &v.Provenance
+		p.FactsUsed = append([]string(nil), p.FactsUsed...)
+		p.VerdictsUsed = append([]string(nil), p.VerdictsUsed...)
+		if p.FactSources != nil {
+			sources := make(map[string]string, len(p.FactSources))
+			for k, s := range p.FactSources {
+				sources[k] = s
+			}
+			p.FactSources = sources
+		}
+		cloned.Verdicts[i] = v
+	}
+	return cloned
+}
+
+// ActionSpaceFingerprint returns a key that determines the action space
+// ComputeActionSpace would return for these arguments, so callers can cache
+// action spaces and skip recomputing them when only irrelevant facts change.
+// Two calls with the same fingerprint yield the same action space.
+//
+// The fingerprint is the hex SHA-256 of the canonical JSON of persona, the
+// entity states (after WithInferInitialStates) and the facts any rule,
+// operation precondition or flow condition references. The references are
+// found statically rather than from verdict provenance: provenance names only
+// the facts behind verdicts that fired, and a change to another fact can
+// make a missing verdict fire. Declared facts no expression references
+// contribute only whether they are present; undeclared facts are ignored.
+// Like FactsHash it fails for values CanonicalJSON cannot encode.
+func (e *Evaluator) ActionSpaceFingerprint(facts FactSet, entityStates EntityStateMap, persona string) (string, error) {
+	if err := e.checkFacts(facts); err != nil {
+		return "", err
+	}
+	used := e.bundle.referencedFacts()
+	relevant := make(map[string]interface{})
+	var present []string
+	for _, f := range e.bundle.Facts {
+		v, ok := facts[f.ID]
+		switch {
+		case !ok:
+		case used[f.ID]:
+			relevant[f.ID] = v
+		default:
+			present = append(present, f.ID)
+		}
+	}
+	sort.Strings(present)
+
+	data, err := CanonicalJSON(map[string]interface{}{
+		"persona": persona,
+		"states":  e.inferStates(entityStates),
+		"facts":   relevant,
+		"present": present,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,454 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FactsFromStruct builds a FactSet from the fields of a struct (or pointer to
+// struct) tagged `tenor:"fact_id"`.
+//
+//   - `tenor:"fact_id,omitempty"` skips the field when it holds its zero value.
+//   - `tenor:"-"` and untagged fields of non-struct type are ignored.
+//   - Untagged struct fields, embedded or not, are searched for tagged fields
+//     of their own, so facts can be grouped in nested structs.
+//   - Nil pointers are skipped, leaving the fact absent so that its declared
+//     default applies; other pointers are dereferenced.
+//   - A tagged struct field becomes a Record value keyed by its fields' tenor
+//     tags (or field names when untagged); slices and arrays become Lists.
+//   - time.Time values are written in RFC 3339.
+//
+// FactsFromStruct does not know the contract's declared types. Use
+// Evaluator.FactsFromStruct to coerce values to them and reject mismatches.
+func FactsFromStruct(v interface{}) (FactSet, error) {
+	raw, err := structFacts(v)
+	if err != nil {
+		return nil, err
+	}
+	facts := make(FactSet, len(raw))
+	for id, value := range raw {
+		coerced, err := coerceFact(value, "")
+		if err != nil {
+			return nil, fmt.Errorf("fact %q: %w", id, err)
+		}
+		facts[id] = coerced
+	}
+	return facts, nil
+}
+
+// FactsFromStruct is like the package-level FactsFromStruct, but checks each
+// fact against the loaded contract: fact IDs the contract does not declare
+// are an error, and values are coerced to the declared type.
+//
+//   - Decimal facts accept Go numbers and write them as decimal strings, so a
+//     float64 0.1 becomes "0.1" rather than a binary approximation.
+//   - Int facts accept integral floats; fractional values are an error.
+//   - Date facts accept time.Time and write YYYY-MM-DD; DateTime and Text
+//     facts write time.Time in RFC 3339.
+//   - Bool, Int, Decimal, Text, Enum, Date and DateTime facts reject values of
+//     an incompatible Go kind. Other types are passed through for the
+//     evaluator to validate.
+func (e *Evaluator) FactsFromStruct(v interface{}) (FactSet, error) {
+	raw, err := structFacts(v)
+	if err != nil {
+		return nil, err
+	}
+	facts := make(FactSet, len(raw))
+	for id, value := range raw {
+		decl, ok := e.bundle.Fact(id)
+		if !ok {
+			return nil, fmt.Errorf("fact %q is not declared in the contract", id)
+		}
+		coerced, err := coerceFact(value, decl.BaseType())
+		if err != nil {
+			return nil, fmt.Errorf("fact %q (%s): %w", id, decl.BaseType(), err)
+		}
+		facts[id] = coerced
+	}
+	return facts, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structFacts collects the tagged fields of v. Values are reduced to bool,
+// int64, uint64, float64, string, time.Time, []interface{} and
+// map[string]interface{}.
+func structFacts(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("FactsFromStruct: nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("FactsFromStruct: expected a struct, got %s", rv.Type())
+	}
+	facts := make(map[string]interface{})
+	if err := collectStructFacts(rv, facts); err != nil {
+		return nil, err
+	}
+	return facts, nil
+}
+
+func collectStructFacts(rv reflect.Value, facts map[string]interface{}) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup("tenor")
+		if tag == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+
+		if !hasTag {
+			// Search untagged structs for nested facts.
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				if err := collectStructFacts(fv, facts); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		id, omitEmpty := parseTenorTag(tag)
+		if id == "" {
+			id = field.Name
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			continue
+		}
+		if _, dup := facts[id]; dup {
+			return fmt.Errorf("FactsFromStruct: fact %q is tagged more than once", id)
+		}
+		value, err := reflectValue(fv)
+		if err != nil {
+			return fmt.Errorf("FactsFromStruct: field %s (fact %q): %w", field.Name, id, err)
+		}
+		facts[id] = value
+	}
+	return nil
+}
+
+// parseTenorTag splits `name,omitempty` into its parts.
+func parseTenorTag(tag string) (name string, omitEmpty bool) {
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty
+}
+
+// reflectValue converts a Go value to its fact representation.
+func reflectValue(rv reflect.Value) (interface{}, error) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Type() == timeType {
+		return rv.Interface().(time.Time), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return []interface{}{}, nil
+		}
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			item, err := reflectValue(rv.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map keys must be strings, got %s", rv.Type().Key())
+		}
+		record := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			value, err := reflectValue(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("[%q]: %w", iter.Key().String(), err)
+			}
+			record[iter.Key().String()] = value
+		}
+		return record, nil
+	case reflect.Struct:
+		record := make(map[string]interface{}, rv.NumField())
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitEmpty := parseTenorTag(field.Tag.Get("tenor"))
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			fv := rv.Field(i)
+			if omitEmpty && fv.IsZero() {
+				continue
+			}
+			value, err := reflectValue(fv)
+			if err != nil {
+				return nil, fmt.Errorf(".%s: %w", name, err)
+			}
+			record[name] = value
+		}
+		return record, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", rv.Type())
+}
+
+// coerceFact converts a value produced by reflectValue to the JSON form the
+// evaluator expects for baseType. An empty baseType applies only the
+// type-independent conversions.
+func coerceFact(value interface{}, baseType string) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		switch baseType {
+		case "Date":
+			return v.Format("2006-01-02"), nil
+		case "", "DateTime", "Text":
+			return v.Format(time.RFC3339), nil
+		}
+		return nil, fmt.Errorf("cannot use time.Time")
+	case bool:
+		if baseType != "" && baseType != "Bool" {
+			return nil, fmt.Errorf("cannot use bool")
+		}
+		return v, nil
+	case int64, uint64, float64:
+		return coerceNumber(v, baseType)
+	case string:
+		switch baseType {
+		case "Bool", "Int":
+			return nil, fmt.Errorf("cannot use string %q", v)
+		}
+		return v, nil
+	case []interface{}:
+		if isScalarBaseType(baseType) {
+			return nil, fmt.Errorf("cannot use a list")
+		}
+		for i, item := range v {
+			coerced, err := coerceFact(item, "")
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			v[i] = coerced
+		}
+		return v, nil
+	case map[string]interface{}:
+		if isScalarBaseType(baseType) {
+			return nil, fmt.Errorf("cannot use a record")
+		}
+		for k, item := range v {
+			coerced, err := coerceFact(item, "")
+			if err != nil {
+				return nil, fmt.Errorf("[%q]: %w", k, err)
+			}
+			v[k] = coerced
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+func coerceNumber(n interface{}, baseType string) (interface{}, error) {
+	switch baseType {
+	case "", "Int", "Money", "Duration", "Record", "List", "TaggedUnion":
+	case "Decimal":
+		switch v := n.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case uint64:
+			return strconv.FormatUint(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+	default:
+		return nil, fmt.Errorf("cannot use number %v", n)
+	}
+	if f, ok := n.(float64); ok && baseType == "Int" {
+		if f != math.Trunc(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("cannot use fractional number %v", f)
+		}
+		return int64(f), nil
+	}
+	return n, nil
+}
+
+// isScalarBaseType reports whether facts of baseType hold a single value
+// rather than a List or Record.
+func isScalarBaseType(baseType string) bool {
+	switch baseType {
+	case "Bool", "Int", "Decimal", "Text", "Enum", "Date", "DateTime":
+		return true
+	}
+	return false
+}
+
+// DecodeInto populates the fields of the struct pointed to by v that are
+// tagged `tenor:"verdict_type"` from the verdicts of that type.
+//
+// The verdict's payload is unwrapped from its interchange form before
+// decoding, so a bool_value payload fills a bool field, an int_value an
+// integer field, and a text_value or enum_value a string field. Decimal
+// payloads fill string fields as written, or float fields parsed. Money,
+// Duration, Record, List and TaggedUnion payloads are decoded like JSON
+// objects and arrays into structs, maps or slices.
+//
+// A tagged field whose verdict is absent is set to its zero value. If several
+// verdicts share a type, the first is used. A payload that cannot be decoded
+// into its field's type is an error. Untagged struct fields are searched for
+// tagged fields of their own.
+func (vs *VerdictSet) DecodeInto(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeInto: expected a non-nil pointer to a struct, got %T", v)
+	}
+
+	byType := make(map[string]*Verdict, len(vs.Verdicts))
+	for i := range vs.Verdicts {
+		if _, ok := byType[vs.Verdicts[i].Type]; !ok {
+			byType[vs.Verdicts[i].Type] = &vs.Verdicts[i]
+		}
+	}
+	return decodeVerdictFields(rv.Elem(), byType)
+}
+
+func decodeVerdictFields(rv reflect.Value, byType map[string]*Verdict) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup("tenor")
+		if tag == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+
+		if !hasTag {
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				if err := decodeVerdictFields(fv, byType); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		verdictType, _ := parseTenorTag(tag)
+		if verdictType == "" {
+			verdictType = field.Name
+		}
+		verdict, ok := byType[verdictType]
+		if !ok {
+			fv.Set(reflect.Zero(fv.Type()))
+			continue
+		}
+		if err := decodePayload(verdict.Payload, fv); err != nil {
+			return fmt.Errorf("DecodeInto: verdict %q into field %s (%s): %w", verdictType, field.Name, fv.Type(), err)
+		}
+	}
+	return nil
+}
+
+// decodePayload unwraps payload and decodes it into fv.
+func decodePayload(payload interface{}, fv reflect.Value) error {
+	value := unwrapPayload(payload)
+
+	target := fv
+	for target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		target = target.Elem()
+	}
+	if s, ok := value.(string); ok && (target.Kind() == reflect.Float32 || target.Kind() == reflect.Float64) {
+		f, err := strconv.ParseFloat(s, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetFloat(f)
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, fv.Addr().Interface())
+}
+
+// unwrapPayload reduces an interchange value ({"kind": "bool_value",
+// "value": true}) to its plain form. Values without a recognised kind are
+// returned unchanged.
+func unwrapPayload(payload interface{}) interface{} {
+	obj, ok := payload.(map[string]interface{})
+	if !ok {
+		return payload
+	}
+	switch obj["kind"] {
+	case "bool_value", "int_value", "decimal_value", "text_value",
+		"date_value", "datetime_value", "enum_value":
+		return obj["value"]
+	case "money_value":
+		return map[string]interface{}{"amount": obj["amount"], "currency": obj["currency"]}
+	case "duration_value":
+		return map[string]interface{}{"value": obj["value"], "unit": obj["unit"]}
+	case "record_value":
+		fields, _ := obj["fields"].(map[string]interface{})
+		record := make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			record[k] = unwrapPayload(v)
+		}
+		return record
+	case "list_value":
+		elements, _ := obj["elements"].([]interface{})
+		list := make([]interface{}, len(elements))
+		for i, v := range elements {
+			list[i] = unwrapPayload(v)
+		}
+		return list
+	case "tagged_union_value":
+		return map[string]interface{}{"tag": obj["tag"], "payload": unwrapPayload(obj["payload"])}
+	}
+	return payload
+}
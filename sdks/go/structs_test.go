@@ -0,0 +1,153 @@
+package tenor_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+type accountFacts struct {
+	IsActive bool   `tenor:"is_active"`
+	Region   string `tenor:"region,omitempty"`
+	Limit    *int   `tenor:"limit"`
+	Ignored  string `tenor:"-"`
+	Notes    string
+	Order    orderFacts
+}
+
+type orderFacts struct {
+	Total   float64   `tenor:"order_total"`
+	Placed  time.Time `tenor:"placed_at"`
+	Address address   `tenor:"shipping_address"`
+}
+
+type address struct {
+	City string `tenor:"city"`
+	Zip  string
+}
+
+func TestFactsFromStruct(t *testing.T) {
+	placed := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	facts, err := tenor.FactsFromStruct(&accountFacts{
+		IsActive: true,
+		Ignored:  "x",
+		Notes:    "y",
+		Order: orderFacts{
+			Total:   12.5,
+			Placed:  placed,
+			Address: address{City: "Oslo", Zip: "0150"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FactsFromStruct failed: %v", err)
+	}
+
+	want := tenor.FactSet{
+		"is_active":        true,
+		"order_total":      12.5,
+		"placed_at":        "2026-03-01T09:30:00Z",
+		"shipping_address": map[string]interface{}{"city": "Oslo", "Zip": "0150"},
+	}
+	if !reflect.DeepEqual(facts, want) {
+		t.Errorf("unexpected facts\n got: %#v\nwant: %#v", facts, want)
+	}
+}
+
+func TestFactsFromStructRejectsNonStruct(t *testing.T) {
+	if _, err := tenor.FactsFromStruct(42); err == nil {
+		t.Error("expected error for a non-struct value, got nil")
+	}
+	var nilFacts *accountFacts
+	if _, err := tenor.FactsFromStruct(nilFacts); err == nil {
+		t.Error("expected error for a nil pointer, got nil")
+	}
+}
+
+func TestEvaluatorFactsFromStruct(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	type input struct {
+		IsActive bool `tenor:"is_active"`
+	}
+	facts, err := eval.FactsFromStruct(input{IsActive: true})
+	if err != nil {
+		t.Fatalf("FactsFromStruct failed: %v", err)
+	}
+	verdicts, err := eval.Evaluate(facts)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(verdicts.Verdicts) != 1 {
+		t.Errorf("expected 1 verdict, got %d", len(verdicts.Verdicts))
+	}
+
+	type mistyped struct {
+		IsActive string `tenor:"is_active"`
+	}
+	if _, err := eval.FactsFromStruct(mistyped{IsActive: "yes"}); err == nil {
+		t.Error("expected error for a string value on a Bool fact, got nil")
+	}
+
+	type undeclared struct {
+		Score int `tenor:"credit_score"`
+	}
+	if _, err := eval.FactsFromStruct(undeclared{Score: 700}); err == nil {
+		t.Error("expected error for an undeclared fact, got nil")
+	}
+}
+
+func TestVerdictSetDecodeInto(t *testing.T) {
+	vs := &tenor.VerdictSet{Verdicts: []tenor.Verdict{
+		{Type: "account_active", Payload: map[string]interface{}{"kind": "bool_value", "value": true}},
+		{Type: "risk_score", Payload: map[string]interface{}{"kind": "int_value", "value": float64(42)}},
+		{Type: "rate", Payload: map[string]interface{}{"kind": "decimal_value", "value": "0.125"}},
+		{Type: "fee", Payload: map[string]interface{}{"kind": "money_value", "amount": "10.00", "currency": "USD"}},
+	}}
+
+	type money struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	var out struct {
+		AccountActive bool    `tenor:"account_active"`
+		RiskScore     int     `tenor:"risk_score"`
+		Rate          float64 `tenor:"rate"`
+		Fee           money   `tenor:"fee"`
+		Flagged       bool    `tenor:"flagged"`
+	}
+	out.Flagged = true
+
+	if err := vs.DecodeInto(&out); err != nil {
+		t.Fatalf("DecodeInto failed: %v", err)
+	}
+	if !out.AccountActive || out.RiskScore != 42 || out.Rate != 0.125 {
+		t.Errorf("unexpected scalar fields: %+v", out)
+	}
+	if out.Fee != (money{Amount: "10.00", Currency: "USD"}) {
+		t.Errorf("unexpected fee: %+v", out.Fee)
+	}
+	if out.Flagged {
+		t.Error("expected absent verdict to reset field to its zero value")
+	}
+}
+
+func TestVerdictSetDecodeIntoTypeMismatch(t *testing.T) {
+	vs := &tenor.VerdictSet{Verdicts: []tenor.Verdict{
+		{Type: "account_active", Payload: map[string]interface{}{"kind": "bool_value", "value": true}},
+	}}
+	var out struct {
+		AccountActive int `tenor:"account_active"`
+	}
+	if err := vs.DecodeInto(&out); err == nil {
+		t.Error("expected error decoding a bool verdict into an int field, got nil")
+	}
+	if err := vs.DecodeInto(out); err == nil {
+		t.Error("expected error for a non-pointer target, got nil")
+	}
+}
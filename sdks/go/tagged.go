@@ -0,0 +1,44 @@
+package tenor
+
+// TaggedFact is a fact value together with the system it came from.
+type TaggedFact struct {
+	Value interface{}
+	// Source names the system that supplied Value, e.g. "crm" or
+	// "billing-db". It is recorded, not interpreted.
+	Source string
+}
+
+// TaggedFactSet maps fact IDs to values tagged with their source, for
+// contracts fed from several systems.
+type TaggedFactSet map[string]TaggedFact
+
+// Facts returns the untagged values.
+func (ts TaggedFactSet) Facts() FactSet {
+	facts := make(FactSet, len(ts))
+	for id, f := range ts {
+		facts[id] = f.Value
+	}
+	return facts
+}
+
+// EvaluateTagged evaluates the tagged facts like Evaluate and records, in
+// each verdict's Provenance.FactSources, the source of every fact in
+// FactsUsed, answering "which system's data drove this decision?". Facts the
+// verdict used but the caller did not supply, such as those taking a declared
+// default, have no entry. FactSources is never nil in the result.
+func (e *Evaluator) EvaluateTagged(facts TaggedFactSet) (*VerdictSet, error) {
+	verdicts, err := e.Evaluate(facts.Facts())
+	if err != nil {
+		return nil, err
+	}
+	for i := range verdicts.Verdicts {
+		p := &verdicts.Verdicts[i].Provenance
+		p.FactSources = make(map[string]string, len(p.FactsUsed))
+		for _, id := range p.FactsUsed {
+			if f, ok := facts[id]; ok {
+				p.FactSources[id] = f.Source
+			}
+		}
+	}
+	return verdicts, nil
+}
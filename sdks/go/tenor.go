@@ -28,12 +28,29 @@
 //	    tenor.EntityStateMap{"Order": "pending"},
 //	    "admin",
 //	)
+//
+// # Import cost
+//
+// Importing the package does no work at init: the embedded binary is
+// read-only data the operating system maps on demand, and it is compiled
+// only when the first evaluator is created, once per process (or once per
+// cache given to WithCompilationCache). Bundle analysis, such as ParseBundle and the Bundle
+// methods, never creates a WASM runtime, so packages that import tenor
+// conditionally pay nothing until they construct an Evaluator.
 package tenor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/riverline-labs/tenor-go/internal/wasm"
 )
@@ -46,98 +63,356 @@ import (
 type Evaluator struct {
 	runtime *wasm.Runtime
 	handle  uint32
+	bundle  *Bundle
+	// bundleHash is the hex SHA-256 of the loaded bundle JSON, prefixing
+	// VerdictStore keys.
+	bundleHash string
+	opts       options
+	// loadedAt is when the contract finished loading.
+	loadedAt time.Time
+	// profilePrefixes are the reduced contracts loaded by WithRuleProfiling.
+	profilePrefixes []profilePrefix
+	// multiInstance records that a nested method was passed several
+	// instances of one entity.
+	multiInstance atomic.Bool
 }
 
 // NewEvaluatorFromBundle creates a new Evaluator from an interchange bundle
 // JSON byte slice. The bundle must be a valid Tenor interchange bundle.
+// Options tune evaluator behaviour; with none, the defaults match the
+// evaluator's own semantics.
 //
 // Each call creates a new isolated WASM runtime instance. For applications
 // that evaluate many contracts concurrently, create one Evaluator per goroutine
 // or use a pool.
-func NewEvaluatorFromBundle(bundleJSON []byte) (*Evaluator, error) {
+func NewEvaluatorFromBundle(bundleJSON []byte, opts ...Option) (*Evaluator, error) {
+	e, _, err := newEvaluator(bundleJSON, opts)
+	return e, err
+}
+
+// newRuntime creates the WASM runtime for an evaluator. Tests replace it to
+// simulate runtime failures.
+var newRuntime = wasm.NewRuntime
+
+// newEvaluator is NewEvaluatorFromBundle, also reporting whether a failure
+// is transient: one of the environment rather than of the bundle or the
+// binary, which a later attempt may not hit.
+func newEvaluator(bundleJSON []byte, opts []Option) (e *Evaluator, transient bool, err error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// A cache is only safe to share between runtimes of the same
+	// configuration, so callers choosing their own keep compiling per
+	// evaluator unless they also pass a cache.
+	if o.cache == nil && o.wazeroConfig == nil {
+		o.cache = defaultCompilationCache()
+	}
+
 	ctx := context.Background()
-	rt, err := wasm.NewRuntime(ctx)
+	rt, err := newRuntime(ctx, o.runtimeConfig())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create WASM runtime: %w", err)
+		return nil, isTransient(err), fmt.Errorf("failed to create WASM runtime: %w", err)
 	}
 
 	result, err := rt.CallOneArg("load_contract", string(bundleJSON))
 	if err != nil {
 		_ = rt.Close()
-		return nil, fmt.Errorf("failed to call load_contract: %w", err)
+		return nil, isTransient(err), fmt.Errorf("failed to call load_contract: %w", err)
 	}
 
 	var loadResult struct {
 		Handle *uint32 `json:"handle"`
 		Error  *string `json:"error"`
 	}
-	if err := json.Unmarshal([]byte(result), &loadResult); err != nil {
+	if err := decodeResult("load_contract", "load result", result, &loadResult, false); err != nil {
 		_ = rt.Close()
-		return nil, fmt.Errorf("failed to parse load_contract result: %w", err)
+		return nil, false, err
 	}
 	if loadResult.Error != nil {
 		_ = rt.Close()
-		return nil, fmt.Errorf("contract load error: %s", *loadResult.Error)
+		return nil, false, fmt.Errorf("contract load error: %s", *loadResult.Error)
 	}
 	if loadResult.Handle == nil {
 		_ = rt.Close()
-		return nil, fmt.Errorf("load_contract returned neither handle nor error")
+		return nil, false, fmt.Errorf("load_contract returned neither handle nor error")
 	}
 
+	bundle, err := ParseBundle(bundleJSON)
+	if err != nil {
+		_ = rt.Close()
+		return nil, false, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	var prefixes []profilePrefix
+	if o.ruleProfiling {
+		if prefixes, err = loadProfilePrefixes(rt, bundle); err != nil {
+			_ = rt.Close()
+			return nil, false, fmt.Errorf("failed to load rule profiling contracts: %w", err)
+		}
+	}
+
+	sum := sha256.Sum256(bundleJSON)
 	return &Evaluator{
-		runtime: rt,
-		handle:  *loadResult.Handle,
-	}, nil
+		runtime:         rt,
+		handle:          *loadResult.Handle,
+		bundle:          bundle,
+		bundleHash:      hex.EncodeToString(sum[:]),
+		opts:            o,
+		loadedAt:        time.Now(),
+		profilePrefixes: prefixes,
+	}, false, nil
+}
+
+// ValidateBundleLoadable reports whether bundleJSON loads: it creates an
+// evaluator for it as NewEvaluatorFromBundle does and closes it at once,
+// returning only the construction error. It states validation intent in CI
+// loops over many bundles, which would otherwise hold a live runtime per
+// bundle.
+//
+// Unless opts include WithCompilationCache, the WASM module is compiled once
+// per process into the cache evaluators share by default, even under
+// WithRuntimeConfig.
+func ValidateBundleLoadable(bundleJSON []byte, opts ...Option) error {
+	opts = append([]Option{WithCompilationCache(defaultCompilationCache())}, opts...)
+	e, err := NewEvaluatorFromBundle(bundleJSON, opts...)
+	if err != nil {
+		return err
+	}
+	return e.Close()
+}
+
+// isTransient reports whether a runtime creation or load_contract failure
+// may succeed on another attempt. Incompatible binaries and configurations,
+// and failures caused by the bundle's size or depth, are deterministic;
+// other compilation, instantiation and trap failures, such as memory
+// exhaustion, depend on the environment.
+func isTransient(err error) bool {
+	for _, deterministic := range []error{
+		ErrIncompatibleWASM, ErrMissingImport, ErrIncompatibleRuntimeConfig,
+		ErrEvaluationTooComplex, ErrResultTooLarge,
+	} {
+		if errors.Is(err, deterministic) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewEvaluatorFromBundleWithRetry is NewEvaluatorFromBundle for flaky
+// environments, such as resource-constrained CI, where creating the WASM
+// runtime or loading the contract occasionally fails for want of memory. It
+// makes up to attempts attempts, waiting backoff before the second and
+// doubling the wait each time, and logs each retry to the WithLogger logger.
+//
+// Only transient failures are retried. A bundle that is not valid interchange
+// JSON, or that the evaluator rejects, fails on the first attempt, as does an
+// incompatible binary or runtime configuration. After the last attempt the
+// last error is returned.
+func NewEvaluatorFromBundleWithRetry(bundleJSON []byte, attempts int, backoff time.Duration, opts ...Option) (*Evaluator, error) {
+	// Reject an invalid bundle before touching the runtime, so that it is
+	// never retried whatever the runtime does.
+	if _, err := ParseBundle(bundleJSON); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for attempt := 1; ; attempt++ {
+		e, transient, err := newEvaluator(bundleJSON, opts)
+		if err == nil || !transient || attempt >= attempts {
+			return e, err
+		}
+		o.log(context.Background(), slog.LevelWarn, "tenor: evaluator construction failed, retrying",
+			"attempt", attempt, "attempts", attempts, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// ContractID returns the top-level id of the loaded bundle, or "" if the
+// bundle does not declare one.
+func (e *Evaluator) ContractID() string {
+	return e.bundle.ID
+}
+
+// Labels returns a copy of the labels set with WithLabel. It is never nil.
+func (e *Evaluator) Labels() map[string]string {
+	labels := make(map[string]string, len(e.opts.labels))
+	for k, v := range e.opts.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// Bundle returns the Go-side view of the loaded contract.
+func (e *Evaluator) Bundle() *Bundle {
+	return e.bundle
+}
+
+// IsDeterministic reports whether the contract's inputs are fixed, so that
+// results for a given FactSet and entity state can safely be reused.
+//
+// Evaluation itself is a pure function of its inputs. What varies is where
+// those inputs come from: a fact bound to a live source (http, database,
+// graphql, grpc or an extension protocol) may take a different value each
+// time the caller fetches it. IsDeterministic returns true only if every fact
+// is bound to a declared Source whose protocol is static or manual. Facts
+// with legacy freetext bindings say nothing about their source and make the
+// result false, as does any source the SDK cannot resolve.
+func (e *Evaluator) IsDeterministic() bool {
+	for _, f := range e.bundle.Facts {
+		src, ok := e.bundle.Source(f.SourceID())
+		if !ok {
+			return false
+		}
+		switch src.Protocol {
+		case "static", "manual":
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 // Evaluate runs stratified rule evaluation against the provided facts.
 // Returns the complete VerdictSet with provenance for each verdict.
 func (e *Evaluator) Evaluate(facts FactSet) (*VerdictSet, error) {
-	factsJSON, err := json.Marshal(facts)
+	return e.EvaluateContext(context.Background(), facts)
+}
+
+// EvaluateContext is Evaluate under ctx. If ctx is done before the WASM call
+// starts, it fails with an error wrapping ctx.Err(). If ctx is done while the
+// call runs, the call is aborted with the same error, and the WASM module is
+// closed: every later call fails, and a new Evaluator must be created.
+func (e *Evaluator) EvaluateContext(ctx context.Context, facts FactSet) (*VerdictSet, error) {
+	if err := e.checkFacts(facts); err != nil {
+		return nil, err
+	}
+
+	var key string
+	if e.opts.verdictStore != nil {
+		if hash, err := FactsHash(facts); err == nil {
+			key = e.bundleHash + ":" + hash
+			if vs, ok := e.opts.verdictStore.Get(key); ok {
+				return cloneVerdictSet(vs), nil
+			}
+		}
+	}
+
+	factsJSON, err := e.marshalFacts(facts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal facts: %w", err)
 	}
 
-	result, err := e.runtime.CallHandleOneArg("evaluate", e.handle, string(factsJSON))
+	result, err := e.runtime.CallHandleContext(ctx, "evaluate", e.handle, string(factsJSON))
 	if err != nil {
 		return nil, fmt.Errorf("evaluate WASM call failed: %w", err)
 	}
 
 	if errMsg := extractError(result); errMsg != "" {
+		if ids := extractMissingFacts(result); len(ids) > 0 {
+			return nil, &MissingFactsError{FactIDs: ids}
+		}
 		return nil, fmt.Errorf("evaluation error: %s", errMsg)
 	}
 
 	var verdicts VerdictSet
-	if err := json.Unmarshal([]byte(result), &verdicts); err != nil {
-		return nil, fmt.Errorf("failed to parse VerdictSet: %w", err)
+	if err := decodeResult("evaluate", "VerdictSet", result, &verdicts, e.opts.strictDecoding); err != nil {
+		return nil, err
 	}
+	e.overlayVerdicts(&verdicts)
+	e.describeVerdicts(verdicts.Verdicts)
 
+	if key != "" {
+		e.opts.verdictStore.Put(key, cloneVerdictSet(&verdicts))
+	}
 	return &verdicts, nil
 }
 
+// EvaluateAsOf evaluates facts as of time t by setting the fact nowFactID to
+// t before evaluating. Any value the caller supplied for nowFactID is
+// replaced; facts itself is not modified.
+//
+// nowFactID must be declared in the contract with a Date, DateTime or Text
+// type. Date facts receive t formatted as YYYY-MM-DD; DateTime and Text facts
+// receive t in RFC 3339.
+func (e *Evaluator) EvaluateAsOf(t time.Time, facts FactSet, nowFactID string) (*VerdictSet, error) {
+	decl, ok := e.bundle.Fact(nowFactID)
+	if !ok {
+		return nil, fmt.Errorf("fact %q is not declared in the contract", nowFactID)
+	}
+
+	var now string
+	switch base := decl.BaseType(); base {
+	case "Date":
+		now = t.Format("2006-01-02")
+	case "DateTime", "Text":
+		now = t.Format(time.RFC3339)
+	default:
+		return nil, fmt.Errorf("fact %q has type %s; EvaluateAsOf requires Date, DateTime or Text", nowFactID, base)
+	}
+
+	asOf := make(FactSet, len(facts)+1)
+	for id, v := range facts {
+		asOf[id] = v
+	}
+	asOf[nowFactID] = now
+
+	return e.Evaluate(asOf)
+}
+
 // ComputeActionSpace computes the set of available and blocked actions for a
 // persona given the current facts and entity states.
 //
 // entityStates maps entity IDs to their current state using the single-instance
 // (flat) format: map[entity_id]state. For multi-instance contracts, use
 // ComputeActionSpaceNested.
+//
+// It returns an error wrapping ErrNotSupported if the contract declares no
+// operations; see Bundle.Capabilities.
 func (e *Evaluator) ComputeActionSpace(
 	facts FactSet,
 	entityStates EntityStateMap,
 	persona string,
 ) (*ActionSpace, error) {
-	factsJSON, err := json.Marshal(facts)
+	return e.ComputeActionSpaceContext(context.Background(), facts, entityStates, persona)
+}
+
+// ComputeActionSpaceContext is ComputeActionSpace under ctx, which cancels
+// as it does for EvaluateContext.
+func (e *Evaluator) ComputeActionSpaceContext(
+	ctx context.Context,
+	facts FactSet,
+	entityStates EntityStateMap,
+	persona string,
+) (*ActionSpace, error) {
+	if err := e.requireOperations(); err != nil {
+		return nil, err
+	}
+	if err := e.checkFlat("ComputeActionSpace"); err != nil {
+		return nil, err
+	}
+	if err := e.checkFacts(facts); err != nil {
+		return nil, err
+	}
+
+	factsJSON, err := e.marshalFacts(facts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal facts: %w", err)
 	}
 
+	entityStates = e.inferStates(entityStates)
 	statesJSON, err := json.Marshal(entityStates)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity states: %w", err)
 	}
 
 	// compute_action_space(handle, facts_ptr, facts_len, states_ptr, states_len, persona_ptr, persona_len)
-	result, err := e.runtime.CallHandleThreeArgs(
+	result, err := e.runtime.CallHandleContext(
+		ctx,
 		"compute_action_space",
 		e.handle,
 		string(factsJSON),
@@ -148,16 +423,7 @@ func (e *Evaluator) ComputeActionSpace(
 		return nil, fmt.Errorf("compute_action_space WASM call failed: %w", err)
 	}
 
-	if errMsg := extractError(result); errMsg != "" {
-		return nil, fmt.Errorf("action space error: %s", errMsg)
-	}
-
-	var actionSpace ActionSpace
-	if err := json.Unmarshal([]byte(result), &actionSpace); err != nil {
-		return nil, fmt.Errorf("failed to parse ActionSpace: %w", err)
-	}
-
-	return &actionSpace, nil
+	return e.decodeActionSpace(result, entityStates.nested())
 }
 
 // ComputeActionSpaceNested is like ComputeActionSpace but accepts entity states
@@ -167,17 +433,38 @@ func (e *Evaluator) ComputeActionSpaceNested(
 	entityStates EntityStateMapNested,
 	persona string,
 ) (*ActionSpace, error) {
-	factsJSON, err := json.Marshal(facts)
+	return e.ComputeActionSpaceNestedContext(context.Background(), facts, entityStates, persona)
+}
+
+// ComputeActionSpaceNestedContext is ComputeActionSpaceNested under ctx,
+// which cancels as it does for EvaluateContext.
+func (e *Evaluator) ComputeActionSpaceNestedContext(
+	ctx context.Context,
+	facts FactSet,
+	entityStates EntityStateMapNested,
+	persona string,
+) (*ActionSpace, error) {
+	if err := e.requireOperations(); err != nil {
+		return nil, err
+	}
+	if err := e.checkFacts(facts); err != nil {
+		return nil, err
+	}
+
+	factsJSON, err := e.marshalFacts(facts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal facts: %w", err)
 	}
 
+	e.noteInstances(entityStates)
+	entityStates = e.inferStatesNested(entityStates)
 	statesJSON, err := json.Marshal(entityStates)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity states: %w", err)
 	}
 
-	result, err := e.runtime.CallHandleThreeArgs(
+	result, err := e.runtime.CallHandleContext(
+		ctx,
 		"compute_action_space",
 		e.handle,
 		string(factsJSON),
@@ -188,18 +475,46 @@ func (e *Evaluator) ComputeActionSpaceNested(
 		return nil, fmt.Errorf("compute_action_space WASM call failed: %w", err)
 	}
 
+	return e.decodeActionSpace(result, entityStates)
+}
+
+// decodeActionSpace turns a compute_action_space result, computed against
+// states, into an ActionSpace.
+func (e *Evaluator) decodeActionSpace(result string, states EntityStateMapNested) (*ActionSpace, error) {
 	if errMsg := extractError(result); errMsg != "" {
 		return nil, fmt.Errorf("action space error: %s", errMsg)
 	}
 
 	var actionSpace ActionSpace
-	if err := json.Unmarshal([]byte(result), &actionSpace); err != nil {
-		return nil, fmt.Errorf("failed to parse ActionSpace: %w", err)
+	if err := decodeResult("compute_action_space", "ActionSpace", result, &actionSpace, e.opts.strictDecoding); err != nil {
+		return nil, err
+	}
+	e.overlayActionSpace(&actionSpace, states)
+	e.filterAllowedFlows(&actionSpace)
+	for i := range actionSpace.BlockedActions {
+		b := &actionSpace.BlockedActions[i]
+		b.AffectedEntityIDs = e.affectedEntityIDs(b.FlowID)
 	}
 
 	return &actionSpace, nil
 }
 
+// affectedEntityIDs returns the entities the effects of flowID's entry
+// operation transition, in effect order, without repeats.
+func (e *Evaluator) affectedEntityIDs(flowID string) []string {
+	op, ok := e.bundle.Operation(e.entryOperation(flowID))
+	if !ok {
+		return nil
+	}
+	var ids []string
+	for _, effect := range op.Effects {
+		if !contains(ids, effect.EntityID) {
+			ids = append(ids, effect.EntityID)
+		}
+	}
+	return ids
+}
+
 // ExecuteFlow simulates a flow execution, returning the outcome, path,
 // entity state changes (would_transition), and current verdicts.
 //
@@ -211,11 +526,34 @@ func (e *Evaluator) ExecuteFlow(
 	entityStates EntityStateMap,
 	persona string,
 ) (*FlowResult, error) {
-	factsJSON, err := json.Marshal(facts)
+	return e.ExecuteFlowContext(context.Background(), flowID, facts, entityStates, persona)
+}
+
+// ExecuteFlowContext is ExecuteFlow under ctx, which cancels as it does for
+// EvaluateContext.
+func (e *Evaluator) ExecuteFlowContext(
+	ctx context.Context,
+	flowID string,
+	facts FactSet,
+	entityStates EntityStateMap,
+	persona string,
+) (*FlowResult, error) {
+	if err := e.checkFlowAllowed(flowID); err != nil {
+		return nil, err
+	}
+	if err := e.checkFlat("ExecuteFlow"); err != nil {
+		return nil, err
+	}
+	if err := e.checkFacts(facts); err != nil {
+		return nil, err
+	}
+
+	factsJSON, err := e.marshalFacts(facts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal facts: %w", err)
 	}
 
+	entityStates = e.inferStates(entityStates)
 	statesJSON, err := json.Marshal(entityStates)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity states: %w", err)
@@ -223,7 +561,8 @@ func (e *Evaluator) ExecuteFlow(
 
 	// simulate_flow(handle, flow_id_ptr, flow_id_len, persona_ptr, persona_len,
 	//               facts_ptr, facts_len, states_ptr, states_len)
-	result, err := e.runtime.CallHandleFourArgs(
+	result, err := e.runtime.CallHandleContext(
+		ctx,
 		"simulate_flow",
 		e.handle,
 		flowID,
@@ -236,12 +575,21 @@ func (e *Evaluator) ExecuteFlow(
 	}
 
 	if errMsg := extractError(result); errMsg != "" {
-		return nil, fmt.Errorf("flow execution error: %s", errMsg)
+		return nil, flowExecutionError(flowID, errMsg)
 	}
 
 	var flowResult FlowResult
-	if err := json.Unmarshal([]byte(result), &flowResult); err != nil {
-		return nil, fmt.Errorf("failed to parse FlowResult: %w", err)
+	if err := decodeResult("simulate_flow", "FlowResult", result, &flowResult, e.opts.strictDecoding); err != nil {
+		return nil, err
+	}
+	flowResult.flow, _ = e.bundle.Flow(flowID)
+	if err := e.checkFlowLength(flowID, &flowResult); err != nil {
+		return nil, err
+	}
+	e.describeVerdicts(flowResult.Verdicts)
+
+	if e.opts.stepStates {
+		e.bundle.replayStepStates(&flowResult, entityStates.nested())
 	}
 
 	return &flowResult, nil
@@ -259,11 +607,33 @@ func (e *Evaluator) ExecuteFlowWithBindings(
 	persona string,
 	bindings InstanceBindings,
 ) (*FlowResult, error) {
-	factsJSON, err := json.Marshal(facts)
+	return e.ExecuteFlowWithBindingsContext(context.Background(), flowID, facts, entityStates, persona, bindings)
+}
+
+// ExecuteFlowWithBindingsContext is ExecuteFlowWithBindings under ctx, which
+// cancels as it does for EvaluateContext.
+func (e *Evaluator) ExecuteFlowWithBindingsContext(
+	ctx context.Context,
+	flowID string,
+	facts FactSet,
+	entityStates EntityStateMapNested,
+	persona string,
+	bindings InstanceBindings,
+) (*FlowResult, error) {
+	if err := e.checkFlowAllowed(flowID); err != nil {
+		return nil, err
+	}
+	if err := e.checkFacts(facts); err != nil {
+		return nil, err
+	}
+
+	factsJSON, err := e.marshalFacts(facts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal facts: %w", err)
 	}
 
+	e.noteInstances(entityStates)
+	entityStates = e.inferStatesNested(entityStates)
 	statesJSON, err := json.Marshal(entityStates)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity states: %w", err)
@@ -280,7 +650,8 @@ func (e *Evaluator) ExecuteFlowWithBindings(
 	//   facts_ptr, facts_len,
 	//   states_ptr, states_len,
 	//   bindings_ptr, bindings_len)
-	result, err := e.runtime.CallHandleFiveArgs(
+	result, err := e.runtime.CallHandleContext(
+		ctx,
 		"simulate_flow_with_bindings",
 		e.handle,
 		flowID,
@@ -294,21 +665,136 @@ func (e *Evaluator) ExecuteFlowWithBindings(
 	}
 
 	if errMsg := extractError(result); errMsg != "" {
-		return nil, fmt.Errorf("flow execution error: %s", errMsg)
+		return nil, flowExecutionError(flowID, errMsg)
 	}
 
 	var flowResult FlowResult
-	if err := json.Unmarshal([]byte(result), &flowResult); err != nil {
-		return nil, fmt.Errorf("failed to parse FlowResult: %w", err)
+	if err := decodeResult("simulate_flow_with_bindings", "FlowResult", result, &flowResult, e.opts.strictDecoding); err != nil {
+		return nil, err
+	}
+	flowResult.flow, _ = e.bundle.Flow(flowID)
+	if err := e.checkFlowLength(flowID, &flowResult); err != nil {
+		return nil, err
+	}
+	e.describeVerdicts(flowResult.Verdicts)
+
+	if e.opts.stepStates {
+		e.bundle.replayStepStates(&flowResult, entityStates)
 	}
 
 	return &flowResult, nil
 }
 
 // Close releases all resources held by the Evaluator, including the WASM runtime.
-// It should be called via defer after creating an Evaluator.
+// It should be called via defer after creating an Evaluator. Calling Close more
+// than once is safe; subsequent calls are no-ops that return nil.
+//
+// The contract handles are freed in the bridge before the runtime is torn
+// down, so nothing is left behind in its handle table.
 func (e *Evaluator) Close() error {
-	return e.runtime.Close()
+	handles := make([]uint32, 0, len(e.profilePrefixes)+1)
+	for _, p := range e.profilePrefixes {
+		handles = append(handles, p.handle)
+	}
+	handles = append(handles, e.handle)
+	return e.runtime.CloseHandles("free_contract", handles...)
+}
+
+// flowExecutionError wraps a bridge flow error, recognising the bridge's own
+// step limit.
+func flowExecutionError(flowID, errMsg string) error {
+	if strings.Contains(errMsg, "exceeded maximum step count") {
+		return fmt.Errorf("flow execution error: %s: %w", errMsg,
+			&FlowTooLongError{FlowID: flowID, Limit: DefaultMaxFlowSteps})
+	}
+	return fmt.Errorf("flow execution error: %s", errMsg)
+}
+
+// checkFlowLength applies WithMaxFlowSteps to a returned flow result.
+func (e *Evaluator) checkFlowLength(flowID string, result *FlowResult) error {
+	limit := e.opts.maxFlowSteps
+	if limit <= 0 || limit >= DefaultMaxFlowSteps || len(result.Path) <= limit {
+		return nil
+	}
+	return &FlowTooLongError{
+		FlowID: flowID,
+		Limit:  limit,
+		Path:   result.Path[:limit+1],
+	}
+}
+
+// describeVerdicts copies each producing rule's description, and under
+// WithSourceLocations its source location, into the verdict's provenance.
+func (e *Evaluator) describeVerdicts(verdicts []Verdict) {
+	for i := range verdicts {
+		if rule, ok := e.bundle.Rule(verdicts[i].Provenance.Rule); ok {
+			verdicts[i].Provenance.RuleDescription = rule.Description
+			if e.opts.sourceLocations {
+				verdicts[i].Provenance.File = rule.Provenance.File
+				verdicts[i].Provenance.Line = rule.Provenance.Line
+			}
+		}
+	}
+}
+
+// checkFacts applies the configured fact validation before a WASM call.
+func (e *Evaluator) checkFacts(facts FactSet) error {
+	if !e.opts.rejectUnknownFacts {
+		return nil
+	}
+	var unknown []string
+	for id := range facts {
+		if _, ok := e.bundle.Fact(id); !ok {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &UnknownFactsError{FactIDs: unknown}
+}
+
+// checkFlowAllowed enforces WithFlowAllowList.
+func (e *Evaluator) checkFlowAllowed(flowID string) error {
+	if !e.opts.flowAllowed(flowID) {
+		return fmt.Errorf("flow %q: %w", flowID, ErrFlowNotAllowed)
+	}
+	return nil
+}
+
+// IsMultiInstance reports whether ComputeActionSpaceNested or
+// ExecuteFlowWithBindings has been passed states holding several instances of
+// one entity. A contract cannot declare an entity single- or multi-instance:
+// how many instances exist is up to the executor, so only the states it
+// passes in tell.
+func (e *Evaluator) IsMultiInstance() bool {
+	return e.multiInstance.Load()
+}
+
+// noteInstances records states passed to a nested method for IsMultiInstance.
+func (e *Evaluator) noteInstances(states EntityStateMapNested) {
+	if states.IsMultiInstance() {
+		e.multiInstance.Store(true)
+	}
+}
+
+// checkFlat applies WithRequireNestedStates to a call of the single-instance
+// method named method.
+func (e *Evaluator) checkFlat(method string) error {
+	if e.opts.requireNested && e.IsMultiInstance() {
+		return fmt.Errorf("%s: %w", method, ErrMultiInstance)
+	}
+	return nil
+}
+
+// requireOperations rejects action-space queries against contracts that have
+// no operations, where the answer would always be empty.
+func (e *Evaluator) requireOperations() error {
+	if !e.bundle.Capabilities().HasOperations {
+		return fmt.Errorf("action space requires a contract with operations: %w", ErrNotSupported)
+	}
+	return nil
 }
 
 // extractError checks if the JSON response contains an "error" field.
@@ -322,3 +808,15 @@ func extractError(result string) string {
 	}
 	return ""
 }
+
+// extractMissingFacts returns the fact IDs listed in a bridge error's
+// missing_facts field, which fact assembly errors carry.
+func extractMissingFacts(result string) []string {
+	var errResp struct {
+		MissingFacts []string `json:"missing_facts"`
+	}
+	if err := json.Unmarshal([]byte(result), &errResp); err != nil {
+		return nil
+	}
+	return errResp.MissingFacts
+}
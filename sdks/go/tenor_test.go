@@ -1,7 +1,23 @@
 package tenor_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
 
 	tenor "github.com/riverline-labs/tenor-go"
 )
@@ -83,6 +99,43 @@ const basicBundle = `{
   "tenor_version": "1.0.0"
 }`
 
+// ruleOnlyBundle is a pure rule contract: one fact and one rule, with no
+// entities, operations or flows.
+const ruleOnlyBundle = `{
+  "constructs": [
+    {
+      "id": "is_active",
+      "kind": "Fact",
+      "provenance": { "file": "rules.tenor", "line": 1 },
+      "source": { "field": "active", "system": "account" },
+      "tenor": "1.0",
+      "type": { "base": "Bool" }
+    },
+    {
+      "body": {
+        "produce": {
+          "payload": { "type": { "base": "Bool" }, "value": true },
+          "verdict_type": "account_active"
+        },
+        "when": {
+          "left": { "fact_ref": "is_active" },
+          "op": "=",
+          "right": { "literal": true, "type": { "base": "Bool" } }
+        }
+      },
+      "id": "check_active",
+      "kind": "Rule",
+      "provenance": { "file": "rules.tenor", "line": 3 },
+      "stratum": 0,
+      "tenor": "1.0"
+    }
+  ],
+  "id": "rules_only",
+  "kind": "Bundle",
+  "tenor": "1.0",
+  "tenor_version": "1.0.0"
+}`
+
 // ── Contract loading ──
 
 func TestLoadValidBundle(t *testing.T) {
@@ -97,6 +150,103 @@ func TestLoadValidBundle(t *testing.T) {
 	}()
 }
 
+func TestContractID(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	if got := eval.ContractID(); got != "entity_operation_basic" {
+		t.Errorf("expected contract id 'entity_operation_basic', got %q", got)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle),
+		tenor.WithLabel("contract", "escrow"),
+		tenor.WithLabel("tenant", "acme"),
+		tenor.WithLabel("tenant", "globex"))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	labels := eval.Labels()
+	if len(labels) != 2 || labels["contract"] != "escrow" || labels["tenant"] != "globex" {
+		t.Errorf("expected contract=escrow tenant=globex, got %v", labels)
+	}
+	labels["contract"] = "changed"
+	if eval.Labels()["contract"] != "escrow" {
+		t.Error("expected Labels to return a copy")
+	}
+}
+
+func TestWatchdogAllowsCallsWithinLimit(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithWatchdog(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	for i := 0; i < 3; i++ {
+		verdicts, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		if len(verdicts.Verdicts) != 1 {
+			t.Errorf("expected 1 verdict, got %d", len(verdicts.Verdicts))
+		}
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if err := eval.Close(); err != nil {
+		t.Fatalf("first Close failed: %v", err)
+	}
+	if err := eval.Close(); err != nil {
+		t.Errorf("second Close returned error: %v", err)
+	}
+}
+
+// stubCompiler returns a fixed bundle regardless of the source it is given.
+type stubCompiler struct {
+	bundle []byte
+	err    error
+}
+
+func (c stubCompiler) Compile(source []byte) ([]byte, error) {
+	return c.bundle, c.err
+}
+
+func TestNewEvaluatorFromSource(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromSource([]byte("fact is_active ..."), stubCompiler{bundle: []byte(basicBundle)})
+	if err != nil {
+		t.Fatalf("NewEvaluatorFromSource failed: %v", err)
+	}
+	defer eval.Close()
+
+	if got := eval.ContractID(); got != "entity_operation_basic" {
+		t.Errorf("expected compiled contract to load, got id %q", got)
+	}
+}
+
+func TestNewEvaluatorFromSourceErrors(t *testing.T) {
+	if _, err := tenor.NewEvaluatorFromSource([]byte("..."), nil); !errors.Is(err, tenor.ErrNoCompiler) {
+		t.Errorf("expected ErrNoCompiler, got %v", err)
+	}
+
+	compileErr := errors.New("syntax error at line 1")
+	_, err := tenor.NewEvaluatorFromSource([]byte("..."), stubCompiler{err: compileErr})
+	if !errors.Is(err, compileErr) {
+		t.Errorf("expected compile error to be wrapped, got %v", err)
+	}
+}
+
 func TestLoadInvalidJSON(t *testing.T) {
 	_, err := tenor.NewEvaluatorFromBundle([]byte("not json"))
 	if err == nil {
@@ -149,398 +299,2377 @@ func TestEvaluate(t *testing.T) {
 	}
 }
 
-func TestEvaluateNoVerdictWhenFalse(t *testing.T) {
-	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+func TestWithSourceLocations(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithSourceLocations())
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	result, err := eval.Evaluate(tenor.FactSet{"is_active": false})
+	result, err := eval.Evaluate(tenor.FactSet{"is_active": true})
 	if err != nil {
 		t.Fatalf("Evaluate failed: %v", err)
 	}
-	if len(result.Verdicts) != 0 {
-		t.Errorf("expected 0 verdicts when is_active=false, got %d", len(result.Verdicts))
+	if p := result.Verdicts[0].Provenance; p.File != "test.tenor" || p.Line != 16 {
+		t.Errorf("expected account_active at test.tenor:16, got %s:%d", p.File, p.Line)
 	}
 }
 
-func TestEvaluateMissingRequiredFact(t *testing.T) {
-	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
-	if err != nil {
-		t.Fatalf("failed to load: %v", err)
-	}
-	defer eval.Close()
+// memoryStore is an in-memory VerdictStore counting its calls.
+type memoryStore struct {
+	entries    map[string]*tenor.VerdictSet
+	gets, puts int
+}
 
-	_, err = eval.Evaluate(tenor.FactSet{})
-	if err == nil {
-		t.Fatal("expected error for missing required fact, got nil")
-	}
+func (m *memoryStore) Get(key string) (*tenor.VerdictSet, bool) {
+	m.gets++
+	vs, ok := m.entries[key]
+	return vs, ok
 }
 
-// ── ComputeActionSpace ──
+func (m *memoryStore) Put(key string, vs *tenor.VerdictSet) {
+	m.puts++
+	m.entries[key] = vs
+}
 
-func TestComputeActionSpaceAvailable(t *testing.T) {
-	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+func TestWithVerdictStore(t *testing.T) {
+	store := &memoryStore{entries: make(map[string]*tenor.VerdictSet)}
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithVerdictStore(store))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	space, err := eval.ComputeActionSpace(
-		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMap{"Order": "pending"},
-		"admin",
-	)
+	first, err := eval.Evaluate(tenor.FactSet{"is_active": true})
 	if err != nil {
-		t.Fatalf("ComputeActionSpace failed: %v", err)
+		t.Fatalf("Evaluate failed: %v", err)
 	}
-
-	if space.PersonaID != "admin" {
-		t.Errorf("expected persona_id 'admin', got %q", space.PersonaID)
+	if store.gets != 1 || store.puts != 1 {
+		t.Fatalf("expected a miss then a store, got %d gets and %d puts", store.gets, store.puts)
 	}
-	if len(space.Actions) != 1 {
-		t.Fatalf("expected 1 available action, got %d", len(space.Actions))
+	first.Verdicts[0].Type = "tampered"
+
+	second, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
 	}
-	if space.Actions[0].FlowID != "approval_flow" {
-		t.Errorf("expected flow_id 'approval_flow', got %q", space.Actions[0].FlowID)
+	if store.gets != 2 || store.puts != 1 {
+		t.Errorf("expected a hit, got %d gets and %d puts", store.gets, store.puts)
 	}
-	if space.Actions[0].EntryOperationID != "approve_order" {
-		t.Errorf("expected entry_operation_id 'approve_order', got %q", space.Actions[0].EntryOperationID)
+	if len(second.Verdicts) != 1 || second.Verdicts[0].Type != "account_active" {
+		t.Errorf("expected the stored account_active verdict, got %+v", second.Verdicts)
 	}
-	if len(space.BlockedActions) != 0 {
-		t.Errorf("expected 0 blocked actions, got %d", len(space.BlockedActions))
+
+	factsHash, _ := tenor.FactsHash(tenor.FactSet{"is_active": true})
+	bundleHash := sha256.Sum256([]byte(basicBundle))
+	if _, ok := store.entries[hex.EncodeToString(bundleHash[:])+":"+factsHash]; !ok || len(store.entries) != 1 {
+		t.Errorf("expected one entry keyed by bundle and facts hash, got %v", store.entries)
 	}
-}
 
-func TestComputeActionSpaceBlockedPersona(t *testing.T) {
-	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	// Another bundle sharing the store does not see the first one's entries.
+	otherBundle := strings.Replace(basicBundle, `"id": "entity_operation_basic"`, `"id": "entity_operation_other"`, 1)
+	other, err := tenor.NewEvaluatorFromBundle([]byte(otherBundle), tenor.WithVerdictStore(store))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
-	defer eval.Close()
+	defer other.Close()
+	if _, err := other.Evaluate(tenor.FactSet{"is_active": true}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if store.puts != 2 || len(store.entries) != 2 {
+		t.Errorf("expected a miss and a second entry for another bundle, got %d puts and %d entries", store.puts, len(store.entries))
+	}
+}
 
-	space, err := eval.ComputeActionSpace(
-		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMap{"Order": "pending"},
-		"guest",
-	)
+func TestFactsHash(t *testing.T) {
+	a, err := tenor.FactsHash(tenor.FactSet{"count": 1000, "is_active": true})
 	if err != nil {
-		t.Fatalf("ComputeActionSpace failed: %v", err)
+		t.Fatalf("FactsHash failed: %v", err)
 	}
-
-	if len(space.Actions) != 0 {
-		t.Errorf("expected 0 actions for guest persona, got %d", len(space.Actions))
+	b, err := tenor.FactsHash(tenor.FactSet{"is_active": true, "count": json.Number("1e3")})
+	if err != nil {
+		t.Fatalf("FactsHash failed: %v", err)
 	}
-	if len(space.BlockedActions) != 1 {
-		t.Fatalf("expected 1 blocked action, got %d", len(space.BlockedActions))
+	if a != b || len(a) != 64 {
+		t.Errorf("expected equal SHA-256 keys, got %s and %s", a, b)
 	}
-	if space.BlockedActions[0].Reason.Type != "PersonaNotAuthorized" {
-		t.Errorf("expected PersonaNotAuthorized, got %q", space.BlockedActions[0].Reason.Type)
+	c, _ := tenor.FactsHash(tenor.FactSet{"is_active": false, "count": 1000})
+	if c == a {
+		t.Error("expected different facts to hash differently")
 	}
 }
 
-func TestComputeActionSpaceBlockedPrecondition(t *testing.T) {
-	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+func TestActionSpaceFingerprint(t *testing.T) {
+	// Declare a fact no expression references.
+	bundle := strings.Replace(basicBundle, `"constructs": [`, `"constructs": [
+    {
+      "id": "note", "kind": "Fact", "provenance": { "file": "test.tenor", "line": 9 },
+      "tenor": "1.0", "type": { "base": "Text" }
+    },`, 1)
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(bundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	space, err := eval.ComputeActionSpace(
-		tenor.FactSet{"is_active": false},
-		tenor.EntityStateMap{"Order": "pending"},
-		"admin",
-	)
-	if err != nil {
-		t.Fatalf("ComputeActionSpace failed: %v", err)
-	}
-
-	if len(space.Actions) != 0 {
-		t.Errorf("expected 0 actions when precondition not met, got %d", len(space.Actions))
-	}
-	if len(space.BlockedActions) != 1 {
-		t.Fatalf("expected 1 blocked action, got %d", len(space.BlockedActions))
+	states := tenor.EntityStateMap{"Order": "pending"}
+	fingerprint := func(facts tenor.FactSet, persona string) string {
+		t.Helper()
+		fp, err := eval.ActionSpaceFingerprint(facts, states, persona)
+		if err != nil {
+			t.Fatalf("ActionSpaceFingerprint failed: %v", err)
+		}
+		return fp
 	}
-	if space.BlockedActions[0].Reason.Type != "PreconditionNotMet" {
-		t.Errorf("expected PreconditionNotMet, got %q", space.BlockedActions[0].Reason.Type)
+	base := fingerprint(tenor.FactSet{"is_active": true, "note": "a"}, "admin")
+	if got := fingerprint(tenor.FactSet{"is_active": true, "note": "b", "undeclared": 1}, "admin"); got != base {
+		t.Error("expected changing an unused fact to keep the fingerprint")
 	}
-	found := false
-	for _, v := range space.BlockedActions[0].Reason.MissingVerdicts {
-		if v == "account_active" {
-			found = true
-			break
-		}
+	if got := fingerprint(tenor.FactSet{"is_active": false, "note": "a"}, "admin"); got == base {
+		t.Error("expected changing a used fact to change the fingerprint")
 	}
-	if !found {
-		t.Errorf("expected missing_verdicts to include 'account_active', got %v",
-			space.BlockedActions[0].Reason.MissingVerdicts)
+	if got := fingerprint(tenor.FactSet{"is_active": true, "note": "a"}, "guest"); got == base {
+		t.Error("expected another persona to change the fingerprint")
 	}
 }
 
-func TestComputeActionSpaceBlockedEntityState(t *testing.T) {
+func TestEvaluateNoVerdictWhenFalse(t *testing.T) {
 	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	space, err := eval.ComputeActionSpace(
-		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMap{"Order": "approved"},
-		"admin",
-	)
+	result, err := eval.Evaluate(tenor.FactSet{"is_active": false})
 	if err != nil {
-		t.Fatalf("ComputeActionSpace failed: %v", err)
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(result.Verdicts) != 0 {
+		t.Errorf("expected 0 verdicts when is_active=false, got %d", len(result.Verdicts))
+	}
+}
+
+func TestEvaluateMissingRequiredFact(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
 	}
+	defer eval.Close()
 
-	if len(space.Actions) != 0 {
-		t.Errorf("expected 0 actions when entity in wrong state, got %d", len(space.Actions))
+	_, err = eval.Evaluate(tenor.FactSet{})
+	if err == nil {
+		t.Fatal("expected error for missing required fact, got nil")
 	}
-	if len(space.BlockedActions) != 1 {
-		t.Fatalf("expected 1 blocked action, got %d", len(space.BlockedActions))
+	var missing *tenor.MissingFactsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("expected *MissingFactsError, got %T: %v", err, err)
 	}
-	if space.BlockedActions[0].Reason.Type != "EntityNotInSourceState" {
-		t.Errorf("expected EntityNotInSourceState, got %q", space.BlockedActions[0].Reason.Type)
+	if len(missing.FactIDs) != 1 || missing.FactIDs[0] != "is_active" {
+		t.Errorf("expected missing fact is_active, got %v", missing.FactIDs)
 	}
 }
 
-func TestComputeActionSpaceCurrentVerdicts(t *testing.T) {
+func TestEvaluateDelta(t *testing.T) {
 	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	space, err := eval.ComputeActionSpace(
-		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMap{},
-		"admin",
-	)
+	baseline, diff, err := eval.EvaluateDelta(nil, tenor.FactSet{"is_active": true})
 	if err != nil {
-		t.Fatalf("ComputeActionSpace failed: %v", err)
+		t.Fatalf("EvaluateDelta failed: %v", err)
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"account_active"}) {
+		t.Errorf("expected account_active added against a nil baseline, got %+v", diff)
 	}
 
-	if len(space.CurrentVerdicts) != 1 {
-		t.Fatalf("expected 1 current verdict, got %d", len(space.CurrentVerdicts))
+	next, diff, err := eval.EvaluateDelta(baseline, tenor.FactSet{"is_active": false})
+	if err != nil {
+		t.Fatalf("EvaluateDelta failed: %v", err)
 	}
-	if space.CurrentVerdicts[0].VerdictType != "account_active" {
-		t.Errorf("expected verdict_type 'account_active', got %q", space.CurrentVerdicts[0].VerdictType)
+	if len(next.Verdicts) != 0 {
+		t.Errorf("expected the new full set to be empty, got %+v", next.Verdicts)
 	}
-	if space.CurrentVerdicts[0].ProducingRule != "check_active" {
-		t.Errorf("expected producing_rule 'check_active', got %q", space.CurrentVerdicts[0].ProducingRule)
+	want := tenor.VerdictDiff{Removed: []string{"account_active"}}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("expected %+v, got %+v", want, diff)
 	}
 }
 
-// ── ExecuteFlow ──
-
-func TestExecuteFlowSuccess(t *testing.T) {
-	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+func TestEvaluateJSONRejectDuplicateKeys(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithRejectDuplicateKeys())
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	result, err := eval.ExecuteFlow(
-		"approval_flow",
-		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMap{},
-		"admin",
-	)
+	result, err := eval.EvaluateJSON([]byte(`{"is_active": true}`))
 	if err != nil {
-		t.Fatalf("ExecuteFlow failed: %v", err)
-	}
-
-	if !result.Simulation {
-		t.Error("expected simulation=true")
+		t.Fatalf("EvaluateJSON failed: %v", err)
 	}
-	if result.FlowID != "approval_flow" {
-		t.Errorf("expected flow_id 'approval_flow', got %q", result.FlowID)
+	if len(result.Verdicts) != 1 {
+		t.Errorf("expected 1 verdict, got %d", len(result.Verdicts))
 	}
-	if result.Outcome != "order_approved" {
-		t.Errorf("expected outcome 'order_approved', got %q", result.Outcome)
+
+	for input, path := range map[string]string{
+		`{"is_active": false, "is_active": true}`:                             "is_active",
+		`{"is_active": true, "order": {"items": [{}, {"sku": 1, "sku": 2}]}}`: "order.items[1].sku",
+	} {
+		_, err := eval.EvaluateJSON([]byte(input))
+		var dup *tenor.DuplicateFactKeyError
+		if !errors.Is(err, tenor.ErrDuplicateFactKey) || !errors.As(err, &dup) || dup.Path != path {
+			t.Errorf("%s: expected a duplicate key at %q, got %v", input, path, err)
+		}
 	}
-	if len(result.Path) == 0 {
-		t.Error("expected non-empty path")
+
+	// Without the option the last value wins, as with encoding/json.
+	lenient, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
 	}
-	if len(result.WouldTransition) == 0 {
-		t.Error("expected non-empty would_transition")
+	defer lenient.Close()
+	result, err = lenient.EvaluateJSON([]byte(`{"is_active": false, "is_active": true}`))
+	if err != nil || len(result.Verdicts) != 1 {
+		t.Errorf("expected the last is_active to win, got %v, %v", result, err)
 	}
 }
 
-func TestExecuteFlowPreconditionFails(t *testing.T) {
+func TestSession(t *testing.T) {
 	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	result, err := eval.ExecuteFlow(
-		"approval_flow",
-		tenor.FactSet{"is_active": false},
-		tenor.EntityStateMap{},
-		"admin",
-	)
+	initial := tenor.FactSet{"is_active": false}
+	session := eval.NewSession(initial)
+	initial["is_active"] = true // the session holds its own copy
+
+	steps := []struct {
+		partial tenor.FactSet
+		want    tenor.VerdictDiff
+	}{
+		{tenor.FactSet{}, tenor.VerdictDiff{}},
+		{tenor.FactSet{"is_active": true}, tenor.VerdictDiff{Added: []string{"account_active"}}},
+		{tenor.FactSet{"is_active": true}, tenor.VerdictDiff{}},
+		{tenor.FactSet{"is_active": false}, tenor.VerdictDiff{Removed: []string{"account_active"}}},
+	}
+	for i, step := range steps {
+		_, diff, err := session.Update(step.partial)
+		if err != nil {
+			t.Fatalf("update %d failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(diff, step.want) {
+			t.Errorf("update %d: expected %+v, got %+v", i, step.want, diff)
+		}
+	}
+
+	// Removing the required fact fails and leaves the session as it was.
+	if _, _, err := session.Update(tenor.FactSet{"is_active": nil}); err == nil {
+		t.Fatal("expected an error without is_active")
+	}
+	if facts := session.Facts(); facts["is_active"] != false {
+		t.Errorf("expected the failed update to leave is_active false, got %v", facts)
+	}
+	if vs := session.Verdicts(); vs == nil || len(vs.Verdicts) != 0 {
+		t.Errorf("expected the last verdict set to be kept, got %+v", vs)
+	}
+}
+
+func TestEnumerateFlowOutcomes(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
-		t.Fatalf("ExecuteFlow failed: %v", err)
+		t.Fatalf("failed to load: %v", err)
 	}
+	defer eval.Close()
 
-	if result.Outcome != "approval_failed" {
-		t.Errorf("expected outcome 'approval_failed', got %q", result.Outcome)
+	// channel is not declared and does not affect the flow, so each outcome
+	// is reached by two combinations.
+	outcomes, err := eval.EnumerateFlowOutcomes("approval_flow", "admin", map[string][]interface{}{
+		"is_active": {true, false},
+		"channel":   {"web", "api"},
+	})
+	if err != nil {
+		t.Fatalf("EnumerateFlowOutcomes failed: %v", err)
+	}
+	if len(outcomes) != 2 {
+		t.Fatalf("expected 2 outcomes, got %+v", outcomes)
+	}
+	failed, approved := outcomes[0], outcomes[1]
+	if failed.Outcome != "approval_failed" || len(failed.Transitions) != 0 || failed.Count != 2 || failed.Example["is_active"] != false {
+		t.Errorf("unexpected failure outcome %+v", failed)
+	}
+	if approved.Outcome != "order_approved" || approved.Count != 2 || approved.Example["is_active"] != true {
+		t.Errorf("unexpected success outcome %+v", approved)
+	}
+	want := []tenor.EntityStateChange{{EntityID: "Order", InstanceID: tenor.DefaultInstanceID, FromState: "pending", ToState: "approved"}}
+	if !reflect.DeepEqual(approved.Transitions, want) {
+		t.Errorf("expected transitions %+v, got %+v", want, approved.Transitions)
 	}
 }
 
-func TestExecuteFlowNotFound(t *testing.T) {
+func TestEvaluatePipe(t *testing.T) {
 	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	_, err = eval.ExecuteFlow(
-		"nonexistent_flow",
-		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMap{},
-		"admin",
-	)
-	if err == nil {
-		t.Fatal("expected error for nonexistent flow, got nil")
+	in := make(chan tenor.FactSet)
+	out := make(chan tenor.EvalResult)
+	go eval.EvaluatePipe(context.Background(), in, out)
+	go func() {
+		in <- tenor.FactSet{"is_active": true}
+		in <- tenor.FactSet{}
+		in <- tenor.FactSet{"is_active": false}
+		close(in)
+	}()
+
+	var results []tenor.EvalResult
+	for r := range out {
+		results = append(results, r)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Err != nil || len(results[0].VerdictSet.Verdicts) != 1 {
+		t.Errorf("expected 1 verdict for is_active=true, got %+v", results[0])
+	}
+	if results[1].Err == nil {
+		t.Error("expected an error for the missing required fact")
+	}
+	if results[2].Err != nil || len(results[2].VerdictSet.Verdicts) != 0 {
+		t.Errorf("expected 0 verdicts for is_active=false, got %+v", results[2])
 	}
 }
 
-// ── Results match Rust evaluator ──
+func TestEvaluatePipeCancel(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
 
-// TestResultsMatchRustEvaluator verifies that the Go SDK produces identical
-// results to the Rust evaluator for the same inputs. We compare the key
-// fields that the Rust tests also verify (type, rule, stratum).
-func TestResultsMatchRustEvaluator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan tenor.EvalResult)
+	go eval.EvaluatePipe(ctx, make(chan tenor.FactSet), out)
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Error("expected no results after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected out to be closed after cancellation")
+	}
+}
+
+func TestCorrelationIDInLogs(t *testing.T) {
+	var logs bytes.Buffer
+	handler := slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithLogger(slog.New(handler)))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	ctx, cancel := context.WithCancel(tenor.ContextWithCorrelationID(context.Background(), "req-42"))
+	if id, ok := tenor.CorrelationIDFromContext(ctx); !ok || id != "req-42" {
+		t.Fatalf("expected req-42 in the context, got %q", id)
+	}
+	out := make(chan tenor.EvalResult)
+	cancel()
+	eval.EvaluatePipe(ctx, make(chan tenor.FactSet), out)
+
+	if !strings.Contains(logs.String(), "evaluate pipe cancelled") || !strings.Contains(logs.String(), "correlation_id=req-42") {
+		t.Errorf("expected a cancellation record with the correlation ID, got %q", logs.String())
+	}
+	if _, ok := tenor.CorrelationIDFromContext(context.Background()); ok {
+		t.Error("expected no correlation ID by default")
+	}
+}
+
+func TestLabelsInLogs(t *testing.T) {
+	var logs bytes.Buffer
+	logger := tenor.WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	restore := tenor.FailRuntimeCreations(1)
+	defer restore()
+	_, _ = tenor.NewEvaluatorFromBundleWithRetry([]byte(basicBundle), 2, time.Millisecond, logger,
+		tenor.WithLabel("tenant", "acme"), tenor.WithLabel("contract", "escrow"))
+	if !strings.Contains(logs.String(), "retrying") || !strings.Contains(logs.String(), "contract=escrow tenant=acme") {
+		t.Errorf("expected a retry record with the sorted labels, got %q", logs.String())
+	}
+}
+
+// ── ComputeActionSpace ──
+
+func TestActionSpaceDeltaForOperation(t *testing.T) {
 	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	// Test 1: evaluate(is_active=true) -> account_active verdict from check_active at stratum 0
-	verdicts, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+	facts := tenor.FactSet{"is_active": true}
+	diff, err := eval.ActionSpaceDeltaForOperation("approve_order", facts, tenor.EntityStateMap{"Order": "pending"}, "admin")
 	if err != nil {
-		t.Fatalf("Evaluate failed: %v", err)
+		t.Fatalf("ActionSpaceDeltaForOperation failed: %v", err)
 	}
-	if len(verdicts.Verdicts) != 1 || verdicts.Verdicts[0].Type != "account_active" {
-		t.Errorf("Rust evaluator produces account_active from is_active=true; Go got: %+v", verdicts.Verdicts)
+	want := &tenor.ActionSpaceDiff{Disabled: []string{"approval_flow"}}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("expected %+v, got %+v", want, diff)
 	}
-	if verdicts.Verdicts[0].Provenance.Rule != "check_active" {
-		t.Errorf("Rust evaluator: rule=check_active; Go got: %q", verdicts.Verdicts[0].Provenance.Rule)
+
+	_, err = eval.ActionSpaceDeltaForOperation("approve_order", facts, tenor.EntityStateMap{"Order": "approved"}, "admin")
+	if !errors.Is(err, tenor.ErrStaleState) {
+		t.Errorf("expected ErrStaleState for an approved order, got %v", err)
 	}
-	if verdicts.Verdicts[0].Provenance.Stratum != 0 {
-		t.Errorf("Rust evaluator: stratum=0; Go got: %d", verdicts.Verdicts[0].Provenance.Stratum)
+	if _, err := eval.ActionSpaceDeltaForOperation("approve_order", facts, tenor.EntityStateMap{"Order": "pending"}, "guest"); err == nil {
+		t.Error("expected an error for a persona the operation does not allow")
 	}
+}
 
-	// Test 2: simulate_flow(approval_flow, is_active=true) -> order_approved
-	flowResult, err := eval.ExecuteFlow(
-		"approval_flow",
+func TestComputeActionSpaceAvailable(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	space, err := eval.ComputeActionSpace(
 		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMap{},
+		tenor.EntityStateMap{"Order": "pending"},
 		"admin",
 	)
 	if err != nil {
-		t.Fatalf("ExecuteFlow failed: %v", err)
+		t.Fatalf("ComputeActionSpace failed: %v", err)
 	}
-	if flowResult.Outcome != "order_approved" {
-		t.Errorf("Rust evaluator: outcome=order_approved; Go got: %q", flowResult.Outcome)
+
+	if space.PersonaID != "admin" {
+		t.Errorf("expected persona_id 'admin', got %q", space.PersonaID)
 	}
-	if len(flowResult.WouldTransition) == 0 {
-		t.Error("Rust evaluator: would_transition non-empty; Go got empty")
-	} else {
-		wt := flowResult.WouldTransition[0]
-		if wt.EntityID != "Order" || wt.FromState != "pending" || wt.ToState != "approved" {
-			t.Errorf("Rust evaluator: Order pending->approved; Go got: %+v", wt)
-		}
+	if len(space.Actions) != 1 {
+		t.Fatalf("expected 1 available action, got %d", len(space.Actions))
+	}
+	if space.Actions[0].FlowID != "approval_flow" {
+		t.Errorf("expected flow_id 'approval_flow', got %q", space.Actions[0].FlowID)
+	}
+	if space.Actions[0].EntryOperationID != "approve_order" {
+		t.Errorf("expected entry_operation_id 'approve_order', got %q", space.Actions[0].EntryOperationID)
+	}
+	if len(space.BlockedActions) != 0 {
+		t.Errorf("expected 0 blocked actions, got %d", len(space.BlockedActions))
 	}
+}
+
+func TestComputeActionSpaceBlockedPersona(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
 
-	// Test 3: compute_action_space(is_active=true, Order=pending, admin) -> 1 action
 	space, err := eval.ComputeActionSpace(
 		tenor.FactSet{"is_active": true},
 		tenor.EntityStateMap{"Order": "pending"},
-		"admin",
+		"guest",
 	)
 	if err != nil {
 		t.Fatalf("ComputeActionSpace failed: %v", err)
 	}
-	if len(space.Actions) != 1 || space.Actions[0].FlowID != "approval_flow" {
-		t.Errorf("Rust evaluator: 1 action (approval_flow); Go got: %d actions", len(space.Actions))
+
+	if len(space.Actions) != 0 {
+		t.Errorf("expected 0 actions for guest persona, got %d", len(space.Actions))
+	}
+	if len(space.BlockedActions) != 1 {
+		t.Fatalf("expected 1 blocked action, got %d", len(space.BlockedActions))
+	}
+	if space.BlockedActions[0].Reason.Type != "PersonaNotAuthorized" {
+		t.Errorf("expected PersonaNotAuthorized, got %q", space.BlockedActions[0].Reason.Type)
 	}
-}
 
-// ── Multi-instance ──
+	// The blocked flow still shows in the Order view through its entry
+	// operation's effects.
+	if ids := space.BlockedActions[0].AffectedEntityIDs; !reflect.DeepEqual(ids, []string{"Order"}) {
+		t.Errorf("expected approval_flow to affect Order, got %v", ids)
+	}
+	if order := space.ForEntity("Order"); len(order.BlockedActions) != 1 || order.BlockedActions[0].FlowID != "approval_flow" {
+		t.Errorf("expected the persona-blocked approval_flow in the Order view, got %+v", order.BlockedActions)
+	}
+}
 
-func TestComputeActionSpaceNestedFormatAvailable(t *testing.T) {
+func TestComputeActionSpaceBlockedPrecondition(t *testing.T) {
 	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	space, err := eval.ComputeActionSpaceNested(
-		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMapNested{"Order": {"ord-001": "pending"}},
+	space, err := eval.ComputeActionSpace(
+		tenor.FactSet{"is_active": false},
+		tenor.EntityStateMap{"Order": "pending"},
 		"admin",
 	)
 	if err != nil {
-		t.Fatalf("ComputeActionSpaceNested failed: %v", err)
+		t.Fatalf("ComputeActionSpace failed: %v", err)
 	}
 
-	if len(space.Actions) != 1 {
-		t.Fatalf("expected 1 action, got %d", len(space.Actions))
+	if len(space.Actions) != 0 {
+		t.Errorf("expected 0 actions when precondition not met, got %d", len(space.Actions))
 	}
-	// instance_bindings should include ord-001
-	orderBindings, ok := space.Actions[0].InstanceBindings["Order"]
-	if !ok {
-		t.Fatal("expected Order in instance_bindings")
+	if len(space.BlockedActions) != 1 {
+		t.Fatalf("expected 1 blocked action, got %d", len(space.BlockedActions))
+	}
+	if space.BlockedActions[0].Reason.Type != "PreconditionNotMet" {
+		t.Errorf("expected PreconditionNotMet, got %q", space.BlockedActions[0].Reason.Type)
 	}
 	found := false
-	for _, iid := range orderBindings {
-		if iid == "ord-001" {
+	for _, v := range space.BlockedActions[0].Reason.MissingVerdicts {
+		if v == "account_active" {
 			found = true
 			break
 		}
 	}
 	if !found {
-		t.Errorf("expected 'ord-001' in instance_bindings['Order'], got %v", orderBindings)
+		t.Errorf("expected missing_verdicts to include 'account_active', got %v",
+			space.BlockedActions[0].Reason.MissingVerdicts)
 	}
 }
 
-func TestExecuteFlowWithBindings(t *testing.T) {
+func TestComputeActionSpaceBlockedEntityState(t *testing.T) {
 	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
 	if err != nil {
 		t.Fatalf("failed to load: %v", err)
 	}
 	defer eval.Close()
 
-	result, err := eval.ExecuteFlowWithBindings(
+	space, err := eval.ComputeActionSpace(
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{"Order": "approved"},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+
+	if len(space.Actions) != 0 {
+		t.Errorf("expected 0 actions when entity in wrong state, got %d", len(space.Actions))
+	}
+	if len(space.BlockedActions) != 1 {
+		t.Fatalf("expected 1 blocked action, got %d", len(space.BlockedActions))
+	}
+	if space.BlockedActions[0].Reason.Type != "EntityNotInSourceState" {
+		t.Errorf("expected EntityNotInSourceState, got %q", space.BlockedActions[0].Reason.Type)
+	}
+}
+
+func TestComputeActionSpaceCurrentVerdicts(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	space, err := eval.ComputeActionSpace(
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+
+	if len(space.CurrentVerdicts) != 1 {
+		t.Fatalf("expected 1 current verdict, got %d", len(space.CurrentVerdicts))
+	}
+	if space.CurrentVerdicts[0].VerdictType != "account_active" {
+		t.Errorf("expected verdict_type 'account_active', got %q", space.CurrentVerdicts[0].VerdictType)
+	}
+	if space.CurrentVerdicts[0].ProducingRule != "check_active" {
+		t.Errorf("expected producing_rule 'check_active', got %q", space.CurrentVerdicts[0].ProducingRule)
+	}
+}
+
+// ── ExecuteFlow ──
+
+func TestExecuteFlowSuccess(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	result, err := eval.ExecuteFlow(
 		"approval_flow",
 		tenor.FactSet{"is_active": true},
-		tenor.EntityStateMapNested{"Order": {"ord-001": "pending"}},
+		tenor.EntityStateMap{},
 		"admin",
-		tenor.InstanceBindings{"Order": "ord-001"},
 	)
 	if err != nil {
-		t.Fatalf("ExecuteFlowWithBindings failed: %v", err)
+		t.Fatalf("ExecuteFlow failed: %v", err)
 	}
 
+	if !result.Simulation {
+		t.Error("expected simulation=true")
+	}
+	if result.FlowID != "approval_flow" {
+		t.Errorf("expected flow_id 'approval_flow', got %q", result.FlowID)
+	}
 	if result.Outcome != "order_approved" {
 		t.Errorf("expected outcome 'order_approved', got %q", result.Outcome)
 	}
+	if len(result.Path) == 0 {
+		t.Error("expected non-empty path")
+	}
 	if len(result.WouldTransition) == 0 {
-		t.Fatal("expected non-empty would_transition")
+		t.Error("expected non-empty would_transition")
+	}
+}
+
+func TestExecuteFlowPreconditionFails(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	result, err := eval.ExecuteFlow(
+		"approval_flow",
+		tenor.FactSet{"is_active": false},
+		tenor.EntityStateMap{},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ExecuteFlow failed: %v", err)
+	}
+
+	if result.Outcome != "approval_failed" {
+		t.Errorf("expected outcome 'approval_failed', got %q", result.Outcome)
+	}
+}
+
+func TestExecuteFlowNotFound(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	_, err = eval.ExecuteFlow(
+		"nonexistent_flow",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{},
+		"admin",
+	)
+	if err == nil {
+		t.Fatal("expected error for nonexistent flow, got nil")
+	}
+}
+
+// ── Flow step limits ──
+
+// withBranchAndHandoff replaces approval_flow's steps with a branch on
+// account_active, a handoff to next, and the approve step.
+func withBranchAndHandoff(next string) string {
+	steps := `{
+          "condition": { "verdict_present": "account_active" },
+          "id": "step_check",
+          "if_false": { "kind": "Terminal", "outcome": "inactive" },
+          "if_true": "step_handoff",
+          "kind": "BranchStep",
+          "persona": "admin"
+        },
+        {
+          "from_persona": "admin",
+          "id": "step_handoff",
+          "kind": "HandoffStep",
+          "next": "` + next + `",
+          "to_persona": "admin"
+        },
+`
+	bundle := strings.Replace(basicBundle, `"entry": "step_approve"`, `"entry": "step_check"`, 1)
+	return strings.Replace(bundle, `"steps": [
+        {
+          "id": "step_approve"`, `"steps": [
+        `+steps+`        {
+          "id": "step_approve"`, 1)
+}
+
+func TestMaxFlowSteps(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(withBranchAndHandoff("step_approve")), tenor.WithMaxFlowSteps(2))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	_, err = eval.ExecuteFlow("approval_flow", tenor.FactSet{"is_active": true}, tenor.EntityStateMap{}, "admin")
+	var tooLong *tenor.FlowTooLongError
+	if !errors.As(err, &tooLong) || !errors.Is(err, tenor.ErrFlowTooLong) {
+		t.Fatalf("expected FlowTooLongError, got %v", err)
+	}
+	if tooLong.Limit != 2 || len(tooLong.Path) != 3 {
+		t.Errorf("expected limit 2 with a 3-step partial path, got %d with %+v", tooLong.Limit, tooLong.Path)
+	}
+
+	// The branch short-circuits to a terminal within the limit.
+	if _, err := eval.ExecuteFlow("approval_flow", tenor.FactSet{"is_active": false}, tenor.EntityStateMap{}, "admin"); err != nil {
+		t.Errorf("expected a 1-step flow within the limit, got %v", err)
+	}
+}
+
+func TestMaxFlowStepsCycle(t *testing.T) {
+	// The handoff loops back to the branch, so the flow never terminates.
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(withBranchAndHandoff("step_check")))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	_, err = eval.ExecuteFlow("approval_flow", tenor.FactSet{"is_active": true}, tenor.EntityStateMap{}, "admin")
+	if !errors.Is(err, tenor.ErrFlowTooLong) {
+		t.Errorf("expected ErrFlowTooLong for a cyclic flow, got %v", err)
+	}
+}
+
+// ── Results match Rust evaluator ──
+
+// TestResultsMatchRustEvaluator verifies that the Go SDK produces identical
+// results to the Rust evaluator for the same inputs. We compare the key
+// fields that the Rust tests also verify (type, rule, stratum).
+func TestResultsMatchRustEvaluator(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	// Test 1: evaluate(is_active=true) -> account_active verdict from check_active at stratum 0
+	verdicts, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(verdicts.Verdicts) != 1 || verdicts.Verdicts[0].Type != "account_active" {
+		t.Errorf("Rust evaluator produces account_active from is_active=true; Go got: %+v", verdicts.Verdicts)
+	}
+	if verdicts.Verdicts[0].Provenance.Rule != "check_active" {
+		t.Errorf("Rust evaluator: rule=check_active; Go got: %q", verdicts.Verdicts[0].Provenance.Rule)
+	}
+	if verdicts.Verdicts[0].Provenance.Stratum != 0 {
+		t.Errorf("Rust evaluator: stratum=0; Go got: %d", verdicts.Verdicts[0].Provenance.Stratum)
+	}
+
+	// Test 2: simulate_flow(approval_flow, is_active=true) -> order_approved
+	flowResult, err := eval.ExecuteFlow(
+		"approval_flow",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ExecuteFlow failed: %v", err)
+	}
+	if flowResult.Outcome != "order_approved" {
+		t.Errorf("Rust evaluator: outcome=order_approved; Go got: %q", flowResult.Outcome)
+	}
+	if len(flowResult.WouldTransition) == 0 {
+		t.Error("Rust evaluator: would_transition non-empty; Go got empty")
+	} else {
+		wt := flowResult.WouldTransition[0]
+		if wt.EntityID != "Order" || wt.FromState != "pending" || wt.ToState != "approved" {
+			t.Errorf("Rust evaluator: Order pending->approved; Go got: %+v", wt)
+		}
+	}
+
+	// Test 3: compute_action_space(is_active=true, Order=pending, admin) -> 1 action
+	space, err := eval.ComputeActionSpace(
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{"Order": "pending"},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(space.Actions) != 1 || space.Actions[0].FlowID != "approval_flow" {
+		t.Errorf("Rust evaluator: 1 action (approval_flow); Go got: %d actions", len(space.Actions))
+	}
+}
+
+// ── Multi-instance ──
+
+func TestComputeActionSpaceNestedFormatAvailable(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	space, err := eval.ComputeActionSpaceNested(
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMapNested{"Order": {"ord-001": "pending"}},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpaceNested failed: %v", err)
+	}
+
+	if len(space.Actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(space.Actions))
+	}
+	// instance_bindings should include ord-001
+	orderBindings, ok := space.Actions[0].InstanceBindings["Order"]
+	if !ok {
+		t.Fatal("expected Order in instance_bindings")
+	}
+	found := false
+	for _, iid := range orderBindings {
+		if iid == "ord-001" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("expected 'ord-001' in instance_bindings['Order'], got %v", orderBindings)
+	}
+}
+
+func TestComputeActionSpaceBatch(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	spaces, err := eval.ComputeActionSpaceBatch(
+		tenor.FactSet{"is_active": true},
+		[]tenor.EntityStateMapNested{
+			{"Order": {"ord-001": "pending"}},
+			{"Order": {"ord-002": "approved"}},
+		},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpaceBatch failed: %v", err)
+	}
+	if len(spaces) != 2 {
+		t.Fatalf("expected 2 action spaces, got %d", len(spaces))
+	}
+	if len(spaces[0].Actions) != 1 {
+		t.Errorf("expected 1 action for the pending order, got %d", len(spaces[0].Actions))
+	}
+	if len(spaces[1].Actions) != 0 {
+		t.Errorf("expected 0 actions for the approved order, got %d", len(spaces[1].Actions))
+	}
+
+	// Missing facts fail each item; failures are reported per item.
+	spaces, err = eval.ComputeActionSpaceBatch(tenor.FactSet{}, []tenor.EntityStateMapNested{{}, {}}, "admin")
+	var batchErr *tenor.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected *BatchError, got %v", err)
+	}
+	if len(batchErr.Errors) != 2 || batchErr.Errors[0] == nil || spaces[0] != nil {
+		t.Errorf("expected aligned per-item errors, got %v", batchErr.Errors)
+	}
+}
+
+func TestExecuteFlowWithBindings(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	result, err := eval.ExecuteFlowWithBindings(
+		"approval_flow",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMapNested{"Order": {"ord-001": "pending"}},
+		"admin",
+		tenor.InstanceBindings{"Order": "ord-001"},
+	)
+	if err != nil {
+		t.Fatalf("ExecuteFlowWithBindings failed: %v", err)
+	}
+
+	if !result.HasOutcome("order_approved") {
+		t.Errorf("expected outcome 'order_approved', got %q", result.Outcome)
+	}
+	if err := result.ExpectTransitions([]tenor.EntityStateChange{
+		{EntityID: "Order", InstanceID: "ord-001", FromState: "pending", ToState: "approved"},
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExecuteFlowWithStepStates(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithStepStates())
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	result, err := eval.ExecuteFlow(
+		"approval_flow",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{"Order": "pending"},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ExecuteFlow failed: %v", err)
+	}
+
+	if len(result.Path) != 1 {
+		t.Fatalf("expected 1 step, got %d", len(result.Path))
+	}
+	got := result.Path[0].StateAfter["Order"][tenor.DefaultInstanceID]
+	if got != "approved" {
+		t.Errorf("expected Order approved after step_approve, got %q", got)
+	}
+}
+
+func TestExecuteFlowStepStatesOffByDefault(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	result, err := eval.ExecuteFlow(
+		"approval_flow",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ExecuteFlow failed: %v", err)
+	}
+	for _, step := range result.Path {
+		if step.StateAfter != nil {
+			t.Errorf("expected no state_after without WithStepStates, got %v", step.StateAfter)
+		}
+	}
+}
+
+func TestRejectUnknownFacts(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithRejectUnknownFacts())
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	_, err = eval.Evaluate(tenor.FactSet{"is_active": true, "is_activ": true})
+	var unknown *tenor.UnknownFactsError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownFactsError, got %v", err)
+	}
+	if len(unknown.FactIDs) != 1 || unknown.FactIDs[0] != "is_activ" {
+		t.Errorf("expected unknown fact IDs [is_activ], got %v", unknown.FactIDs)
+	}
+
+	if _, err := eval.Evaluate(tenor.FactSet{"is_active": true}); err != nil {
+		t.Errorf("expected declared facts to evaluate, got %v", err)
+	}
+}
+
+func TestUnknownFactsIgnoredByDefault(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	if _, err := eval.Evaluate(tenor.FactSet{"is_active": true, "is_activ": true}); err != nil {
+		t.Errorf("expected extra facts to be ignored by default, got %v", err)
+	}
+}
+
+// ── Capabilities ──
+
+func TestComputeActionSpaceNotSupportedWithoutOperations(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(ruleOnlyBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	if _, err := eval.Evaluate(tenor.FactSet{"is_active": true}); err != nil {
+		t.Fatalf("expected Evaluate to work on a rule-only contract, got %v", err)
+	}
+	_, err = eval.ComputeActionSpace(tenor.FactSet{"is_active": true}, tenor.EntityStateMap{}, "admin")
+	if !errors.Is(err, tenor.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+// ── ExecuteFlowAuto ──
+
+func TestExecuteFlowAutoLowestID(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	states := tenor.EntityStateMapNested{"Order": {
+		"ord-003": "pending",
+		"ord-001": "pending",
+		"ord-002": "approved",
+	}}
+
+	result, err := eval.ExecuteFlowAuto("approval_flow", facts, states, "admin", tenor.LowestID)
+	if err != nil {
+		t.Fatalf("ExecuteFlowAuto failed: %v", err)
+	}
+	if got := result.InstanceBindings["Order"]; got != "ord-001" {
+		t.Errorf("expected LowestID to bind ord-001, got %q", got)
+	}
+
+	last := tenor.Custom(func(_ string, candidates []string) (string, error) {
+		return candidates[len(candidates)-1], nil
+	})
+	result, err = eval.ExecuteFlowAuto("approval_flow", facts, states, "admin", last)
+	if err != nil {
+		t.Fatalf("ExecuteFlowAuto failed: %v", err)
+	}
+	if got := result.InstanceBindings["Order"]; got != "ord-003" {
+		t.Errorf("expected custom policy to bind ord-003, got %q", got)
+	}
+
+	refuse := tenor.Custom(func(_ string, candidates []string) (string, error) {
+		return "", fmt.Errorf("%d candidates, want exactly one", len(candidates))
+	})
+	if _, err := eval.ExecuteFlowAuto("approval_flow", facts, states, "admin", refuse); err == nil {
+		t.Error("expected error when the policy cannot resolve a unique binding")
+	}
+}
+
+// ── IsDeterministic ──
+
+// staticSourceBundle binds its only fact to a declared static Source.
+const staticSourceBundle = `{
+  "constructs": [
+    {
+      "fields": {},
+      "id": "config",
+      "kind": "Source",
+      "protocol": "static",
+      "provenance": { "file": "static.tenor", "line": 1 },
+      "tenor": "1.0"
+    },
+    {
+      "id": "is_active",
+      "kind": "Fact",
+      "provenance": { "file": "static.tenor", "line": 3 },
+      "source": { "path": "account.active", "source_id": "config" },
+      "tenor": "1.0",
+      "type": { "base": "Bool" }
+    }
+  ],
+  "id": "static_source",
+  "kind": "Bundle",
+  "tenor": "1.0",
+  "tenor_version": "1.0.0"
+}`
+
+func TestIsDeterministic(t *testing.T) {
+	static, err := tenor.NewEvaluatorFromBundle([]byte(staticSourceBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer static.Close()
+	if !static.IsDeterministic() {
+		t.Error("expected a contract with only static sources to be deterministic")
+	}
+
+	freetext, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer freetext.Close()
+	if freetext.IsDeterministic() {
+		t.Error("expected a freetext-sourced fact to make the contract non-deterministic")
+	}
+}
+
+// ── Inferred initial states ──
+
+func TestInferInitialStates(t *testing.T) {
+	facts := tenor.FactSet{"is_active": true}
+
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	space, err := eval.ComputeActionSpace(facts, tenor.EntityStateMap{}, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(space.Actions) != 0 {
+		t.Fatalf("expected no actions without Order state, got %+v", space.Actions)
+	}
+
+	inferred, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithInferInitialStates())
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer inferred.Close()
+
+	space, err = inferred.ComputeActionSpace(facts, tenor.EntityStateMap{}, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(space.Actions) != 1 || space.Actions[0].FlowID != "approval_flow" {
+		t.Errorf("expected approval_flow available with Order inferred as pending, got %+v", space.Actions)
+	}
+}
+
+// ── ComputeActionSpaceAfter ──
+
+func TestComputeActionSpaceAfter(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	states := tenor.EntityStateMap{"Order": "pending"}
+	flowResult, space, err := eval.ComputeActionSpaceAfter(
+		"approval_flow",
+		tenor.FactSet{"is_active": true},
+		states,
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpaceAfter failed: %v", err)
+	}
+
+	if flowResult.Outcome != "order_approved" {
+		t.Errorf("expected outcome 'order_approved', got %q", flowResult.Outcome)
+	}
+	if len(space.Actions) != 0 {
+		t.Errorf("expected approve action to disappear once approved, got %+v", space.Actions)
+	}
+	if len(space.BlockedActions) != 1 || space.BlockedActions[0].Reason.Type != "EntityNotInSourceState" {
+		t.Errorf("expected approval_flow blocked by entity state, got %+v", space.BlockedActions)
+	}
+	if states["Order"] != "pending" {
+		t.Errorf("expected caller's states to be unchanged, got %q", states["Order"])
+	}
+}
+
+// ── Strict decoding ──
+
+func TestStrictDecoding(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithStrictDecoding())
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	states := tenor.EntityStateMap{"Order": "pending"}
+	if _, err := eval.Evaluate(facts); err != nil {
+		t.Errorf("Evaluate: bridge output has fields the SDK does not model: %v", err)
+	}
+	if _, err := eval.ComputeActionSpace(facts, states, "admin"); err != nil {
+		t.Errorf("ComputeActionSpace: bridge output has fields the SDK does not model: %v", err)
+	}
+	if _, err := eval.ExecuteFlow("approval_flow", facts, states, "admin"); err != nil {
+		t.Errorf("ExecuteFlow: bridge output has fields the SDK does not model: %v", err)
+	}
+}
+
+// ── Fact coercion trace ──
+
+func TestFactCoercionTrace(t *testing.T) {
+	var trace []tenor.FactCoercion
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle),
+		tenor.WithFactCoercionTrace(func(tr []tenor.FactCoercion) { trace = tr }))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	// is_active is declared Bool; passing an int must show up as a mismatch.
+	_, _ = eval.Evaluate(tenor.FactSet{"is_active": 1, "extra": "x"})
+
+	if len(trace) != 2 {
+		t.Fatalf("expected 2 trace entries, got %+v", trace)
+	}
+	extra, active := trace[0], trace[1]
+	if extra.FactID != "extra" || extra.DeclaredType != "" || extra.Mismatch != "" {
+		t.Errorf("unexpected entry for undeclared fact: %+v", extra)
+	}
+	if active.FactID != "is_active" || active.GoType != "int" || active.DeclaredType != "Bool" ||
+		string(active.JSON) != "1" || active.Mismatch == "" {
+		t.Errorf("unexpected entry for is_active: %+v", active)
+	}
+}
+
+// ── Describe ──
+
+func TestDescribe(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	d, err := eval.Describe()
+	if err != nil {
+		t.Fatalf("Describe failed: %v", err)
+	}
+	if d.ID != "entity_operation_basic" || len(d.Facts) != 1 || len(d.Flows) != 1 {
+		t.Errorf("unexpected description: %+v", d)
+	}
+	if len(d.Personas) != 1 || d.Personas[0].ID != "admin" {
+		t.Errorf("expected persona admin derived from operations, got %+v", d.Personas)
+	}
+	if len(d.VerdictTypes) != 1 || d.VerdictTypes[0].Type != "account_active" ||
+		d.VerdictTypes[0].ProducedBy[0] != "check_active" {
+		t.Errorf("unexpected verdict types: %+v", d.VerdictTypes)
+	}
+	if d.Facts[0].Provenance.Line != 11 {
+		t.Errorf("expected fact provenance line 11, got %+v", d.Facts[0].Provenance)
+	}
+
+	raw, err := eval.DescribeJSON()
+	if err != nil {
+		t.Fatalf("DescribeJSON failed: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("DescribeJSON produced invalid JSON: %v", err)
+	}
+	if sources, ok := decoded["sources"].([]interface{}); !ok || len(sources) != 0 {
+		t.Errorf("expected empty sources array, got %v", decoded["sources"])
+	}
+}
+
+// ── Initial action space ──
+
+func TestInitialActionSpace(t *testing.T) {
+	defaulted := strings.Replace(basicBundle, `"type": { "base": "Bool" }`,
+		`"type": { "base": "Bool" }, "default": { "kind": "bool_literal", "value": true }`, 1)
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(defaulted))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	space, err := eval.InitialActionSpace("admin")
+	if err != nil {
+		t.Fatalf("InitialActionSpace failed: %v", err)
+	}
+	if len(space.Actions) != 1 || space.Actions[0].FlowID != "approval_flow" {
+		t.Fatalf("expected approval_flow available, got %+v", space)
+	}
+	if got := space.Actions[0].AffectedEntities; len(got) != 1 || got[0].EntityID != "Order" || got[0].CurrentState != "pending" {
+		t.Errorf("expected a fresh Order in pending, got %+v", got)
+	}
+}
+
+func TestInitialActionSpaceRequiresDefaults(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	_, err = eval.InitialActionSpace("admin")
+	var missing *tenor.MissingFactDefaultsError
+	if !errors.As(err, &missing) || len(missing.FactIDs) != 1 || missing.FactIDs[0] != "is_active" {
+		t.Errorf("expected MissingFactDefaultsError for is_active, got %v", err)
+	}
+}
+
+// ── Verdict overlay ──
+
+func TestVerdictOverlaySuppress(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle),
+		tenor.WithVerdictOverlay([]string{"account_active"}, nil))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	verdicts, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(verdicts.Verdicts) != 0 {
+		t.Errorf("expected account_active suppressed, got %+v", verdicts.Verdicts)
+	}
+
+	space, err := eval.ComputeActionSpace(tenor.FactSet{"is_active": true}, tenor.EntityStateMap{"Order": "pending"}, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(space.Actions) != 0 || len(space.CurrentVerdicts) != 0 {
+		t.Errorf("expected no actions and no current verdicts, got %+v", space)
+	}
+	if len(space.BlockedActions) != 1 {
+		t.Fatalf("expected approval_flow blocked, got %+v", space.BlockedActions)
+	}
+	reason := space.BlockedActions[0].Reason
+	if reason.Type != tenor.ReasonPreconditionNotMet || len(reason.MissingVerdicts) != 1 || reason.MissingVerdicts[0] != "account_active" {
+		t.Errorf("expected approve_order blocked on account_active, got %+v", reason)
+	}
+}
+
+func TestVerdictOverlayInject(t *testing.T) {
+	injected := tenor.VerdictSummary{
+		VerdictType:   "account_active",
+		Payload:       map[string]interface{}{"kind": "bool_value", "value": true},
+		ProducingRule: "check_active",
+	}
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle),
+		tenor.WithVerdictOverlay(nil, []tenor.VerdictSummary{injected}))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	plain, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer plain.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	states := tenor.EntityStateMap{"Order": "pending"}
+	want, err := plain.ComputeActionSpace(facts, states, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+
+	// With is_active false the rule does not fire, but the injected verdict
+	// enables the action exactly as the real one would.
+	got, err := eval.ComputeActionSpace(tenor.FactSet{"is_active": false}, states, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(got.Actions) != 1 || len(got.BlockedActions) != 0 {
+		t.Fatalf("expected approval_flow available, got %+v", got)
+	}
+	if !reflect.DeepEqual(got.Actions[0].AffectedEntities, want.Actions[0].AffectedEntities) ||
+		got.Actions[0].Description != want.Actions[0].Description ||
+		!reflect.DeepEqual(got.Actions[0].InstanceBindings, want.Actions[0].InstanceBindings) {
+		t.Errorf("expected the re-derived action to match the evaluator's:\n got  %+v\n want %+v", got.Actions[0], want.Actions[0])
+	}
+
+	// Entity states still apply.
+	blocked, err := eval.ComputeActionSpace(tenor.FactSet{"is_active": false}, tenor.EntityStateMap{"Order": "approved"}, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(blocked.BlockedActions) != 1 || blocked.BlockedActions[0].Reason.Type != tenor.ReasonEntityNotInSourceState {
+		t.Errorf("expected EntityNotInSourceState, got %+v", blocked.BlockedActions)
+	}
+}
+
+// ── Flow allow-list ──
+
+func TestFlowAllowList(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithFlowAllowList("other_flow"))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	states := tenor.EntityStateMap{"Order": "pending"}
+
+	_, err = eval.ExecuteFlow("approval_flow", facts, states, "admin")
+	if !errors.Is(err, tenor.ErrFlowNotAllowed) {
+		t.Errorf("expected ErrFlowNotAllowed, got %v", err)
+	}
+
+	space, err := eval.ComputeActionSpace(facts, states, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(space.Actions) != 0 || len(space.BlockedActions) != 0 {
+		t.Errorf("expected approval_flow to be filtered out, got %d actions and %d blocked",
+			len(space.Actions), len(space.BlockedActions))
+	}
+
+	allowed, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithFlowAllowList("approval_flow"))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer allowed.Close()
+
+	space, err = allowed.ComputeActionSpace(facts, states, "admin")
+	if err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if len(space.Actions) != 1 || space.Actions[0].FlowID != "approval_flow" {
+		t.Errorf("expected approval_flow to be available, got %+v", space.Actions)
+	}
+}
+
+func TestWithFactMarshaler(t *testing.T) {
+	bundle := strings.Replace(basicBundle, `"constructs": [`, `"constructs": [
+    {
+      "id": "decided_at",
+      "kind": "Fact",
+      "provenance": { "file": "test.tenor", "line": 2 },
+      "source": { "field": "decided_at", "system": "orders" },
+      "tenor": "1.0",
+      "type": { "base": "DateTime" }
+    },`, 1)
+	rfc3339 := func(id string, v interface{}) (json.RawMessage, error) {
+		if ts, ok := v.(time.Time); ok {
+			return json.Marshal(ts.UTC().Format(time.RFC3339))
+		}
+		return nil, nil
+	}
+	var sent []tenor.FactCoercion
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(bundle), tenor.WithFactMarshaler(rfc3339),
+		tenor.WithFactCoercionTrace(func(trace []tenor.FactCoercion) { sent = trace }))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	decided := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	result, err := eval.Evaluate(tenor.FactSet{"decided_at": decided, "is_active": true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(result.Verdicts) != 1 {
+		t.Errorf("expected 1 verdict, got %d", len(result.Verdicts))
+	}
+	if len(sent) != 2 || string(sent[0].JSON) != `"2024-01-02T02:04:05Z"` || string(sent[1].JSON) != "true" {
+		t.Errorf("expected the marshaled timestamp and the default encoding of is_active, got %+v", sent)
+	}
+
+	// Output that does not fit the declared type is rejected Go-side.
+	unix, err := tenor.NewEvaluatorFromBundle([]byte(bundle), tenor.WithFactMarshaler(func(id string, v interface{}) (json.RawMessage, error) {
+		if ts, ok := v.(time.Time); ok {
+			return json.Marshal(ts.Unix())
+		}
+		return nil, nil
+	}))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer unix.Close()
+	if _, err := unix.Evaluate(tenor.FactSet{"decided_at": decided, "is_active": true}); err == nil || !strings.Contains(err.Error(), "DateTime facts must be strings") {
+		t.Errorf("expected a type mismatch for a Unix timestamp, got %v", err)
+	}
+}
+
+// ── EvaluateAsOf ──
+
+func TestEvaluateAsOfRequiresTimeFact(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := eval.EvaluateAsOf(now, tenor.FactSet{}, "is_active"); err == nil {
+		t.Error("expected error for a Bool now-fact, got nil")
+	}
+	if _, err := eval.EvaluateAsOf(now, tenor.FactSet{"is_active": true}, "as_of"); err == nil {
+		t.Error("expected error for an undeclared now-fact, got nil")
+	}
+}
+
+// ── ExplainBlocked ──
+
+func TestExplainBlockedPrecondition(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	x, err := eval.ExplainBlocked(
+		"approval_flow",
+		tenor.FactSet{"is_active": false},
+		tenor.EntityStateMap{"Order": "pending"},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ExplainBlocked failed: %v", err)
+	}
+	if !x.Blocked {
+		t.Fatal("expected approval_flow to be blocked")
+	}
+	if len(x.MissingVerdicts) != 1 || x.MissingVerdicts[0].VerdictType != "account_active" {
+		t.Fatalf("expected missing verdict account_active, got %+v", x.MissingVerdicts)
+	}
+	rules := x.MissingVerdicts[0].Rules
+	if len(rules) != 1 || rules[0].RuleID != "check_active" {
+		t.Fatalf("expected rule check_active, got %+v", rules)
+	}
+	if len(rules[0].Conditions) != 1 {
+		t.Fatalf("expected 1 condition, got %+v", rules[0].Conditions)
+	}
+	c := rules[0].Conditions[0]
+	if c.FactID != "is_active" || c.Satisfied || c.Actual != false {
+		t.Errorf("expected unsatisfied is_active=false condition, got %+v", c)
+	}
+
+	want := "approve_order is blocked because account_active isn't present because is_active is false"
+	if got := x.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestExplainBlockedNotBlocked(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	x, err := eval.ExplainBlocked(
+		"approval_flow",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{"Order": "pending"},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("ExplainBlocked failed: %v", err)
+	}
+	if x.Blocked {
+		t.Errorf("expected approval_flow to be available, got %+v", x)
+	}
+}
+
+// ── Allocation strategy ──
+
+func TestAllocPerArgStrategy(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithAllocStrategy(tenor.AllocPerArg))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	result, err := eval.ExecuteFlowWithBindings(
+		"approval_flow",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMapNested{"Order": {"ord-001": "pending"}},
+		"admin",
+		tenor.InstanceBindings{"Order": "ord-001"},
+	)
+	if err != nil {
+		t.Fatalf("ExecuteFlowWithBindings failed: %v", err)
+	}
+	if result.Outcome != "order_approved" {
+		t.Errorf("expected outcome 'order_approved', got %q", result.Outcome)
+	}
+}
+
+func benchmarkExecuteFlowWithBindings(b *testing.B, strategy tenor.AllocStrategy) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithAllocStrategy(strategy))
+	if err != nil {
+		b.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	states := tenor.EntityStateMapNested{"Order": {"ord-001": "pending"}}
+	bindings := tenor.InstanceBindings{"Order": "ord-001"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := eval.ExecuteFlowWithBindings("approval_flow", facts, states, "admin", bindings); err != nil {
+			b.Fatalf("ExecuteFlowWithBindings failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkExecuteFlowWithBindingsArena(b *testing.B) {
+	benchmarkExecuteFlowWithBindings(b, tenor.AllocArena)
+}
+
+func BenchmarkExecuteFlowWithBindingsPerArg(b *testing.B) {
+	benchmarkExecuteFlowWithBindings(b, tenor.AllocPerArg)
+}
+
+func batchSnapshots(n int) []tenor.EntityStateMapNested {
+	snapshots := make([]tenor.EntityStateMapNested, n)
+	for i := range snapshots {
+		snapshots[i] = tenor.EntityStateMapNested{"Order": {fmt.Sprintf("ord-%04d", i): "pending"}}
+	}
+	return snapshots
+}
+
+func BenchmarkComputeActionSpaceBatch(b *testing.B) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		b.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	snapshots := batchSnapshots(100)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := eval.ComputeActionSpaceBatch(facts, snapshots, "admin"); err != nil {
+			b.Fatalf("ComputeActionSpaceBatch failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkComputeActionSpaceLoop(b *testing.B) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		b.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	snapshots := batchSnapshots(100)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, states := range snapshots {
+			if _, err := eval.ComputeActionSpaceNested(facts, states, "admin"); err != nil {
+				b.Fatalf("ComputeActionSpaceNested failed: %v", err)
+			}
+		}
+	}
+}
+
+// ── Multiple personas ──
+
+func TestComputeActionSpaceMulti(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	space, err := eval.ComputeActionSpaceMulti(
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{"Order": "pending"},
+		[]string{"admin", "guest"},
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpaceMulti failed: %v", err)
+	}
+
+	if len(space.Actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(space.Actions))
+	}
+	if space.Actions[0].FlowID != "approval_flow" || space.Actions[0].PersonaID != "admin" {
+		t.Errorf("expected approval_flow available to admin, got %+v", space.Actions[0])
+	}
+	// guest is blocked, but admin can act, so the action is not blocked overall.
+	if len(space.BlockedActions) != 0 {
+		t.Errorf("expected 0 blocked actions, got %+v", space.BlockedActions)
+	}
+}
+
+func TestComputeActionSpaceMultiAllBlocked(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	space, err := eval.ComputeActionSpaceMulti(
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{"Order": "pending"},
+		[]string{"guest", "auditor"},
+	)
+	if err != nil {
+		t.Fatalf("ComputeActionSpaceMulti failed: %v", err)
+	}
+	if len(space.Actions) != 0 {
+		t.Errorf("expected 0 actions, got %d", len(space.Actions))
+	}
+	if len(space.BlockedActions) != 1 || space.BlockedActions[0].Reason.Type != "PersonaNotAuthorized" {
+		t.Errorf("expected approval_flow blocked with PersonaNotAuthorized, got %+v", space.BlockedActions)
+	}
+}
+
+func TestCheckInvariants(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundles([][]byte{[]byte(basicBundle), []byte(overlayBundle)})
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	invariants := []tenor.Invariant{tenor.MutuallyExclusive("account_active", "tenant_eligible")}
+	violations, err := eval.CheckInvariants(tenor.FactSet{"is_active": true}, invariants)
+	if err != nil {
+		t.Fatalf("CheckInvariants failed: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Name != "mutually exclusive: account_active, tenant_eligible" {
+		t.Errorf("expected the exclusivity invariant to be violated, got %+v", violations)
+	}
+
+	violations, err = eval.CheckInvariants(tenor.FactSet{"is_active": false}, invariants)
+	if err != nil || violations != nil {
+		t.Errorf("expected no violations, got %+v, %v", violations, err)
+	}
+}
+
+func TestRuleFiringStats(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundles([][]byte{[]byte(basicBundle), []byte(overlayBundle)})
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	counts, err := tenor.RuleFiringStats(eval, []tenor.FactSet{
+		{"is_active": true}, {"is_active": false}, {"is_active": true},
+	})
+	if err != nil {
+		t.Fatalf("RuleFiringStats failed: %v", err)
+	}
+	want := map[string]int{"check_active": 2, "check_tenant": 2}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected %v, got %v", want, counts)
+	}
+
+	counts, err = tenor.RuleFiringStats(eval, []tenor.FactSet{{"is_active": false}})
+	if err != nil || !reflect.DeepEqual(counts, map[string]int{"check_active": 0, "check_tenant": 0}) {
+		t.Errorf("expected zero counts for rules that never fired, got %v, %v", counts, err)
+	}
+
+	if _, err := tenor.RuleFiringStats(eval, []tenor.FactSet{{"is_active": true}, {}}); err == nil || !strings.Contains(err.Error(), "fact set 1") {
+		t.Errorf("expected fact set 1 to fail, got %v", err)
+	}
+}
+
+func TestEvaluatorStats(t *testing.T) {
+	before := time.Now()
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	stats := eval.Stats()
+	if stats.LoadedAt.Before(before) || stats.LoadedAt.After(time.Now()) {
+		t.Errorf("LoadedAt = %v, want a time during the load", stats.LoadedAt)
+	}
+	if stats.Evaluations != 0 || stats.ActionSpaceCalls != 0 || stats.FlowCalls != 0 {
+		t.Errorf("expected no calls after load, got %+v", stats)
+	}
+	loadedUse := stats.LastUsed
+
+	facts := tenor.FactSet{"is_active": true}
+	states := tenor.EntityStateMap{"Order": "pending"}
+	for i := 0; i < 2; i++ {
+		if _, err := eval.Evaluate(facts); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+	}
+	if _, err := eval.ComputeActionSpace(facts, states, "admin"); err != nil {
+		t.Fatalf("ComputeActionSpace failed: %v", err)
+	}
+	if _, err := eval.ExecuteFlow("approval_flow", facts, states, "admin"); err != nil {
+		t.Fatalf("ExecuteFlow failed: %v", err)
+	}
+
+	stats = eval.Stats()
+	if stats.Evaluations != 2 {
+		t.Errorf("Evaluations = %d, want 2", stats.Evaluations)
+	}
+	if stats.ActionSpaceCalls != 1 {
+		t.Errorf("ActionSpaceCalls = %d, want 1", stats.ActionSpaceCalls)
+	}
+	if stats.FlowCalls != 1 {
+		t.Errorf("FlowCalls = %d, want 1", stats.FlowCalls)
+	}
+	if stats.WASMTime <= 0 {
+		t.Errorf("WASMTime = %v, want positive", stats.WASMTime)
+	}
+	if !stats.LastUsed.After(loadedUse) {
+		t.Errorf("LastUsed = %v, want after %v", stats.LastUsed, loadedUse)
+	}
+}
+
+func TestBundleSliceEvaluates(t *testing.T) {
+	b, err := tenor.ParseBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	slice, err := b.Slice("account_active")
+	if err != nil {
+		t.Fatalf("Slice failed: %v", err)
+	}
+	data, err := json.Marshal(slice)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	eval, err := tenor.NewEvaluatorFromBundle(data)
+	if err != nil {
+		t.Fatalf("sliced bundle does not load: %v", err)
+	}
+	defer eval.Close()
+
+	verdicts, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(verdicts.Verdicts) != 1 || verdicts.Verdicts[0].Type != "account_active" {
+		t.Errorf("expected account_active from the slice, got %+v", verdicts.Verdicts)
+	}
+}
+
+func TestEvaluateTagged(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	verdicts, err := eval.EvaluateTagged(tenor.TaggedFactSet{
+		"is_active": {Value: true, Source: "crm"},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateTagged failed: %v", err)
+	}
+	if len(verdicts.Verdicts) != 1 {
+		t.Fatalf("expected 1 verdict, got %d", len(verdicts.Verdicts))
+	}
+	want := map[string]string{"is_active": "crm"}
+	if got := verdicts.Verdicts[0].Provenance.FactSources; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected FactSources %v, got %v", want, got)
+	}
+}
+
+func TestSensitivityOf(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	report, err := eval.SensitivityOf("is_active",
+		tenor.FactSet{"is_active": true},
+		tenor.EntityStateMap{"Order": "pending"},
+		"admin",
+	)
+	if err != nil {
+		t.Fatalf("SensitivityOf failed: %v", err)
+	}
+	if report.Baseline != true || len(report.Alternatives) != 1 || report.Truncated {
+		t.Fatalf("expected one alternative from baseline true, got %+v", report)
+	}
+	alt := report.Alternatives[0]
+	if alt.Value != false {
+		t.Errorf("expected alternative false, got %v", alt.Value)
+	}
+	if want := []string{"account_active"}; !reflect.DeepEqual(alt.Verdicts.Removed, want) {
+		t.Errorf("expected removed verdicts %v, got %+v", want, alt.Verdicts)
+	}
+	if want := []string{"approval_flow"}; !reflect.DeepEqual(alt.Actions.Disabled, want) {
+		t.Errorf("expected disabled flows %v, got %+v", want, alt.Actions)
+	}
+}
+
+func TestNewCompilationCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := tenor.NewCompilationCacheDir(filepath.Join(dir, "wasm"))
+	if err != nil {
+		t.Fatalf("NewCompilationCacheDir failed: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tenor.NewCompilationCacheDir(file); err == nil {
+		t.Error("expected an error for a cache path that is a file")
+	}
+}
+
+func TestCompilationCacheDirAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	// Each cache stands for one process; the second finds the module the
+	// first compiled on disk.
+	for i := 0; i < 2; i++ {
+		cache, err := tenor.NewCompilationCacheDir(dir)
+		if err != nil {
+			t.Fatalf("NewCompilationCacheDir failed: %v", err)
+		}
+		eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithCompilationCache(cache))
+		if err != nil {
+			t.Fatalf("run %d: failed to load: %v", i, err)
+		}
+		if _, err := eval.Evaluate(tenor.FactSet{"is_active": true}); err != nil {
+			t.Errorf("run %d: Evaluate failed: %v", i, err)
+		}
+		eval.Close()
+		cache.Close()
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) == 0 {
+		t.Errorf("expected compiled modules in %s, got %v, %v", dir, entries, err)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	reg := tenor.NewRegistry(true)
+	defer reg.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("contract-%d", i%4)
+			if err := reg.Register(name, []byte(basicBundle)); err != nil && !strings.Contains(err.Error(), "already registered") {
+				t.Errorf("Register(%s) failed: %v", name, err)
+				return
+			}
+			if eval, ok := reg.Get(name); ok {
+				// The evaluator may be unregistered concurrently; only a
+				// successful call is checked.
+				if vs, err := eval.Evaluate(tenor.FactSet{"is_active": true}); err == nil && len(vs.Verdicts) != 1 {
+					t.Errorf("expected 1 verdict, got %d", len(vs.Verdicts))
+				}
+			}
+			if i%2 == 0 {
+				reg.Unregister(name)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := reg.Register("final", []byte(basicBundle)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	eval, ok := reg.Get("final")
+	if !ok {
+		t.Fatal("expected final to be registered")
+	}
+	reg.Unregister("final")
+	if _, ok := reg.Get("final"); ok {
+		t.Error("expected final to be gone after Unregister")
+	}
+	if _, err := eval.Evaluate(tenor.FactSet{"is_active": true}); err == nil {
+		t.Error("expected an unregistered evaluator to be closed")
+	}
+
+	if err := reg.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := reg.Register("late", []byte(basicBundle)); !errors.Is(err, tenor.ErrRegistryClosed) {
+		t.Errorf("expected ErrRegistryClosed, got %v", err)
+	}
+}
+
+func TestMaxResultBytes(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithMaxResultBytes(16))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	if _, err := eval.Evaluate(tenor.FactSet{"is_active": true}); !errors.Is(err, tenor.ErrResultTooLarge) {
+		t.Errorf("expected ErrResultTooLarge, got %v", err)
+	}
+}
+
+func TestWithRuntimeConfigInterpreter(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle),
+		tenor.WithRuntimeConfig(wazero.NewRuntimeConfigInterpreter()))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	verdicts, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(verdicts.Verdicts) != 1 {
+		t.Errorf("expected 1 verdict, got %d", len(verdicts.Verdicts))
+	}
+}
+
+func TestRequireNestedStates(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithRequireNestedStates())
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	// Until the evaluator sees several instances, the flat APIs work.
+	facts := tenor.FactSet{"is_active": true}
+	flat := tenor.EntityStateMap{"Order": "pending"}
+	if _, err := eval.ComputeActionSpace(facts, flat, "admin"); err != nil {
+		t.Errorf("expected the flat ComputeActionSpace to work, got %v", err)
+	}
+	single := tenor.EntityStateMapNested{"Order": {"ord-1": "pending"}}
+	if _, err := eval.ComputeActionSpaceNested(facts, single, "admin"); err != nil || eval.IsMultiInstance() {
+		t.Fatalf("expected one instance not to make the evaluator multi-instance, got %v", err)
+	}
+
+	nested := tenor.EntityStateMapNested{"Order": {"ord-1": "pending", "ord-2": "approved"}}
+	if _, err := eval.ComputeActionSpaceNested(facts, nested, "admin"); err != nil {
+		t.Fatalf("ComputeActionSpaceNested failed: %v", err)
+	}
+	if !eval.IsMultiInstance() {
+		t.Error("expected two Order instances to make the evaluator multi-instance")
+	}
+	if _, err := eval.ComputeActionSpace(facts, flat, "admin"); !errors.Is(err, tenor.ErrMultiInstance) {
+		t.Errorf("expected ErrMultiInstance from ComputeActionSpace, got %v", err)
+	}
+	if _, err := eval.ExecuteFlow("approval_flow", facts, flat, "admin"); !errors.Is(err, tenor.ErrMultiInstance) {
+		t.Errorf("expected ErrMultiInstance from ExecuteFlow, got %v", err)
+	}
+
+	// Without the option the flat APIs keep working.
+	lenient, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer lenient.Close()
+	if _, err := lenient.ComputeActionSpaceNested(facts, nested, "admin"); err != nil {
+		t.Fatalf("ComputeActionSpaceNested failed: %v", err)
+	}
+	if _, err := lenient.ComputeActionSpace(facts, flat, "admin"); err != nil {
+		t.Errorf("expected the flat ComputeActionSpace to work without the option, got %v", err)
+	}
+}
+
+func TestNewEvaluatorFromBundleWithRetry(t *testing.T) {
+	var logs bytes.Buffer
+	logger := tenor.WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// An invalid bundle is never retried: a retry would wait an hour.
+	restore := tenor.FailRuntimeCreations(100)
+	if _, err := tenor.NewEvaluatorFromBundleWithRetry([]byte(`{"kind": "Bundle"`), 5, time.Hour, logger); err == nil {
+		t.Error("expected an invalid bundle to fail")
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no retries for an invalid bundle, got logs:\n%s", logs.String())
+	}
+
+	// Transient failures are retried until attempts run out.
+	_, err := tenor.NewEvaluatorFromBundleWithRetry([]byte(basicBundle), 3, time.Millisecond, logger)
+	restore()
+	if err == nil || !strings.Contains(err.Error(), "simulated mmap failure") {
+		t.Errorf("expected the simulated failure after 3 attempts, got %v", err)
+	}
+	if n := strings.Count(logs.String(), "retrying"); n != 2 {
+		t.Errorf("expected 2 logged retries, got %d:\n%s", n, logs.String())
+	}
+
+	// A transient failure followed by success yields an evaluator.
+	logs.Reset()
+	restore = tenor.FailRuntimeCreations(2)
+	defer restore()
+	eval, err := tenor.NewEvaluatorFromBundleWithRetry([]byte(basicBundle), 3, time.Millisecond, logger)
+	if err != nil {
+		t.Fatalf("expected success on the third attempt, got %v", err)
+	}
+	defer eval.Close()
+	if n := strings.Count(logs.String(), "retrying"); n != 2 {
+		t.Errorf("expected 2 logged retries, got %d:\n%s", n, logs.String())
+	}
+}
+
+func TestValidateBundleLoadable(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		if err := tenor.ValidateBundleLoadable([]byte(basicBundle)); err != nil {
+			t.Fatalf("expected basicBundle to load, got %v", err)
+		}
+	}
+	for _, bundle := range []string{`not json`, `{"kind": "Bundle", "constructs": [{"kind": "Rule", "id": "r"}]}`} {
+		if err := tenor.ValidateBundleLoadable([]byte(bundle)); err == nil {
+			t.Errorf("expected %s to fail to load", bundle)
+		}
+	}
+}
+
+func TestNewEvaluatorFromBundles(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundles([][]byte{[]byte(basicBundle), []byte(overlayBundle)})
+	if err != nil {
+		t.Fatalf("NewEvaluatorFromBundles failed: %v", err)
+	}
+	defer eval.Close()
+
+	verdicts, err := eval.Evaluate(tenor.FactSet{"is_active": true})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, v := range verdicts.Verdicts {
+		seen[v.Type] = true
+	}
+	if !seen["account_active"] || !seen["tenant_eligible"] {
+		t.Errorf("expected verdicts from both bundles, got %+v", verdicts.Verdicts)
+	}
+}
+
+func TestEvaluateProfiled(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+	if _, _, err := eval.EvaluateProfiled(tenor.FactSet{"is_active": true}); !errors.Is(err, tenor.ErrProfilingDisabled) {
+		t.Errorf("expected ErrProfilingDisabled, got %v", err)
+	}
+
+	profiled, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle), tenor.WithRuleProfiling())
+	if err != nil {
+		t.Fatalf("failed to load with profiling: %v", err)
+	}
+	defer profiled.Close()
+	vs, profile, err := profiled.EvaluateProfiled(tenor.FactSet{"is_active": true})
+	if err != nil {
+		t.Fatalf("EvaluateProfiled failed: %v", err)
+	}
+	if len(vs.Verdicts) != 1 {
+		t.Errorf("expected 1 verdict, got %d", len(vs.Verdicts))
+	}
+	timing, ok := profile["check_active"]
+	if len(profile) != 1 || !ok || timing.Count != 1 || timing.Duration < 0 {
+		t.Errorf("expected one timing for check_active, got %+v", profile)
+	}
+}
+
+func TestDumpConformance(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	states := tenor.EntityStateMap{"Order": "pending"}
+	cases := []tenor.ConformanceCase{
+		{Name: "active", Facts: tenor.FactSet{"is_active": true}, States: states, Persona: "admin", FlowID: "approval_flow"},
+		{Name: "inactive", Facts: tenor.FactSet{"is_active": false}},
+	}
+	var golden bytes.Buffer
+	if err := tenor.DumpConformance(eval, cases, &golden); err != nil {
+		t.Fatalf("DumpConformance failed: %v", err)
+	}
+	if lines := strings.Count(golden.String(), "\n"); lines != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", lines, golden.String())
+	}
+
+	mismatches, err := tenor.CompareConformance(bytes.NewReader(golden.Bytes()), eval, cases)
+	if err != nil || mismatches != nil {
+		t.Fatalf("expected no mismatches, got %+v, %v", mismatches, err)
+	}
+
+	changed := []tenor.ConformanceCase{cases[0], {Name: "inactive", Facts: tenor.FactSet{"is_active": true}}}
+	mismatches, err = tenor.CompareConformance(bytes.NewReader(golden.Bytes()), eval, changed)
+	if err != nil {
+		t.Fatalf("CompareConformance failed: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Name != "inactive" || mismatches[0].Golden == "" || mismatches[0].Got == "" {
+		t.Errorf("expected one mismatch for inactive, got %+v", mismatches)
+	}
+
+	mismatches, err = tenor.CompareConformance(bytes.NewReader(golden.Bytes()), eval, cases[:1])
+	if err != nil || len(mismatches) != 1 || mismatches[0].Name != "inactive" || mismatches[0].Got != "" {
+		t.Errorf("expected the dropped case to be reported, got %+v, %v", mismatches, err)
+	}
+}
+
+func TestCapabilityMatrix(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	m, err := eval.CapabilityMatrix(tenor.FactSet{"is_active": true}, tenor.EntityStateMap{"Order": "pending"}, []string{"admin", "guest"})
+	if err != nil {
+		t.Fatalf("CapabilityMatrix failed: %v", err)
+	}
+	if !reflect.DeepEqual(m.Flows, []string{"approval_flow"}) {
+		t.Fatalf("expected one flow row, got %v", m.Flows)
+	}
+	if c := m.Cell("approval_flow", "admin"); !c.Available {
+		t.Errorf("expected approval_flow to be available to admin, got %v", c)
+	}
+	if c := m.Cell("approval_flow", "guest"); c.Available || c.Blocked == nil || c.Blocked.Type != tenor.ReasonPersonaNotAuthorized {
+		t.Errorf("expected approval_flow to be blocked for guest, got %v", c)
+	}
+
+	want := "FLOW           admin      guest\napproval_flow  available  blocked: persona not authorized\n"
+	if got := m.Table(); got != want {
+		t.Errorf("unexpected table:\n%s\nwant:\n%s", got, want)
+	}
+	var buf bytes.Buffer
+	if err := m.CSV(&buf); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+	if want := "flow,admin,guest\napproval_flow,available,blocked: persona not authorized\n"; buf.String() != want {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestContextVariants(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := tenor.FactSet{"is_active": true}
+	states := tenor.EntityStateMap{"Order": "pending"}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := eval.EvaluateContext(cancelled, facts); !errors.Is(err, context.Canceled) {
+		t.Errorf("EvaluateContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := eval.ComputeActionSpaceContext(cancelled, facts, states, "admin"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ComputeActionSpaceContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := eval.ExecuteFlowContext(cancelled, "approval_flow", facts, states, "admin"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExecuteFlowContext: expected context.Canceled, got %v", err)
+	}
+
+	// A call refused before it started leaves the evaluator usable.
+	vs, err := eval.EvaluateContext(context.Background(), facts)
+	if err != nil || len(vs.Verdicts) != 1 {
+		t.Errorf("expected a normal evaluation, got %+v, %v", vs, err)
+	}
+}
+
+// TestContextVariantsAbortMidCall checks that evaluators are built to abort a
+// call in progress when its context is done, with or without other options.
+// The abort itself is tested against a looping module in internal/wasm.
+func TestContextVariantsAbortMidCall(t *testing.T) {
+	if !tenor.AbortsMidCall() {
+		t.Error("expected the default runtime to abort calls mid-call")
+	}
+	if !tenor.AbortsMidCall(tenor.WithWatchdog(time.Second), tenor.WithRuntimeConfig(wazero.NewRuntimeConfigInterpreter())) {
+		t.Error("expected a custom runtime to abort calls mid-call")
+	}
+}
+
+// TestEvaluateMemoryStable checks that repeated evaluation reuses WASM
+// memory: linear memory only ever grows, so growth after warm-up would mean
+// a leak per call.
+func TestEvaluateMemoryStable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("100k evaluations")
+	}
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts := []tenor.FactSet{{"is_active": true}, {"is_active": false}}
+	for i := 0; i < 1000; i++ {
+		if _, err := eval.Evaluate(facts[i%2]); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+	}
+	warm := tenor.MemorySize(eval)
+	for i := 0; i < 100000; i++ {
+		if _, err := eval.Evaluate(facts[i%2]); err != nil {
+			t.Fatalf("Evaluate %d failed: %v", i, err)
+		}
+	}
+	if size := tenor.MemorySize(eval); size != warm {
+		t.Errorf("WASM memory grew from %d to %d bytes over 100k evaluations", warm, size)
+	}
+}
+
+func TestListFlows(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	flows, err := eval.ListFlows()
+	if err != nil {
+		t.Fatalf("ListFlows failed: %v", err)
+	}
+	want := []tenor.FlowInfo{{
+		ID:              "approval_flow",
+		Entry:           "step_approve",
+		Snapshot:        "at_initiation",
+		AllowedPersonas: []string{"admin"},
+	}}
+	if !reflect.DeepEqual(flows, want) {
+		t.Errorf("ListFlows = %+v, want %+v", flows, want)
+	}
+}
+
+func TestListPersonas(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	personas, err := eval.ListPersonas()
+	if err != nil {
+		t.Fatalf("ListPersonas failed: %v", err)
+	}
+	if !reflect.DeepEqual(personas, []string{"admin"}) {
+		t.Errorf("ListPersonas = %v, want [admin]", personas)
+	}
+}
+
+func TestListFacts(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	facts, err := eval.ListFacts()
+	if err != nil {
+		t.Fatalf("ListFacts failed: %v", err)
+	}
+	want := []tenor.FactInfo{{
+		ID:       "is_active",
+		Type:     "Bool",
+		Source:   &tenor.FactSource{System: "account", Field: "active"},
+		Required: true,
+	}}
+	if !reflect.DeepEqual(facts, want) {
+		t.Errorf("ListFacts = %+v, want %+v", facts, want)
+	}
+}
+
+func TestListEntities(t *testing.T) {
+	eval, err := tenor.NewEvaluatorFromBundle([]byte(basicBundle))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	defer eval.Close()
+
+	entities, err := eval.ListEntities()
+	if err != nil {
+		t.Fatalf("ListEntities failed: %v", err)
+	}
+	if len(entities) != 1 {
+		t.Fatalf("expected 1 entity, got %+v", entities)
 	}
-	wt := result.WouldTransition[0]
-	if wt.EntityID != "Order" {
-		t.Errorf("expected entity_id 'Order', got %q", wt.EntityID)
+	ent := entities[0]
+	if ent.ID != "Order" || ent.Initial != "pending" {
+		t.Errorf("unexpected entity %q with initial state %q", ent.ID, ent.Initial)
 	}
-	if wt.InstanceID != "ord-001" {
-		t.Errorf("expected instance_id 'ord-001', got %q", wt.InstanceID)
+	if !reflect.DeepEqual(ent.States, []string{"pending", "approved"}) {
+		t.Errorf("States = %v, want [pending approved]", ent.States)
 	}
-	if wt.FromState != "pending" || wt.ToState != "approved" {
-		t.Errorf("expected pending->approved, got %q->%q", wt.FromState, wt.ToState)
+	if !reflect.DeepEqual(ent.Transitions, []tenor.Transition{{From: "pending", To: "approved"}}) {
+		t.Errorf("Transitions = %v, want [pending->approved]", ent.Transitions)
 	}
 }
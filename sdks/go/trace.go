@@ -0,0 +1,145 @@
+package tenor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FactCoercion records how one fact value crossed from Go into the JSON the
+// evaluator receives.
+type FactCoercion struct {
+	FactID string
+	// GoType is the dynamic Go type of the value in the FactSet, e.g. "int".
+	GoType string
+	// DeclaredType is the fact's declared base type, or "" if the contract
+	// does not declare the fact.
+	DeclaredType string
+	// JSON is the value as sent to the evaluator.
+	JSON json.RawMessage
+	// Mismatch describes why JSON does not have the shape the evaluator
+	// accepts for DeclaredType. It is empty when the shape fits.
+	Mismatch string
+}
+
+// WithFactCoercionTrace calls fn with a trace of every fact each call sends to
+// the evaluator, sorted by fact ID, just before the WASM call is made. It is
+// a debugging aid for type mismatches such as an int passed for a Decimal
+// fact; without it no trace is built.
+//
+// fn runs on the calling goroutine and must not retain the slice beyond the
+// call if the Evaluator is shared.
+func WithFactCoercionTrace(fn func([]FactCoercion)) Option {
+	return func(o *options) {
+		o.coercionTrace = fn
+	}
+}
+
+// WithFactMarshaler makes fn encode fact values, for Go types encoding/json
+// does not encode the way the evaluator expects, such as time.Time for a Date
+// fact or a decimal type for a Decimal fact. fn is called with each fact's ID
+// and value; it returns the value's JSON, or nil to fall back to the default
+// encoding. JSON fn returns for a declared fact must have the shape the
+// evaluator accepts for the fact's type (see FactCoercion.Mismatch), or the
+// call fails before reaching the evaluator.
+func WithFactMarshaler(fn func(id string, v interface{}) (json.RawMessage, error)) Option {
+	return func(o *options) {
+		o.factMarshaler = fn
+	}
+}
+
+// marshalFacts encodes facts for the bridge, applying WithFactMarshaler and
+// reporting a coercion trace when WithFactCoercionTrace is set.
+func (e *Evaluator) marshalFacts(facts FactSet) ([]byte, error) {
+	if e.opts.factMarshaler == nil && e.opts.coercionTrace == nil {
+		return json.Marshal(facts)
+	}
+
+	var encoded map[string]json.RawMessage // nil facts stay null
+	if facts != nil {
+		encoded = make(map[string]json.RawMessage, len(facts))
+	}
+	trace := make([]FactCoercion, 0, len(facts))
+	for id, value := range facts {
+		raw, err := e.marshalFact(id, value)
+		if err != nil {
+			return nil, err
+		}
+		encoded[id] = raw
+		if e.opts.coercionTrace == nil {
+			continue
+		}
+		c := FactCoercion{FactID: id, GoType: fmt.Sprintf("%T", value), JSON: raw}
+		if decl, ok := e.bundle.Fact(id); ok {
+			c.DeclaredType = decl.BaseType()
+			c.Mismatch = jsonShapeMismatch(c.DeclaredType, raw)
+		}
+		trace = append(trace, c)
+	}
+	if e.opts.coercionTrace != nil {
+		sort.Slice(trace, func(i, j int) bool { return trace[i].FactID < trace[j].FactID })
+		e.opts.coercionTrace(trace)
+	}
+	return json.Marshal(encoded)
+}
+
+// marshalFact encodes one fact value, through WithFactMarshaler if set.
+func (e *Evaluator) marshalFact(id string, value interface{}) (json.RawMessage, error) {
+	if fn := e.opts.factMarshaler; fn != nil {
+		raw, err := fn(id, value)
+		if err != nil {
+			return nil, fmt.Errorf("fact %q: %w", id, err)
+		}
+		if raw != nil {
+			if decl, ok := e.bundle.Fact(id); ok {
+				if mismatch := jsonShapeMismatch(decl.BaseType(), raw); mismatch != "" {
+					return nil, fmt.Errorf("fact %q: marshaler output %s: %s", id, raw, mismatch)
+				}
+			}
+			return raw, nil
+		}
+	}
+	return json.Marshal(plainNumbers(value))
+}
+
+// jsonShapeMismatch checks raw against the JSON shapes the evaluator accepts
+// for a plain fact of baseType. It checks shape only, not content.
+func jsonShapeMismatch(baseType string, raw json.RawMessage) string {
+	s := strings.TrimSpace(string(raw))
+	if s == "" {
+		return ""
+	}
+	isNumber := s[0] == '-' || (s[0] >= '0' && s[0] <= '9')
+
+	switch baseType {
+	case "Bool":
+		if s != "true" && s != "false" {
+			return "Bool facts must be true or false"
+		}
+	case "Int":
+		if !isNumber || strings.ContainsAny(s, ".eE") {
+			return "Int facts must be integral JSON numbers"
+		}
+	case "Decimal":
+		if isNumber {
+			return "Decimal facts must be strings such as \"12.50\"; a JSON number is rejected"
+		}
+		if s[0] != '"' && s[0] != '{' {
+			return "Decimal facts must be strings"
+		}
+	case "Money", "Record", "TaggedUnion", "Duration":
+		if s[0] != '{' {
+			return baseType + " facts must be JSON objects"
+		}
+	case "List":
+		if s[0] != '[' {
+			return "List facts must be JSON arrays"
+		}
+	case "Text", "Enum", "Date", "DateTime":
+		if s[0] != '"' {
+			return baseType + " facts must be strings"
+		}
+	}
+	return ""
+}
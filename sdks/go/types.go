@@ -1,5 +1,12 @@
 package tenor
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
 // FactSet maps fact IDs to their values. Values may be bool, float64, string,
 // map[string]interface{}, or []interface{} depending on the fact type.
 type FactSet map[string]interface{}
@@ -12,15 +19,43 @@ type EntityStateMap map[string]string
 // This is the new multi-instance format.
 type EntityStateMapNested map[string]map[string]string
 
+// IsMultiInstance reports whether m holds more than one instance of some
+// entity, which EntityStateMap cannot express.
+func (m EntityStateMapNested) IsMultiInstance() bool {
+	for _, instances := range m {
+		if len(instances) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
 // InstanceBindings maps entity IDs to instance IDs for flow execution.
+//
+// This is the "chosen" shape: exactly one instance per entity. Action and
+// BlockedAction use the "candidate" shape, map[string][]string, listing every
+// instance an action could target. NormalizedBindings on FlowResult and Action
+// converts both to the candidate shape for consumers that handle them
+// generically.
 type InstanceBindings map[string]string
 
 // VerdictProvenance traces how a verdict was produced.
+//
+// RuleDescription is filled in by the SDK from the producing rule's optional
+// description; it is empty when the rule has none. FactSources is filled in
+// by EvaluateTagged and is nil otherwise. File and Line locate the producing
+// rule in its source; they are filled in under WithSourceLocations and are
+// empty otherwise (see Verdict.SourceLocation).
 type VerdictProvenance struct {
-	Rule         string   `json:"rule"`
-	Stratum      int      `json:"stratum"`
-	FactsUsed    []string `json:"facts_used"`
-	VerdictsUsed []string `json:"verdicts_used"`
+	Rule            string   `json:"rule"`
+	Stratum         int      `json:"stratum"`
+	FactsUsed       []string `json:"facts_used"`
+	VerdictsUsed    []string `json:"verdicts_used"`
+	RuleDescription string   `json:"rule_description,omitempty"`
+	// FactSources maps each tagged fact in FactsUsed to its source.
+	FactSources map[string]string `json:"fact_sources,omitempty"`
+	File        string            `json:"file,omitempty"`
+	Line        int               `json:"line,omitempty"`
 }
 
 // Verdict represents a single evaluated verdict.
@@ -30,6 +65,17 @@ type Verdict struct {
 	Provenance VerdictProvenance `json:"provenance"`
 }
 
+// SourceLocation returns the source file and line of the rule that produced
+// v, looked up in b, so that an editor can jump to it. ok is false when b
+// does not declare the rule.
+func (v *Verdict) SourceLocation(b *Bundle) (file string, line int, ok bool) {
+	rule, ok := b.Rule(v.Provenance.Rule)
+	if !ok {
+		return "", 0, false
+	}
+	return rule.Provenance.File, rule.Provenance.Line, true
+}
+
 // VerdictSet contains all verdicts produced by evaluation.
 type VerdictSet struct {
 	Verdicts []Verdict `json:"verdicts"`
@@ -51,27 +97,58 @@ type EntitySummary struct {
 }
 
 // Action represents an available action in the action space.
-// InstanceBindings maps entity_id to the set of valid instance_ids for this action.
+// InstanceBindings maps entity_id to the set of valid instance_ids for this
+// action (the candidate shape; compare FlowResult.InstanceBindings, which
+// holds the single instance chosen per entity).
 type Action struct {
-	FlowID           string                       `json:"flow_id"`
-	PersonaID        string                       `json:"persona_id"`
-	EntryOperationID string                       `json:"entry_operation_id"`
-	EnablingVerdicts []VerdictSummary             `json:"enabling_verdicts"`
-	AffectedEntities []EntitySummary              `json:"affected_entities"`
-	Description      string                       `json:"description"`
-	InstanceBindings map[string][]string          `json:"instance_bindings,omitempty"`
-}
-
-// BlockedReason describes why an action is blocked.
-// The Type field contains one of: PersonaNotAuthorized, PreconditionNotMet,
-// EntityNotInSourceState, MissingFacts.
+	FlowID           string              `json:"flow_id"`
+	PersonaID        string              `json:"persona_id"`
+	EntryOperationID string              `json:"entry_operation_id"`
+	EnablingVerdicts []VerdictSummary    `json:"enabling_verdicts"`
+	AffectedEntities []EntitySummary     `json:"affected_entities"`
+	Description      string              `json:"description"`
+	InstanceBindings map[string][]string `json:"instance_bindings,omitempty"`
+}
+
+// BlockedReasonType identifies why an action is blocked.
+type BlockedReasonType string
+
+// Blocked reason types, matching the evaluator's BlockedReason variants.
+const (
+	ReasonPersonaNotAuthorized   BlockedReasonType = "PersonaNotAuthorized"
+	ReasonPreconditionNotMet     BlockedReasonType = "PreconditionNotMet"
+	ReasonEntityNotInSourceState BlockedReasonType = "EntityNotInSourceState"
+	ReasonMissingFacts           BlockedReasonType = "MissingFacts"
+)
+
+// BlockedReason describes why an action is blocked. Which of the remaining
+// fields are set depends on Type.
 type BlockedReason struct {
-	Type            string   `json:"type"`
-	MissingVerdicts []string `json:"missing_verdicts,omitempty"`
-	EntityID        string   `json:"entity_id,omitempty"`
-	CurrentState    string   `json:"current_state,omitempty"`
-	RequiredState   string   `json:"required_state,omitempty"`
-	FactIDs         []string `json:"fact_ids,omitempty"`
+	Type            BlockedReasonType `json:"type"`
+	MissingVerdicts []string          `json:"missing_verdicts,omitempty"`
+	EntityID        string            `json:"entity_id,omitempty"`
+	CurrentState    string            `json:"current_state,omitempty"`
+	RequiredState   string            `json:"required_state,omitempty"`
+	FactIDs         []string          `json:"fact_ids,omitempty"`
+}
+
+// String describes the reason in words, e.g. "precondition not met: missing
+// account_active" or "Order is approved, needs pending".
+func (r BlockedReason) String() string {
+	switch r.Type {
+	case ReasonPersonaNotAuthorized:
+		return "persona not authorized"
+	case ReasonPreconditionNotMet:
+		if len(r.MissingVerdicts) == 0 {
+			return "precondition not met"
+		}
+		return "precondition not met: missing " + strings.Join(r.MissingVerdicts, ", ")
+	case ReasonEntityNotInSourceState:
+		return fmt.Sprintf("%s is %s, needs %s", r.EntityID, r.CurrentState, r.RequiredState)
+	case ReasonMissingFacts:
+		return "missing facts: " + strings.Join(r.FactIDs, ", ")
+	}
+	return string(r.Type)
 }
 
 // BlockedAction represents an action that exists but cannot currently be executed.
@@ -79,6 +156,10 @@ type BlockedAction struct {
 	FlowID           string              `json:"flow_id"`
 	Reason           BlockedReason       `json:"reason"`
 	InstanceBindings map[string][]string `json:"instance_bindings"`
+	// AffectedEntityIDs are the entities the effects of the flow's entry
+	// operation transition. The evaluator does not report them; Evaluator
+	// methods fill them in from the contract.
+	AffectedEntityIDs []string `json:"affected_entity_ids,omitempty"`
 }
 
 // ActionSpace is the complete set of available and blocked actions for a persona.
@@ -89,12 +170,132 @@ type ActionSpace struct {
 	BlockedActions  []BlockedAction  `json:"blocked_actions"`
 }
 
+// BlockedByReason groups the blocked actions by reason type. The result is
+// never nil.
+func (s *ActionSpace) BlockedByReason() map[BlockedReasonType][]BlockedAction {
+	grouped := make(map[BlockedReasonType][]BlockedAction)
+	for _, b := range s.BlockedActions {
+		grouped[b.Reason.Type] = append(grouped[b.Reason.Type], b)
+	}
+	return grouped
+}
+
+// ForEntity returns a copy of s holding only the actions that affect
+// entityID, for per-entity views such as "what can I do to this Order?".
+// PersonaID and CurrentVerdicts are kept as they are.
+//
+// An available action matches when entityID is among its AffectedEntities. A
+// blocked action matches when entityID is among its AffectedEntityIDs, appears
+// in its InstanceBindings or is the entity its Reason names, so an action
+// blocked by persona or precondition still matches the entities it would
+// transition.
+func (s *ActionSpace) ForEntity(entityID string) *ActionSpace {
+	filtered := &ActionSpace{
+		PersonaID:       s.PersonaID,
+		Actions:         []Action{},
+		CurrentVerdicts: append([]VerdictSummary(nil), s.CurrentVerdicts...),
+		BlockedActions:  []BlockedAction{},
+	}
+	for _, a := range s.Actions {
+		for _, e := range a.AffectedEntities {
+			if e.EntityID == entityID {
+				filtered.Actions = append(filtered.Actions, a)
+				break
+			}
+		}
+	}
+	for _, b := range s.BlockedActions {
+		if _, ok := b.InstanceBindings[entityID]; ok || b.Reason.EntityID == entityID || contains(b.AffectedEntityIDs, entityID) {
+			filtered.BlockedActions = append(filtered.BlockedActions, b)
+		}
+	}
+	return filtered
+}
+
+// SortActions orders s.Actions by less, keeping the relative order of
+// actions less considers equal. The sorted actions are written to a new
+// slice, so a slice taken from s.Actions beforehand keeps its order.
+// Blocked actions are left as they are.
+func (s *ActionSpace) SortActions(less func(a, b Action) bool) {
+	actions := append([]Action(nil), s.Actions...)
+	sort.SliceStable(actions, func(i, j int) bool { return less(actions[i], actions[j]) })
+	s.Actions = actions
+}
+
+// ByFlowID orders actions by flow ID, for use with SortActions.
+func ByFlowID(a, b Action) bool {
+	return a.FlowID < b.FlowID
+}
+
+// ByEntryOperation orders actions by entry operation ID, for use with
+// SortActions.
+func ByEntryOperation(a, b Action) bool {
+	return a.EntryOperationID < b.EntryOperationID
+}
+
+// Table renders s as aligned plain text for CLI and debugging output: a
+// summary of the persona and current verdicts, then a table of available
+// actions (flow, entry operation, enabling verdicts) and one of blocked
+// actions (flow, reason). A table with no rows is replaced by "(none)".
+func (s *ActionSpace) Table() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Persona: %s\n", s.PersonaID)
+	verdicts := make([]string, len(s.CurrentVerdicts))
+	for i, v := range s.CurrentVerdicts {
+		verdicts[i] = v.VerdictType
+	}
+	fmt.Fprintf(&b, "Verdicts: %s\n", listOrNone(verdicts))
+
+	b.WriteString("\nAvailable:\n")
+	if len(s.Actions) == 0 {
+		b.WriteString("  (none)\n")
+	} else {
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  FLOW\tENTRY OPERATION\tENABLING VERDICTS")
+		for _, a := range s.Actions {
+			enabling := make([]string, len(a.EnablingVerdicts))
+			for i, v := range a.EnablingVerdicts {
+				enabling[i] = v.VerdictType
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", a.FlowID, a.EntryOperationID, listOrNone(enabling))
+		}
+		w.Flush()
+	}
+
+	b.WriteString("\nBlocked:\n")
+	if len(s.BlockedActions) == 0 {
+		b.WriteString("  (none)\n")
+	} else {
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  FLOW\tREASON")
+		for _, a := range s.BlockedActions {
+			fmt.Fprintf(w, "  %s\t%s\n", a.FlowID, a.Reason)
+		}
+		w.Flush()
+	}
+	return b.String()
+}
+
+// listOrNone joins items with commas, or returns "-" if there are none.
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ", ")
+}
+
 // StepResult describes the result of a single flow step.
+//
+// StateAfter is only populated when the Evaluator was created with
+// WithStepStates. It holds every entity instance's state once this step's
+// transitions have been applied; facts and verdicts stay frozen at flow
+// initiation (at_initiation snapshot), so only entity state evolves.
 type StepResult struct {
-	StepID           string            `json:"step_id"`
-	StepType         string            `json:"step_type"`
-	Result           string            `json:"result"`
-	InstanceBindings map[string]string `json:"instance_bindings,omitempty"`
+	StepID           string               `json:"step_id"`
+	StepType         string               `json:"step_type"`
+	Result           string               `json:"result"`
+	InstanceBindings map[string]string    `json:"instance_bindings,omitempty"`
+	StateAfter       EntityStateMapNested `json:"state_after,omitempty"`
 }
 
 // EntityStateChange describes a state transition caused by flow execution.
@@ -106,6 +307,14 @@ type EntityStateChange struct {
 }
 
 // FlowResult contains the results of a flow simulation.
+// InstanceBindings holds the single instance chosen per entity (the chosen
+// shape; compare Action.InstanceBindings).
+//
+// Path lists the steps the simulation executed, in order; branches not taken
+// never appear. Alongside the flow's own steps it holds the steps of each
+// parallel branch, after the parallel step's record, and compensation steps,
+// with step IDs of the form "comp:<operation>". CriticalPath keeps only the
+// flow's own route.
 type FlowResult struct {
 	Simulation       bool                `json:"simulation"`
 	FlowID           string              `json:"flow_id"`
@@ -115,4 +324,172 @@ type FlowResult struct {
 	WouldTransition  []EntityStateChange `json:"would_transition"`
 	Verdicts         []Verdict           `json:"verdicts"`
 	InstanceBindings InstanceBindings    `json:"instance_bindings"`
+	// flow is the definition of the flow run, set by the Evaluator for
+	// CriticalPath.
+	flow *FlowDef
+}
+
+// NormalizedBindings returns the action's instance bindings in the uniform
+// map[string][]string shape. The result is a copy and never nil.
+func (a Action) NormalizedBindings() map[string][]string {
+	normalized := make(map[string][]string, len(a.InstanceBindings))
+	for entityID, instanceIDs := range a.InstanceBindings {
+		normalized[entityID] = append([]string(nil), instanceIDs...)
+	}
+	return normalized
+}
+
+// NormalizedBindings returns the flow's instance bindings in the uniform
+// map[string][]string shape, each entity mapping to a one-element slice.
+// The result is never nil.
+func (r FlowResult) NormalizedBindings() map[string][]string {
+	normalized := make(map[string][]string, len(r.InstanceBindings))
+	for entityID, instanceID := range r.InstanceBindings {
+		normalized[entityID] = []string{instanceID}
+	}
+	return normalized
+}
+
+// CriticalPath returns the steps of Path on the flow's own route from entry
+// to outcome: the records of steps inside parallel branches, including
+// their compensations, are dropped, leaving each parallel step's summary
+// record. Compensation and escalation records of the flow's own steps, and
+// of a parallel step's join, are kept, as they lie on the route.
+//
+// Path alone does not tell branch steps from the flow's own, so CriticalPath
+// relies on the flow definition the Evaluator attaches to the results it
+// returns. For a FlowResult built any other way, such as one decoded from
+// JSON, it returns the whole Path. The result is a new slice.
+func (r *FlowResult) CriticalPath() []StepResult {
+	if r.flow == nil {
+		return append([]StepResult(nil), r.Path...)
+	}
+	var path []StepResult
+	for i := 0; i < len(r.Path); i++ {
+		step := r.Path[i]
+		path = append(path, step)
+		if def, ok := r.flow.Step(step.StepID); ok && def.Kind == "ParallelStep" {
+			i += branchRecordCount(def.Branches, r.Path[i+1:])
+		}
+	}
+	return path
+}
+
+// branchRecordCount returns how many of the leading records of path the
+// steps of branches produced, following a parallel step's record. Branch
+// records carry branch step IDs, and compensation records follow the failed
+// step, or the nested parallel step's branches, whose handler ran them, up to
+// that handler's length. The first record that is neither, such as a join
+// compensation of the parallel step itself, ends the branch records even when
+// it reuses a branch compensation's ID.
+func branchRecordCount(branches []ParallelBranch, path []StepResult) int {
+	steps := make(map[string]*FlowStep)
+	collectBranchSteps(branches, steps)
+	n := 0
+	for n < len(path) {
+		record := path[n]
+		step, ok := steps[record.StepID]
+		if !ok {
+			break
+		}
+		n++
+		failed, handler := strings.HasPrefix(record.Result, "error:"), step.OnFailure
+		if step.Kind == "ParallelStep" {
+			// The summary lists a failed branch as "<branch>:error:<message>".
+			n += branchRecordCount(step.Branches, path[n:])
+			failed, handler = strings.Contains(record.Result, ":error:"), nil
+			if step.Join != nil {
+				handler = step.Join.OnAnyFailure
+			}
+		}
+		if failed && handler != nil && handler.Kind == "Compensate" {
+			n += compensationRecordCount(handler.Steps, path[n:])
+		}
+	}
+	return n
+}
+
+// compensationRecordCount returns how many of the leading records of path
+// the compensation steps comps produced: one per step, in order, until one
+// fails.
+func compensationRecordCount(comps []CompensationStep, path []StepResult) int {
+	n := 0
+	for n < len(comps) && n < len(path) && path[n].StepID == "comp:"+comps[n].Op {
+		n++
+		if strings.HasPrefix(path[n-1].Result, "error:") {
+			break
+		}
+	}
+	return n
+}
+
+// collectBranchSteps adds to steps the steps of branches by ID, through nested
+// parallel steps.
+func collectBranchSteps(branches []ParallelBranch, steps map[string]*FlowStep) {
+	for _, branch := range branches {
+		for i := range branch.Steps {
+			step := &branch.Steps[i]
+			steps[step.ID] = step
+			collectBranchSteps(step.Branches, steps)
+		}
+	}
+}
+
+// HasOutcome reports whether the flow ended with outcome.
+func (r *FlowResult) HasOutcome(outcome string) bool {
+	return r.Outcome == outcome
+}
+
+// AssertTransition reports whether the flow would move the given entity
+// instance from one state to another.
+func (r *FlowResult) AssertTransition(entityID, instanceID, from, to string) bool {
+	for _, c := range r.WouldTransition {
+		if c == (EntityStateChange{EntityID: entityID, InstanceID: instanceID, FromState: from, ToState: to}) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectTransitions checks that the flow's transitions are exactly expected,
+// in any order. Repeated transitions must appear as often as expected lists
+// them. The error lists the missing and unexpected transitions.
+func (r *FlowResult) ExpectTransitions(expected []EntityStateChange) error {
+	remaining := make(map[EntityStateChange]int, len(expected))
+	for _, c := range expected {
+		remaining[c]++
+	}
+	var unexpected []string
+	for _, c := range r.WouldTransition {
+		if remaining[c] > 0 {
+			remaining[c]--
+			continue
+		}
+		unexpected = append(unexpected, c.String())
+	}
+	var missing []string
+	for c, n := range remaining {
+		for ; n > 0; n-- {
+			missing = append(missing, c.String())
+		}
+	}
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	var parts []string
+	if len(missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(missing, ", "))
+	}
+	if len(unexpected) > 0 {
+		parts = append(parts, "unexpected "+strings.Join(unexpected, ", "))
+	}
+	return fmt.Errorf("flow %q transitions differ: %s", r.FlowID, strings.Join(parts, "; "))
+}
+
+// String formats the change as "Entity/instance: from -> to".
+func (c EntityStateChange) String() string {
+	return fmt.Sprintf("%s/%s: %s -> %s", c.EntityID, c.InstanceID, c.FromState, c.ToState)
 }
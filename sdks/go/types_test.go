@@ -0,0 +1,585 @@
+package tenor_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tenor "github.com/riverline-labs/tenor-go"
+)
+
+func TestInstanceBindingsRoundTrip(t *testing.T) {
+	flowJSON := `{"flow_id": "approval_flow", "instance_bindings": {"Order": "ord-001"}}`
+	actionJSON := `{"flow_id": "approval_flow", "instance_bindings": {"Order": ["ord-001", "ord-002"]}}`
+
+	var flow tenor.FlowResult
+	if err := json.Unmarshal([]byte(flowJSON), &flow); err != nil {
+		t.Fatalf("failed to decode FlowResult: %v", err)
+	}
+	var action tenor.Action
+	if err := json.Unmarshal([]byte(actionJSON), &action); err != nil {
+		t.Fatalf("failed to decode Action: %v", err)
+	}
+
+	// Both shapes survive a marshal/unmarshal cycle unchanged.
+	var flowAgain tenor.FlowResult
+	data, _ := json.Marshal(flow)
+	if err := json.Unmarshal(data, &flowAgain); err != nil {
+		t.Fatalf("failed to re-decode FlowResult: %v", err)
+	}
+	if !reflect.DeepEqual(flow.InstanceBindings, flowAgain.InstanceBindings) {
+		t.Errorf("FlowResult bindings changed: %v -> %v", flow.InstanceBindings, flowAgain.InstanceBindings)
+	}
+	var actionAgain tenor.Action
+	data, _ = json.Marshal(action)
+	if err := json.Unmarshal(data, &actionAgain); err != nil {
+		t.Fatalf("failed to re-decode Action: %v", err)
+	}
+	if !reflect.DeepEqual(action.InstanceBindings, actionAgain.InstanceBindings) {
+		t.Errorf("Action bindings changed: %v -> %v", action.InstanceBindings, actionAgain.InstanceBindings)
+	}
+
+	wantFlow := map[string][]string{"Order": {"ord-001"}}
+	if got := flow.NormalizedBindings(); !reflect.DeepEqual(got, wantFlow) {
+		t.Errorf("FlowResult.NormalizedBindings: expected %v, got %v", wantFlow, got)
+	}
+	wantAction := map[string][]string{"Order": {"ord-001", "ord-002"}}
+	if got := action.NormalizedBindings(); !reflect.DeepEqual(got, wantAction) {
+		t.Errorf("Action.NormalizedBindings: expected %v, got %v", wantAction, got)
+	}
+}
+
+func TestNormalizedBindingsEmpty(t *testing.T) {
+	if got := (tenor.FlowResult{}).NormalizedBindings(); got == nil || len(got) != 0 {
+		t.Errorf("expected empty non-nil map, got %v", got)
+	}
+	if got := (tenor.Action{}).NormalizedBindings(); got == nil || len(got) != 0 {
+		t.Errorf("expected empty non-nil map, got %v", got)
+	}
+}
+
+func TestBindingPolicies(t *testing.T) {
+	candidates := []string{"ord-2", "ord-10", "ord-1"}
+
+	first, err := tenor.FirstInstance("Order", candidates)
+	if err != nil || first != "ord-2" {
+		t.Errorf("expected FirstInstance to pick ord-2, got %q (%v)", first, err)
+	}
+	lowest, err := tenor.LowestID("Order", candidates)
+	if err != nil || lowest != "ord-1" {
+		t.Errorf("expected LowestID to pick ord-1, got %q (%v)", lowest, err)
+	}
+	if candidates[0] != "ord-2" {
+		t.Errorf("expected LowestID not to reorder candidates, got %v", candidates)
+	}
+}
+
+func TestBindingCombinations(t *testing.T) {
+	action := tenor.Action{
+		FlowID: "approval_flow",
+		InstanceBindings: map[string][]string{
+			"Order":   {"ord-001", "ord-002"},
+			"Invoice": {"inv-1", "inv-2"},
+		},
+	}
+
+	combos, err := action.BindingCombinations(0)
+	if err != nil {
+		t.Fatalf("BindingCombinations failed: %v", err)
+	}
+	want := []tenor.InstanceBindings{
+		{"Invoice": "inv-1", "Order": "ord-001"},
+		{"Invoice": "inv-1", "Order": "ord-002"},
+		{"Invoice": "inv-2", "Order": "ord-001"},
+		{"Invoice": "inv-2", "Order": "ord-002"},
+	}
+	if !reflect.DeepEqual(combos, want) {
+		t.Errorf("unexpected combinations\n got: %v\nwant: %v", combos, want)
+	}
+
+	if _, err := action.BindingCombinations(3); !errors.Is(err, tenor.ErrTooManyCombinations) {
+		t.Errorf("expected ErrTooManyCombinations with a cap of 3, got %v", err)
+	}
+
+	empty, err := tenor.Action{FlowID: "f"}.BindingCombinations(0)
+	if err != nil || len(empty) != 1 || len(empty[0]) != 0 {
+		t.Errorf("expected one empty combination for an unbound action, got %v (%v)", empty, err)
+	}
+}
+
+func TestNewEntityStateMapNested(t *testing.T) {
+	states, err := tenor.NewEntityStateMapNested([]tenor.EntityStateRow{
+		{EntityID: "Order", InstanceID: "o1", State: "pending"},
+		{EntityID: "Order", InstanceID: "o2", State: "approved"},
+		{EntityID: "Invoice", InstanceID: "i1", State: "open"},
+		{EntityID: "Order", InstanceID: "o1", State: "pending"},
+	})
+	if err != nil {
+		t.Fatalf("NewEntityStateMapNested failed: %v", err)
+	}
+	want := tenor.EntityStateMapNested{
+		"Order":   {"o1": "pending", "o2": "approved"},
+		"Invoice": {"i1": "open"},
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("expected %v, got %v", want, states)
+	}
+
+	_, err = tenor.NewEntityStateMapNested([]tenor.EntityStateRow{
+		{EntityID: "Order", InstanceID: "o1", State: "pending"},
+		{EntityID: "Order", InstanceID: "o1", State: "approved"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"o1"`) {
+		t.Errorf("expected a conflict on o1, got %v", err)
+	}
+
+	empty, err := tenor.NewEntityStateMapNested(nil)
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("expected an empty non-nil map, got %v, %v", empty, err)
+	}
+}
+
+func TestEntityStateMapNestedIsMultiInstance(t *testing.T) {
+	for _, tc := range []struct {
+		states tenor.EntityStateMapNested
+		want   bool
+	}{
+		{nil, false},
+		{tenor.EntityStateMapNested{"Order": {"o1": "pending"}, "Invoice": {"i1": "open"}}, false},
+		{tenor.EntityStateMapNested{"Order": {"o1": "pending", "o2": "approved"}}, true},
+	} {
+		if got := tc.states.IsMultiInstance(); got != tc.want {
+			t.Errorf("IsMultiInstance(%v) = %v, want %v", tc.states, got, tc.want)
+		}
+	}
+}
+
+func TestApplyTransitions(t *testing.T) {
+	states := tenor.EntityStateMapNested{"Order": {"ord-1": "pending", "ord-2": "pending"}}
+	changes := []tenor.EntityStateChange{
+		{EntityID: "Order", InstanceID: "ord-1", FromState: "pending", ToState: "approved"},
+		{EntityID: "Order", InstanceID: "ord-1", FromState: "approved", ToState: "shipped"},
+	}
+
+	applied, err := tenor.ApplyTransitions(states, changes)
+	if err != nil {
+		t.Fatalf("ApplyTransitions failed: %v", err)
+	}
+	want := tenor.EntityStateMapNested{"Order": {"ord-1": "shipped", "ord-2": "pending"}}
+	if !reflect.DeepEqual(applied, want) {
+		t.Errorf("expected %v, got %v", want, applied)
+	}
+	if states["Order"]["ord-1"] != "pending" {
+		t.Errorf("expected input states to be unchanged, got %v", states)
+	}
+
+	// Replaying the same changes against the applied states is stale.
+	_, err = tenor.ApplyTransitions(applied, changes)
+	if !errors.Is(err, tenor.ErrStaleState) {
+		t.Fatalf("expected ErrStaleState, got %v", err)
+	}
+	var stale *tenor.StaleStateError
+	if !errors.As(err, &stale) || stale.InstanceID != "ord-1" || stale.Actual != "shipped" || stale.Expected != "pending" {
+		t.Errorf("expected ord-1 conflict (shipped, expected pending), got %+v", stale)
+	}
+
+	_, err = tenor.ApplyTransitions(states, []tenor.EntityStateChange{
+		{EntityID: "Order", InstanceID: "ord-3", FromState: "pending", ToState: "approved"},
+	})
+	if !errors.As(err, &stale) || !stale.Missing {
+		t.Errorf("expected a missing-instance conflict, got %v", err)
+	}
+}
+
+func TestValidatePayloads(t *testing.T) {
+	bundleJSON, err := os.ReadFile("../conformance/fixtures/escrow-bundle.json")
+	if err != nil {
+		t.Fatalf("failed to read bundle fixture: %v", err)
+	}
+	b, err := tenor.ParseBundle(bundleJSON)
+	if err != nil {
+		t.Fatalf("ParseBundle failed: %v", err)
+	}
+	verdictsJSON, err := os.ReadFile("../conformance/fixtures/expected-verdicts.json")
+	if err != nil {
+		t.Fatalf("failed to read verdicts fixture: %v", err)
+	}
+	var vs tenor.VerdictSet
+	if err := json.Unmarshal(verdictsJSON, &vs); err != nil {
+		t.Fatalf("failed to parse verdicts fixture: %v", err)
+	}
+
+	if errs := vs.ValidatePayloads(b); errs != nil {
+		t.Errorf("expected fixture payloads to validate, got %v", errs)
+	}
+
+	vs.Verdicts[0].Payload = map[string]interface{}{"kind": "bool_value", "value": "true"}
+	errs := vs.ValidatePayloads(b)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 mismatch, got %v", errs)
+	}
+	var mismatch *tenor.PayloadMismatchError
+	if !errors.As(errs[0], &mismatch) || mismatch.VerdictType != "account_active" ||
+		mismatch.Path != "payload.value" || mismatch.Expected != "bool" || mismatch.Actual != "string" {
+		t.Errorf("unexpected mismatch: %+v", mismatch)
+	}
+}
+
+func TestFlowResultAssertions(t *testing.T) {
+	approve := tenor.EntityStateChange{EntityID: "Order", InstanceID: "ord-1", FromState: "pending", ToState: "approved"}
+	ship := tenor.EntityStateChange{EntityID: "Order", InstanceID: "ord-2", FromState: "approved", ToState: "shipped"}
+	result := &tenor.FlowResult{
+		FlowID:          "approval_flow",
+		Outcome:         "order_approved",
+		WouldTransition: []tenor.EntityStateChange{approve, ship},
+	}
+
+	if !result.HasOutcome("order_approved") || result.HasOutcome("approval_failed") {
+		t.Errorf("unexpected HasOutcome results for %q", result.Outcome)
+	}
+	if !result.AssertTransition("Order", "ord-1", "pending", "approved") {
+		t.Error("expected ord-1 pending -> approved")
+	}
+	if result.AssertTransition("Order", "ord-1", "pending", "shipped") {
+		t.Error("did not expect ord-1 pending -> shipped")
+	}
+
+	if err := result.ExpectTransitions([]tenor.EntityStateChange{ship, approve}); err != nil {
+		t.Errorf("expected order-insensitive match, got %v", err)
+	}
+	err := result.ExpectTransitions([]tenor.EntityStateChange{approve})
+	if err == nil || !strings.Contains(err.Error(), "unexpected Order/ord-2: approved -> shipped") {
+		t.Errorf("expected the extra transition to be reported, got %v", err)
+	}
+	err = result.ExpectTransitions([]tenor.EntityStateChange{approve, ship, approve})
+	if err == nil || !strings.Contains(err.Error(), "missing Order/ord-1: pending -> approved") {
+		t.Errorf("expected the repeated transition to be reported missing, got %v", err)
+	}
+}
+
+func TestScenarioForksAreIndependent(t *testing.T) {
+	root := tenor.NewScenario(
+		tenor.FactSet{"limits": map[string]interface{}{"daily": "100.00"}},
+		tenor.EntityStateMapNested{"Order": {"ord-1": "pending"}},
+	)
+	fork := root.Fork()
+
+	approve := &tenor.FlowResult{WouldTransition: []tenor.EntityStateChange{
+		{EntityID: "Order", InstanceID: "ord-1", FromState: "pending", ToState: "approved"},
+	}}
+	if err := fork.Apply(approve); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	fork.Facts["limits"].(map[string]interface{})["daily"] = "0.00"
+
+	if got := root.States["Order"]["ord-1"]; got != "pending" {
+		t.Errorf("expected root Order to stay pending, got %q", got)
+	}
+	if got := root.Facts["limits"].(map[string]interface{})["daily"]; got != "100.00" {
+		t.Errorf("expected root facts unchanged, got %v", got)
+	}
+	if got := fork.States["Order"]["ord-1"]; got != "approved" {
+		t.Errorf("expected fork Order approved, got %q", got)
+	}
+
+	// Applying the same result again is stale and leaves the fork unchanged.
+	if err := fork.Apply(approve); !errors.Is(err, tenor.ErrStaleState) {
+		t.Errorf("expected ErrStaleState, got %v", err)
+	}
+	if got := fork.States["Order"]["ord-1"]; got != "approved" {
+		t.Errorf("expected fork Order still approved, got %q", got)
+	}
+}
+
+// TestTypesModelConformanceFixtures decodes the Rust-generated conformance
+// fixtures with unknown fields disallowed, so that a field added on the Rust
+// side without a Go counterpart fails here.
+func TestTypesModelConformanceFixtures(t *testing.T) {
+	fixtures := map[string]func() interface{}{
+		"expected-verdicts.json":             func() interface{} { return &tenor.VerdictSet{} },
+		"expected-action-space.json":         func() interface{} { return &tenor.ActionSpace{} },
+		"expected-action-space-blocked.json": func() interface{} { return &tenor.ActionSpace{} },
+		"expected-flow-result.json":          func() interface{} { return &tenor.FlowResult{} },
+	}
+	for name, target := range fixtures {
+		data, err := os.ReadFile("../conformance/fixtures/" + name)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(target()); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestActionSpaceForEntity(t *testing.T) {
+	order := tenor.EntitySummary{EntityID: "Order", CurrentState: "pending"}
+	invoice := tenor.EntitySummary{EntityID: "Invoice", CurrentState: "draft"}
+	verdicts := []tenor.VerdictSummary{{VerdictType: "account_active"}}
+	space := &tenor.ActionSpace{
+		PersonaID:       "admin",
+		CurrentVerdicts: verdicts,
+		Actions: []tenor.Action{
+			{FlowID: "approve_order", AffectedEntities: []tenor.EntitySummary{order}},
+			{FlowID: "issue_invoice", AffectedEntities: []tenor.EntitySummary{invoice}},
+			{FlowID: "bill_order", AffectedEntities: []tenor.EntitySummary{order, invoice}},
+		},
+		BlockedActions: []tenor.BlockedAction{
+			{FlowID: "ship_order", Reason: tenor.BlockedReason{Type: tenor.ReasonEntityNotInSourceState, EntityID: "Order"},
+				InstanceBindings: map[string][]string{"Order": {"_default"}}},
+			{FlowID: "void_invoice", Reason: tenor.BlockedReason{Type: tenor.ReasonEntityNotInSourceState, EntityID: "Invoice"},
+				InstanceBindings: map[string][]string{"Invoice": {"_default"}}},
+			{FlowID: "cancel_order", Reason: tenor.BlockedReason{Type: tenor.ReasonPersonaNotAuthorized},
+				InstanceBindings: map[string][]string{}, AffectedEntityIDs: []string{"Order"}},
+			{FlowID: "audit", Reason: tenor.BlockedReason{Type: tenor.ReasonPersonaNotAuthorized}},
+		},
+	}
+
+	got := space.ForEntity("Order")
+	var flows []string
+	for _, a := range got.Actions {
+		flows = append(flows, a.FlowID)
+	}
+	for _, b := range got.BlockedActions {
+		flows = append(flows, b.FlowID)
+	}
+	if want := []string{"approve_order", "bill_order", "ship_order", "cancel_order"}; !reflect.DeepEqual(flows, want) {
+		t.Errorf("expected %v, got %v", want, flows)
+	}
+	if got.PersonaID != "admin" || !reflect.DeepEqual(got.CurrentVerdicts, verdicts) {
+		t.Errorf("expected persona and current verdicts preserved, got %+v", got)
+	}
+	if len(space.Actions) != 3 || len(space.BlockedActions) != 4 {
+		t.Error("expected ForEntity to leave the original untouched")
+	}
+
+	if none := space.ForEntity("Customer"); none.Actions == nil || len(none.Actions) != 0 || len(none.BlockedActions) != 0 {
+		t.Errorf("expected empty non-nil lists for an unrelated entity, got %+v", none)
+	}
+}
+
+func TestBlockedByReason(t *testing.T) {
+	empty := &tenor.ActionSpace{}
+	if grouped := empty.BlockedByReason(); grouped == nil || len(grouped) != 0 {
+		t.Errorf("expected empty non-nil map, got %#v", grouped)
+	}
+
+	space := &tenor.ActionSpace{BlockedActions: []tenor.BlockedAction{
+		{FlowID: "a", Reason: tenor.BlockedReason{Type: tenor.ReasonMissingFacts, FactIDs: []string{"x"}}},
+		{FlowID: "b", Reason: tenor.BlockedReason{Type: tenor.ReasonPersonaNotAuthorized}},
+		{FlowID: "c", Reason: tenor.BlockedReason{Type: tenor.ReasonMissingFacts, FactIDs: []string{"y"}}},
+	}}
+	grouped := space.BlockedByReason()
+	if len(grouped) != 2 {
+		t.Fatalf("expected 2 reason groups, got %d", len(grouped))
+	}
+	missing := grouped[tenor.ReasonMissingFacts]
+	if len(missing) != 2 || missing[0].FlowID != "a" || missing[1].FlowID != "c" {
+		t.Errorf("expected flows a and c under MissingFacts, got %+v", missing)
+	}
+	if len(grouped[tenor.ReasonPersonaNotAuthorized]) != 1 {
+		t.Errorf("expected one PersonaNotAuthorized action, got %+v", grouped[tenor.ReasonPersonaNotAuthorized])
+	}
+}
+
+func TestDiffVerdictsAndActionSpaces(t *testing.T) {
+	from := &tenor.VerdictSet{Verdicts: []tenor.Verdict{
+		{Type: "account_active", Payload: true},
+		{Type: "risk_score", Payload: 3.0},
+		{Type: "flagged", Payload: true},
+	}}
+	to := &tenor.VerdictSet{Verdicts: []tenor.Verdict{
+		{Type: "account_active", Payload: true},
+		{Type: "risk_score", Payload: 7.0},
+		{Type: "needs_review", Payload: true},
+	}}
+	want := tenor.VerdictDiff{Added: []string{"needs_review"}, Removed: []string{"flagged"}, Changed: []string{"risk_score"}}
+	if got := tenor.DiffVerdicts(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if d := tenor.DiffVerdicts(from, from); !d.IsEmpty() {
+		t.Errorf("expected no difference, got %+v", d)
+	}
+
+	before := &tenor.ActionSpace{Actions: []tenor.Action{{FlowID: "approve"}, {FlowID: "cancel"}}}
+	after := &tenor.ActionSpace{Actions: []tenor.Action{{FlowID: "cancel"}, {FlowID: "refund"}}}
+	wantActions := tenor.ActionSpaceDiff{Enabled: []string{"refund"}, Disabled: []string{"approve"}}
+	if got := tenor.DiffActionSpaces(before, after); !reflect.DeepEqual(got, wantActions) {
+		t.Errorf("expected %+v, got %+v", wantActions, got)
+	}
+}
+
+func TestFlowResultCriticalPath(t *testing.T) {
+	b := &tenor.Bundle{Flows: []tenor.FlowDef{{ID: "fulfil", Entry: "check", Steps: []tenor.FlowStep{
+		{ID: "check", Kind: "BranchStep"},
+		{ID: "fanout", Kind: "ParallelStep", Branches: []tenor.ParallelBranch{
+			{ID: "billing", Entry: "charge", Steps: []tenor.FlowStep{{ID: "charge", Kind: "OperationStep", Op: "charge_card",
+				OnFailure: &tenor.FailureHandler{Kind: "Compensate", Steps: []tenor.CompensationStep{{Op: "void_charge"}}}}}},
+			{ID: "shipping", Entry: "pack", Steps: []tenor.FlowStep{{ID: "pack", Kind: "OperationStep", Op: "pack_order"}}},
+		}, Join: &tenor.JoinPolicy{OnAnyFailure: &tenor.FailureHandler{Kind: "Compensate",
+			Steps: []tenor.CompensationStep{{Op: "void_charge"}}, Then: &tenor.StepTarget{StepID: "close"}}}},
+		{ID: "close", Kind: "OperationStep", Op: "close_order"},
+	}}}}
+	stepIDs := func(steps []tenor.StepResult) []string {
+		var ids []string
+		for _, step := range steps {
+			ids = append(ids, step.StepID)
+		}
+		return ids
+	}
+
+	result := tenor.AttachFlow(&tenor.FlowResult{FlowID: "fulfil", Outcome: "done", Path: []tenor.StepResult{
+		{StepID: "check", StepType: "branch", Result: "true"},
+		{StepID: "fanout", StepType: "parallel", Result: "billing:voided, shipping:packed"},
+		{StepID: "charge", StepType: "operation", Result: "error: declined"},
+		{StepID: "comp:void_charge", StepType: "compensation", Result: "voided"},
+		{StepID: "pack", StepType: "operation", Result: "packed"},
+		{StepID: "close", StepType: "operation", Result: "closed"},
+		{StepID: "comp:refund", StepType: "compensation", Result: "refunded"},
+	}}, b)
+	want := []string{"check", "fanout", "close", "comp:refund"}
+	if got := stepIDs(result.CriticalPath()); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if len(result.Path) != 7 {
+		t.Errorf("expected Path to be left alone, got %d steps", len(result.Path))
+	}
+
+	// A join compensation reusing the branch compensation's operation follows
+	// the branch records and lies on the route.
+	joined := tenor.AttachFlow(&tenor.FlowResult{FlowID: "fulfil", Outcome: "done", Path: []tenor.StepResult{
+		{StepID: "fanout", StepType: "parallel", Result: "billing:voided, shipping:error:no stock"},
+		{StepID: "charge", StepType: "operation", Result: "error: declined"},
+		{StepID: "comp:void_charge", StepType: "compensation", Result: "voided"},
+		{StepID: "comp:void_charge", StepType: "compensation", Result: "voided"},
+		{StepID: "close", StepType: "operation", Result: "closed"},
+	}}, b)
+	want = []string{"fanout", "comp:void_charge", "close"}
+	if got := stepIDs(joined.CriticalPath()); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the join compensation kept, %v, got %v", want, got)
+	}
+
+	// Without a flow definition, as for a decoded result, the whole path.
+	decoded := &tenor.FlowResult{FlowID: "fulfil", Path: result.Path}
+	if got := decoded.CriticalPath(); len(got) != 7 {
+		t.Errorf("expected the whole path without a flow definition, got %d steps", len(got))
+	}
+}
+
+func TestSortActions(t *testing.T) {
+	original := []tenor.Action{
+		{FlowID: "refund", EntryOperationID: "issue_refund"},
+		{FlowID: "approve", EntryOperationID: "approve_order"},
+		{FlowID: "escalate", EntryOperationID: "open_case"},
+	}
+	space := &tenor.ActionSpace{Actions: original}
+
+	priority := map[string]int{"escalate": 0, "refund": 1, "approve": 2}
+	space.SortActions(func(a, b tenor.Action) bool { return priority[a.FlowID] < priority[b.FlowID] })
+	flows := func() []string {
+		ids := make([]string, len(space.Actions))
+		for i, a := range space.Actions {
+			ids[i] = a.FlowID
+		}
+		return ids
+	}
+	if got, want := flows(), []string{"escalate", "refund", "approve"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if original[0].FlowID != "refund" {
+		t.Errorf("expected the original slice to keep its order, got %+v", original)
+	}
+
+	space.SortActions(tenor.ByFlowID)
+	if got, want := flows(), []string{"approve", "escalate", "refund"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ByFlowID: expected %v, got %v", want, got)
+	}
+	space.SortActions(tenor.ByEntryOperation)
+	if got, want := flows(), []string{"approve", "refund", "escalate"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ByEntryOperation: expected %v, got %v", want, got)
+	}
+}
+
+func TestRuleProfileMerge(t *testing.T) {
+	p := tenor.RuleProfile{"a": {Duration: 2 * time.Millisecond, Count: 1}}
+	p.Merge(tenor.RuleProfile{
+		"a": {Duration: 3 * time.Millisecond, Count: 1},
+		"b": {Duration: time.Millisecond, Count: 1},
+	})
+	want := tenor.RuleProfile{
+		"a": {Duration: 5 * time.Millisecond, Count: 2},
+		"b": {Duration: time.Millisecond, Count: 1},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("expected %v, got %v", want, p)
+	}
+}
+
+func TestMutuallyExclusive(t *testing.T) {
+	inv := tenor.MutuallyExclusive("account_active", "account_frozen")
+	vs := &tenor.VerdictSet{Verdicts: []tenor.Verdict{{Type: "account_active"}, {Type: "vip"}}}
+	if err := inv.Check(vs); err != nil {
+		t.Errorf("expected one of the two to satisfy the invariant, got %v", err)
+	}
+	vs.Verdicts = append(vs.Verdicts, tenor.Verdict{Type: "account_frozen"})
+	if err := inv.Check(vs); err == nil || !strings.Contains(err.Error(), "account_active, account_frozen") {
+		t.Errorf("expected a violation naming both verdicts, got %v", err)
+	}
+}
+
+func TestMatrixCellString(t *testing.T) {
+	cells := map[string]tenor.MatrixCell{
+		"available": {Available: true},
+		"blocked: precondition not met: missing account_active": {Blocked: &tenor.BlockedReason{
+			Type: tenor.ReasonPreconditionNotMet, MissingVerdicts: []string{"account_active"}}},
+		"-": {},
+	}
+	for want, c := range cells {
+		if got := c.String(); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestActionSpaceTable(t *testing.T) {
+	space := &tenor.ActionSpace{
+		PersonaID:       "admin",
+		CurrentVerdicts: []tenor.VerdictSummary{{VerdictType: "account_active"}},
+		Actions: []tenor.Action{{
+			FlowID:           "approval_flow",
+			EntryOperationID: "approve_order",
+			EnablingVerdicts: []tenor.VerdictSummary{{VerdictType: "account_active"}},
+		}},
+		BlockedActions: []tenor.BlockedAction{
+			{FlowID: "refund_flow", Reason: tenor.BlockedReason{Type: tenor.ReasonPreconditionNotMet, MissingVerdicts: []string{"refund_allowed"}}},
+			{FlowID: "ship_flow", Reason: tenor.BlockedReason{Type: tenor.ReasonEntityNotInSourceState, EntityID: "Order", CurrentState: "pending", RequiredState: "approved"}},
+		},
+	}
+	want := `Persona: admin
+Verdicts: account_active
+
+Available:
+  FLOW           ENTRY OPERATION  ENABLING VERDICTS
+  approval_flow  approve_order    account_active
+
+Blocked:
+  FLOW         REASON
+  refund_flow  precondition not met: missing refund_allowed
+  ship_flow    Order is pending, needs approved
+`
+	if got := space.Table(); got != want {
+		t.Errorf("unexpected table:\n%s\nwant:\n%s", got, want)
+	}
+
+	empty := (&tenor.ActionSpace{PersonaID: "guest"}).Table()
+	if want := "Persona: guest\nVerdicts: -\n\nAvailable:\n  (none)\n\nBlocked:\n  (none)\n"; empty != want {
+		t.Errorf("unexpected empty table:\n%q\nwant:\n%q", empty, want)
+	}
+}
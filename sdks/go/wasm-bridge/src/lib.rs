@@ -21,8 +21,8 @@ use tenor_eval::Contract;
 
 struct StoredContract {
     contract: Contract,
-    // Keep the bundle around for future inspect_contract support
-    #[allow(dead_code)]
+    // The bundle as loaded, for listings that need more than Contract keeps
+    // (fact sources, rule expressions as written).
     bundle: serde_json::Value,
 }
 
@@ -58,6 +58,19 @@ where
     set_result(&result);
 }
 
+/// The declared facts that `facts` does not supply and that have no default.
+///
+/// Fact assembly stops at the first missing fact; this lists all of them so
+/// a caller can ask for every one at once.
+fn missing_facts(contract: &Contract, facts: &serde_json::Value) -> Vec<String> {
+    contract
+        .facts
+        .iter()
+        .filter(|decl| decl.default.is_none() && facts.get(&decl.id).is_none())
+        .map(|decl| decl.id.clone())
+        .collect()
+}
+
 /// Parse entity_states JSON with auto-detection of old and new formats.
 ///
 /// Old format (flat):  `{ "Order": "pending" }`
@@ -173,6 +186,14 @@ pub extern "C" fn get_result_len() -> u32 {
     RESULT_BUF.with(|buf| buf.borrow().len() as u32)
 }
 
+/// Release the result buffer's allocation. The host calls it once it has
+/// copied a result out, so the largest result seen does not stay allocated
+/// until the next call. The result is empty afterwards.
+#[no_mangle]
+pub extern "C" fn free_result() {
+    RESULT_BUF.with(|buf| *buf.borrow_mut() = Vec::new());
+}
+
 // ── Contract management exports ──
 
 /// Load a contract from interchange bundle JSON.
@@ -256,6 +277,13 @@ pub unsafe extern "C" fn evaluate(handle: u32, ptr: *const u8, len: u32) {
         let fact_set =
             match tenor_eval::assemble::assemble_facts(&stored.contract, &facts) {
                 Ok(fs) => fs,
+                Err(e @ tenor_eval::EvalError::MissingFact { .. }) => {
+                    return serde_json::json!({
+                        "error": format!("fact assembly error: {}", e),
+                        "missing_facts": missing_facts(&stored.contract, &facts),
+                    })
+                    .to_string()
+                }
                 Err(e) => {
                     return serde_json::json!({ "error": format!("fact assembly error: {}", e) })
                         .to_string()
@@ -594,3 +622,172 @@ unsafe fn simulate_flow_with_bindings_impl(
         .to_string()
     });
 }
+
+// ── Listing exports ──
+
+/// List the contract's flows.
+///
+/// Args:   handle
+/// Result: `{"flows": [{"id", "entry", "snapshot", "allowed_personas"}]}` or
+///         `{"error": "..."}`. `allowed_personas` are those of the entry
+///         step's operation; empty when the flow is entered by a step of
+///         another kind.
+#[no_mangle]
+pub extern "C" fn list_flows(handle: u32) {
+    with_contract(handle, |stored| {
+        let contract = &stored.contract;
+        let mut flows = Vec::with_capacity(contract.flows.len());
+        for flow in &contract.flows {
+            let entry_op = flow.steps.iter().find_map(|step| match step {
+                tenor_eval::types::FlowStep::OperationStep { id, op, .. } if *id == flow.entry => {
+                    Some(op)
+                }
+                _ => None,
+            });
+            let allowed_personas = match entry_op {
+                Some(op_id) => match contract.get_operation(op_id) {
+                    Some(op) => op.allowed_personas.clone(),
+                    None => {
+                        return serde_json::json!({
+                            "error": format!(
+                                "flow {} entry step runs undeclared operation {}",
+                                flow.id, op_id
+                            )
+                        })
+                        .to_string()
+                    }
+                },
+                None => Vec::new(),
+            };
+            flows.push(serde_json::json!({
+                "id": flow.id,
+                "entry": flow.entry,
+                "snapshot": flow.snapshot,
+                "allowed_personas": allowed_personas,
+            }));
+        }
+        serde_json::json!({ "flows": flows }).to_string()
+    });
+}
+
+/// List the personas the contract's operations allow.
+///
+/// Args:   handle
+/// Result: `{"personas": [...]}`, the sorted, de-duplicated union of every
+///         operation's `allowed_personas`.
+#[no_mangle]
+pub extern "C" fn list_personas(handle: u32) {
+    with_contract(handle, |stored| {
+        let personas: std::collections::BTreeSet<&String> = stored
+            .contract
+            .operations
+            .iter()
+            .flat_map(|op| op.allowed_personas.iter())
+            .collect();
+        serde_json::json!({ "personas": personas }).to_string()
+    });
+}
+
+/// List the contract's facts.
+///
+/// Args:   handle
+/// Result: `{"facts": [{"id", "type", "source", "required"}]}`. `type` is the
+///         declared base type, `source` the binding as `{"system", "field"}`
+///         or `{"source_id", "path"}` (omitted if none is declared), and
+///         `required` whether some rule references the fact.
+#[no_mangle]
+pub extern "C" fn list_facts(handle: u32) {
+    with_contract(handle, |stored| {
+        let constructs: &[serde_json::Value] = match stored.bundle["constructs"].as_array() {
+            Some(c) => c.as_slice(),
+            None => &[],
+        };
+        let mut referenced = std::collections::BTreeSet::new();
+        for rule in constructs.iter().filter(|c| c["kind"] == "Rule") {
+            collect_fact_refs(&rule["body"], &mut referenced);
+        }
+        let facts: Vec<serde_json::Value> = constructs
+            .iter()
+            .filter(|c| c["kind"] == "Fact")
+            .map(|fact| {
+                let id = fact["id"].as_str().unwrap_or_default();
+                let mut info = serde_json::json!({
+                    "id": id,
+                    "type": fact["type"]["base"],
+                    "required": referenced.contains(id),
+                });
+                if let Some(source) = fact.get("source") {
+                    info["source"] = fact_source(source);
+                }
+                info
+            })
+            .collect();
+        serde_json::json!({ "facts": facts }).to_string()
+    });
+}
+
+/// Collect the IDs of the facts an expression references.
+fn collect_fact_refs<'a>(
+    expr: &'a serde_json::Value,
+    out: &mut std::collections::BTreeSet<&'a str>,
+) {
+    match expr {
+        serde_json::Value::Object(m) => {
+            if let Some(id) = m.get("fact_ref").and_then(|v| v.as_str()) {
+                out.insert(id);
+            }
+            for child in m.values() {
+                collect_fact_refs(child, out);
+            }
+        }
+        serde_json::Value::Array(a) => {
+            for child in a {
+                collect_fact_refs(child, out);
+            }
+        }
+        _ => {}
+    }
+}
+
+/// Normalize a fact's source binding to an object. The elaborator writes a
+/// legacy freetext binding it could not split on a dot as a bare string; any
+/// other string is split like it would have been.
+fn fact_source(source: &serde_json::Value) -> serde_json::Value {
+    match source.as_str() {
+        Some(s) => match s.split_once('.') {
+            Some((system, field)) => serde_json::json!({ "system": system, "field": field }),
+            None => serde_json::json!({ "field": s }),
+        },
+        None => source.clone(),
+    }
+}
+
+/// List the contract's entities.
+///
+/// Args:   handle
+/// Result: `{"entities": [{"id", "initial", "states", "transitions"}]}`, each
+///         transition as `{"from", "to"}`.
+#[no_mangle]
+pub extern "C" fn list_entities(handle: u32) {
+    with_contract(handle, |stored| {
+        let entities: Vec<serde_json::Value> = stored
+            .contract
+            .entities
+            .iter()
+            .map(|entity| {
+                let transitions: Vec<serde_json::Value> = entity
+                    .transitions
+                    .iter()
+                    .map(|t| serde_json::json!({ "from": t.from, "to": t.to }))
+                    .collect();
+                serde_json::json!({
+                    "id": entity.id,
+                    "initial": entity.initial,
+                    "states": entity.states,
+                    "transitions": transitions,
+                })
+            })
+            .collect();
+        serde_json::json!({ "entities": entities }).to_string()
+    });
+}